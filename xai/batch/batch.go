@@ -3,10 +3,12 @@ package batch
 import (
 	"context"
 	"fmt"
+	"iter"
 
 	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/chat"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/image"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/pager"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/video"
 	"google.golang.org/protobuf/proto"
 )
@@ -75,6 +77,16 @@ func (c *Client) List(ctx context.Context, opts *ListOptions) ([]*xaiv1.Batch, s
 	return resp.Batches, resp.GetPaginationToken(), nil
 }
 
+// All returns an iterator over every batch, transparently following
+// pagination tokens. Iteration stops at the first error or when the loop breaks.
+func (c *Client) All(ctx context.Context, opts *ListOptions) iter.Seq2[*xaiv1.Batch, error] {
+	pageOpts := copyListOptions(opts)
+	return pager.All(ctx, pageOpts.PaginationToken, func(ctx context.Context, token string) ([]*xaiv1.Batch, string, error) {
+		pageOpts.PaginationToken = token
+		return c.List(ctx, &pageOpts)
+	})
+}
+
 func (c *Client) Cancel(ctx context.Context, batchID string) (*xaiv1.Batch, error) {
 	if c.grpcClient == nil {
 		return nil, fmt.Errorf("batch client not initialized")
@@ -156,6 +168,17 @@ func (c *Client) ListRequestMetadata(ctx context.Context, batchID string, opts *
 	return resp.BatchRequestMetadata, resp.GetPaginationToken(), nil
 }
 
+// AllRequestMetadata returns an iterator over the metadata of every request in
+// a batch, transparently following pagination tokens. Iteration stops at the
+// first error or when the loop breaks.
+func (c *Client) AllRequestMetadata(ctx context.Context, batchID string, opts *ListOptions) iter.Seq2[*xaiv1.BatchRequestMetadata, error] {
+	pageOpts := copyListOptions(opts)
+	return pager.All(ctx, pageOpts.PaginationToken, func(ctx context.Context, token string) ([]*xaiv1.BatchRequestMetadata, string, error) {
+		pageOpts.PaginationToken = token
+		return c.ListRequestMetadata(ctx, batchID, &pageOpts)
+	})
+}
+
 func (c *Client) ListBatchRequests(ctx context.Context, batchID string, opts *ListOptions) ([]*xaiv1.BatchRequestMetadata, string, error) {
 	return c.ListRequestMetadata(ctx, batchID, opts)
 }
@@ -181,6 +204,24 @@ func (c *Client) ListResults(ctx context.Context, batchID string, opts *ListOpti
 	return resp.Results, resp.GetPaginationToken(), nil
 }
 
+// AllResults returns an iterator over every result of a batch, transparently
+// following pagination tokens. Iteration stops at the first error or when the
+// loop breaks.
+func (c *Client) AllResults(ctx context.Context, batchID string, opts *ListOptions) iter.Seq2[*xaiv1.BatchResult, error] {
+	pageOpts := copyListOptions(opts)
+	return pager.All(ctx, pageOpts.PaginationToken, func(ctx context.Context, token string) ([]*xaiv1.BatchResult, string, error) {
+		pageOpts.PaginationToken = token
+		return c.ListResults(ctx, batchID, &pageOpts)
+	})
+}
+
+func copyListOptions(opts *ListOptions) ListOptions {
+	if opts == nil {
+		return ListOptions{}
+	}
+	return *opts
+}
+
 func (c *Client) ListBatchResults(ctx context.Context, batchID string, opts *ListOptions) ([]*xaiv1.BatchResult, string, error) {
 	return c.ListResults(ctx, batchID, opts)
 }
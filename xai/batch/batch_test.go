@@ -1,12 +1,15 @@
 package batch
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/image"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/video"
 	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
 )
 
 func TestRequestFromImageRequest(t *testing.T) {
@@ -109,3 +112,47 @@ func TestResultWrappers(t *testing.T) {
 		t.Fatal("list result wrapper mismatch")
 	}
 }
+
+type pagedBatchClient struct {
+	xaiv1.BatchMgmtClient
+	tokens []string
+}
+
+func (c *pagedBatchClient) ListBatchResults(_ context.Context, in *xaiv1.ListBatchResultsRequest, _ ...grpc.CallOption) (*xaiv1.ListBatchResultsResponse, error) {
+	c.tokens = append(c.tokens, in.GetPaginationToken())
+	if in.GetPaginationToken() == "" {
+		next := "page-2"
+		return &xaiv1.ListBatchResultsResponse{
+			Results:         []*xaiv1.BatchResult{{BatchRequestId: "req-1"}, {BatchRequestId: "req-2"}},
+			PaginationToken: &next,
+		}, nil
+	}
+	return &xaiv1.ListBatchResultsResponse{Results: []*xaiv1.BatchResult{{BatchRequestId: "req-3"}}}, nil
+}
+
+func TestAllResultsFollowsPagination(t *testing.T) {
+	grpcClient := &pagedBatchClient{}
+	client := NewClient(grpcClient)
+
+	var ids []string
+	for result, err := range client.AllResults(context.Background(), "batch-1", &ListOptions{Limit: 2}) {
+		if err != nil {
+			t.Fatalf("AllResults() error = %v", err)
+		}
+		ids = append(ids, result.GetBatchRequestId())
+	}
+	if len(ids) != 3 || ids[2] != "req-3" {
+		t.Errorf("ids = %v", ids)
+	}
+	if len(grpcClient.tokens) != 2 || grpcClient.tokens[1] != "page-2" {
+		t.Errorf("tokens = %v", grpcClient.tokens)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range client.AllResults(ctx, "batch-1", nil) {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+	}
+}
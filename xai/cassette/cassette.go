@@ -0,0 +1,346 @@
+// Package cassette records the gRPC and REST traffic of a client to a file
+// and replays it, so that code built on xai.Client can be tested offline and
+// deterministically:
+//
+//	rec, err := cassette.New("testdata/chat.json", cassette.ModeReplayOrRecord)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer rec.Close() // writes the cassette when recording
+//	client, err := xai.NewClient(xai.NewConfig().WithCassette(rec))
+//
+// When recording, calls go to the API and every interaction (unary call,
+// stream or HTTP request) is kept, with API keys, authorization headers and
+// other secrets redacted. When replaying, no connection is made: each call is
+// answered with the recorded interaction matching its method and request,
+// compared as canonical JSON. Server-streaming calls such as
+// GetCompletionChunk replay their chunks in order.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/logging"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Version is the version of the cassette format.
+const Version = 1
+
+// ErrInteractionNotFound is returned when replaying a call that the cassette
+// does not contain.
+var ErrInteractionNotFound = errors.New("cassette: no recorded interaction matches the request")
+
+// Mode selects whether a Recorder records or replays.
+type Mode int
+
+const (
+	// ModeReplay replays the cassette, which must exist.
+	ModeReplay Mode = iota
+	// ModeRecord sends calls to the API and records them, replacing the
+	// cassette on Close.
+	ModeRecord
+	// ModeReplayOrRecord replays the cassette if it exists and records it
+	// otherwise.
+	ModeReplayOrRecord
+)
+
+// String returns the name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeReplay:
+		return "replay"
+	case ModeRecord:
+		return "record"
+	case ModeReplayOrRecord:
+		return "replay_or_record"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded gRPC call or HTTP request; exactly one of GRPC
+// and HTTP is set.
+type Interaction struct {
+	GRPC *GRPCInteraction `json:"grpc,omitempty"`
+	HTTP *HTTPInteraction `json:"http,omitempty"`
+}
+
+// GRPCInteraction is a recorded gRPC call.
+type GRPCInteraction struct {
+	// Method is the full method name, such as "/xai_api.Chat/GetCompletion".
+	Method string `json:"method"`
+
+	// Stream reports whether the call is a stream.
+	Stream bool `json:"stream,omitempty"`
+
+	// Requests are the request messages in proto JSON: one for unary and
+	// server-streaming calls.
+	Requests []json.RawMessage `json:"requests"`
+
+	// Header is the response header metadata.
+	Header map[string][]string `json:"header,omitempty"`
+
+	// Responses are the response messages in proto JSON: one for unary
+	// calls, the received messages for streams.
+	Responses []json.RawMessage `json:"responses,omitempty"`
+
+	// Status is the final google.rpc.Status in proto JSON if the call failed.
+	Status json.RawMessage `json:"status,omitempty"`
+}
+
+// HTTPInteraction is a recorded HTTP request.
+type HTTPInteraction struct {
+	// Method and URL are the request method and the URL path and query.
+	Method string `json:"method"`
+	URL    string `json:"url"`
+
+	// RequestBody is the request body; JSON bodies are canonicalized and
+	// multipart boundaries replaced by a fixed one.
+	RequestBody string `json:"request_body,omitempty"`
+
+	// StatusCode, Header and Body are the response.
+	StatusCode int                 `json:"status_code"`
+	Header     map[string][]string `json:"header,omitempty"`
+	Body       string              `json:"body,omitempty"`
+
+	// BodyBase64 reports whether Body is base64-encoded binary content.
+	BodyBase64 bool `json:"body_base64,omitempty"`
+}
+
+// Matcher reports whether a recorded interaction answers a request. The
+// request is an Interaction holding the request fields only.
+type Matcher func(recorded, request Interaction) bool
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithMatcher replaces the default matching, by method (and URL) and
+// canonical request.
+func WithMatcher(matcher Matcher) Option {
+	return func(r *Recorder) {
+		r.matcher = matcher
+	}
+}
+
+// WithRedaction adds secrets redacted from the recorded interactions, in
+// addition to API keys, authorization headers and proxy credentials.
+func WithRedaction(secrets ...string) Option {
+	return func(r *Recorder) {
+		r.secrets = append(r.secrets, secrets...)
+	}
+}
+
+// Recorder records or replays the calls of a client. It is safe for
+// concurrent use.
+type Recorder struct {
+	path      string
+	recording bool
+	matcher   Matcher
+	secrets   []string
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New creates a recorder for the cassette at path. In replay modes the
+// cassette is loaded at once.
+func New(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{path: path, matcher: DefaultMatcher, cassette: Cassette{Version: Version}}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	switch mode {
+	case ModeRecord:
+		r.recording = true
+	case ModeReplay, ModeReplayOrRecord:
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) && mode == ModeReplayOrRecord {
+			r.recording = true
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("cassette: invalid cassette %s: %w", path, err)
+		}
+		if r.cassette.Version != Version {
+			return nil, fmt.Errorf("cassette: unsupported cassette version %d in %s", r.cassette.Version, path)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	default:
+		return nil, fmt.Errorf("cassette: invalid mode %v", mode)
+	}
+	return r, nil
+}
+
+// Recording reports whether the recorder records, rather than replays.
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// Interactions returns a copy of the recorded or loaded interactions.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+// Close writes the cassette if recording. It does nothing when replaying.
+func (r *Recorder) Close() error {
+	if !r.recording {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return nil
+}
+
+// record appends an interaction.
+func (r *Recorder) record(interaction Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+}
+
+// find returns the first unused recorded interaction matching request, or
+// the last used one if all matching interactions have been replayed, so that
+// repeated identical calls replay in recording order.
+func (r *Recorder) find(request Interaction) (Interaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := -1
+	for i, recorded := range r.cassette.Interactions {
+		if !r.matcher(recorded, request) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return recorded, nil
+		}
+		last = i
+	}
+	if last < 0 {
+		return Interaction{}, fmt.Errorf("%w: %s", ErrInteractionNotFound, describe(request))
+	}
+	return r.cassette.Interactions[last], nil
+}
+
+// DefaultMatcher matches gRPC calls by method and requests and HTTP requests
+// by method, URL and body, comparing JSON as canonical JSON.
+func DefaultMatcher(recorded, request Interaction) bool {
+	switch {
+	case recorded.GRPC != nil && request.GRPC != nil:
+		a, b := recorded.GRPC, request.GRPC
+		if a.Method != b.Method || len(a.Requests) != len(b.Requests) {
+			return false
+		}
+		for i := range a.Requests {
+			if !bytes.Equal(canonicalJSON(a.Requests[i]), canonicalJSON(b.Requests[i])) {
+				return false
+			}
+		}
+		return true
+	case recorded.HTTP != nil && request.HTTP != nil:
+		a, b := recorded.HTTP, request.HTTP
+		return a.Method == b.Method && a.URL == b.URL && a.RequestBody == b.RequestBody
+	default:
+		return false
+	}
+}
+
+func describe(request Interaction) string {
+	if request.GRPC != nil {
+		return request.GRPC.Method
+	}
+	return request.HTTP.Method + " " + request.HTTP.URL
+}
+
+// redact redacts the secrets in s.
+func (r *Recorder) redact(s string) string {
+	return logging.Redact(s, r.secrets...)
+}
+
+// redactHeader copies header without sensitive keys, redacting the values.
+func (r *Recorder) redactHeader(header map[string][]string) map[string][]string {
+	if len(header) == 0 {
+		return nil
+	}
+	redacted := make(map[string][]string, len(header))
+	for key, values := range header {
+		if logging.IsSensitiveKey(key) {
+			continue
+		}
+		for _, v := range values {
+			redacted[key] = append(redacted[key], r.redact(v))
+		}
+	}
+	return redacted
+}
+
+// marshalMessage returns the redacted canonical proto JSON of msg.
+func (r *Recorder) marshalMessage(msg any) (json.RawMessage, error) {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("cassette: %T is not a proto message", msg)
+	}
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	return canonicalJSON([]byte(r.redact(string(data)))), nil
+}
+
+// unmarshalMessage decodes recorded proto JSON into msg.
+func unmarshalMessage(data json.RawMessage, msg any) error {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cassette: %T is not a proto message", msg)
+	}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, m); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return nil
+}
+
+// canonicalJSON returns data compacted with sorted object keys, or data
+// unchanged if it is not JSON.
+func canonicalJSON(data []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v any
+	if decoder.Decode(&v) != nil {
+		return data
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return data
+	}
+	return canonical
+}
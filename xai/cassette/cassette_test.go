@@ -0,0 +1,279 @@
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const testKey = "xai-0123456789abcdefghijklmnop"
+
+func completionRequest(text string) *xaiv1.GetCompletionsRequest {
+	return &xaiv1.GetCompletionsRequest{
+		Model: "grok-4",
+		Messages: []*xaiv1.Message{{
+			Role:    xaiv1.MessageRole_ROLE_USER,
+			Content: []*xaiv1.Content{{Content: &xaiv1.Content_Text{Text: text}}},
+		}},
+	}
+}
+
+func newRecorder(t *testing.T, path string, mode Mode, opts ...Option) *Recorder {
+	t.Helper()
+	r, err := New(path, mode, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// noNetwork fails the test if a replayed call reaches the network.
+func noNetwork(t *testing.T) grpc.UnaryInvoker {
+	return func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+		t.Fatal("replayed call reached the network")
+		return nil
+	}
+}
+
+func TestUnaryRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "chat.json")
+	const method = "/xai_api.Chat/GetCompletion"
+
+	rec := newRecorder(t, path, ModeReplayOrRecord)
+	if !rec.Recording() {
+		t.Fatal("a missing cassette should be recorded")
+	}
+	server := func(_ context.Context, _ string, req, reply interface{}, _ *grpc.ClientConn, opts ...grpc.CallOption) error {
+		for _, opt := range opts {
+			if h, ok := opt.(grpc.HeaderCallOption); ok {
+				*h.HeaderAddr = metadata.Pairs("x-request-id", "req-1", "authorization", "Bearer "+testKey)
+			}
+		}
+		if strings.Contains(req.(*xaiv1.GetCompletionsRequest).String(), "missing") {
+			return status.Error(codes.NotFound, "no such model")
+		}
+		proto.Merge(reply.(proto.Message), &xaiv1.GetChatCompletionResponse{
+			Id:      "resp-1",
+			Outputs: []*xaiv1.CompletionOutput{{Message: &xaiv1.CompletionMessage{Content: "Hello!"}}},
+		})
+		return nil
+	}
+	interceptor := rec.UnaryInterceptor()
+	if err := interceptor(context.Background(), method, completionRequest("Hi, my key is "+testKey), &xaiv1.GetChatCompletionResponse{}, nil, server); err != nil {
+		t.Fatal(err)
+	}
+	if err := interceptor(context.Background(), method, completionRequest("missing"), &xaiv1.GetChatCompletionResponse{}, nil, server); status.Code(err) != codes.NotFound {
+		t.Fatalf("error = %v", err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(testKey)) || bytes.Contains(data, []byte("authorization")) {
+		t.Errorf("the cassette contains secrets:\n%s", data)
+	}
+
+	replay := newRecorder(t, path, ModeReplayOrRecord)
+	if replay.Recording() {
+		t.Fatal("an existing cassette should be replayed")
+	}
+	interceptor = replay.UnaryInterceptor()
+	var header metadata.MD
+	reply := &xaiv1.GetChatCompletionResponse{}
+	// The recorded request was redacted, and so is the replayed one.
+	err = interceptor(context.Background(), method, completionRequest("Hi, my key is "+testKey), reply, nil, noNetwork(t), grpc.Header(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.GetId() != "resp-1" || reply.GetOutputs()[0].GetMessage().GetContent() != "Hello!" {
+		t.Errorf("reply = %v", reply)
+	}
+	if got := header.Get("x-request-id"); len(got) != 1 || got[0] != "req-1" {
+		t.Errorf("header = %v", header)
+	}
+	err = interceptor(context.Background(), method, completionRequest("missing"), &xaiv1.GetChatCompletionResponse{}, nil, noNetwork(t))
+	if st, _ := status.FromError(err); st.Code() != codes.NotFound || st.Message() != "no such model" {
+		t.Errorf("error = %v", err)
+	}
+	err = interceptor(context.Background(), method, completionRequest("never recorded"), &xaiv1.GetChatCompletionResponse{}, nil, noNetwork(t))
+	if !errors.Is(err, ErrInteractionNotFound) {
+		t.Errorf("error = %v, want ErrInteractionNotFound", err)
+	}
+}
+
+// fakeStream is a server stream returning chunks.
+type fakeStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	chunks []*xaiv1.GetChatCompletionChunk
+}
+
+func (s *fakeStream) Context() context.Context  { return s.ctx }
+func (s *fakeStream) SendMsg(interface{}) error { return nil }
+func (s *fakeStream) CloseSend() error          { return nil }
+func (s *fakeStream) Header() (metadata.MD, error) {
+	return metadata.Pairs("x-request-id", "req-2"), nil
+}
+func (s *fakeStream) RecvMsg(m interface{}) error {
+	if len(s.chunks) == 0 {
+		return status.Error(codes.Internal, "stream reset")
+	}
+	proto.Merge(m.(proto.Message), s.chunks[0])
+	s.chunks = s.chunks[1:]
+	return nil
+}
+
+// readStream opens a stream through interceptor and reads it to the end.
+func readStream(t *testing.T, interceptor grpc.StreamClientInterceptor, streamer grpc.Streamer) ([]string, metadata.MD, error) {
+	t.Helper()
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	stream, err := interceptor(context.Background(), desc, nil, "/xai_api.Chat/GetCompletionChunk", streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendMsg(completionRequest("Count to two")); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	for {
+		chunk := &xaiv1.GetChatCompletionChunk{}
+		if err := stream.RecvMsg(chunk); err != nil {
+			header, _ := stream.Header()
+			return contents, header, err
+		}
+		contents = append(contents, chunk.GetOutputs()[0].GetDelta().GetContent())
+	}
+}
+
+func TestStreamRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stream.json")
+	rec := newRecorder(t, path, ModeRecord)
+	streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeStream{ctx: ctx, chunks: []*xaiv1.GetChatCompletionChunk{
+			{Outputs: []*xaiv1.CompletionOutputChunk{{Delta: &xaiv1.Delta{Content: "One"}}}},
+			{Outputs: []*xaiv1.CompletionOutputChunk{{Delta: &xaiv1.Delta{Content: "Two"}}}},
+		}}, nil
+	}
+	recorded, _, recordErr := readStream(t, rec.StreamInterceptor(), streamer)
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if interactions := rec.Interactions(); len(interactions) != 1 || !interactions[0].GRPC.Stream {
+		t.Fatalf("interactions = %+v", interactions)
+	}
+
+	replay := newRecorder(t, path, ModeReplay)
+	contents, header, err := readStream(t, replay.StreamInterceptor(), func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		t.Fatal("replayed stream reached the network")
+		return nil, nil
+	})
+	if strings.Join(contents, ",") != strings.Join(recorded, ",") || len(contents) != 2 {
+		t.Errorf("replayed chunks = %v, recorded %v", contents, recorded)
+	}
+	if status.Code(err) != status.Code(recordErr) || status.Code(err) != codes.Internal {
+		t.Errorf("replayed error = %v, recorded %v", err, recordErr)
+	}
+	if got := header.Get("x-request-id"); len(got) != 1 || got[0] != "req-2" {
+		t.Errorf("header = %v", header)
+	}
+}
+
+// multipartBody returns a multipart body with a random boundary.
+func multipartBody(t *testing.T) (string, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile("file", "notes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write([]byte("some notes"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return w.FormDataContentType(), &buf
+}
+
+func TestHTTPRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-3")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			_, _ = io.WriteString(w, `{"id":"file-2"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"id":"file-1","filename":"notes.txt"}`)
+	}))
+	path := filepath.Join(t.TempDir(), "http.json")
+
+	send := func(client *http.Client) []string {
+		t.Helper()
+		var bodies []string
+		get, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/files/file-1?expand=true", nil)
+		get.Header.Set("Authorization", "Bearer "+testKey)
+		contentType, body := multipartBody(t)
+		post, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/files", body)
+		post.Header.Set("Content-Type", contentType)
+		for _, req := range []*http.Request{get, post} {
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.Header.Get("X-Request-Id") != "req-3" {
+				t.Errorf("response header = %v", resp.Header)
+			}
+			bodies = append(bodies, string(data))
+		}
+		return bodies
+	}
+
+	rec := newRecorder(t, path, ModeRecord)
+	recorded := send(rec.HTTPClient(nil))
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte(testKey)) || bytes.Contains(data, []byte("session=secret")) {
+		t.Errorf("the cassette contains secrets:\n%s", data)
+	}
+
+	// The server is gone: the requests, including a multipart body with a
+	// new boundary, are answered from the cassette.
+	replayed := send(newRecorder(t, path, ModeReplay).HTTPClient(nil))
+	if strings.Join(replayed, "|") != strings.Join(recorded, "|") {
+		t.Errorf("replayed %v, recorded %v", replayed, recorded)
+	}
+}
+
+func TestNewReplayMissingCassette(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing.json"), ModeReplay); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("error = %v, want os.ErrNotExist", err)
+	}
+}
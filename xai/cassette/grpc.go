@@ -0,0 +1,257 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// UnaryInterceptor returns an interceptor recording or replaying unary calls.
+// It should be the innermost interceptor, so that it sees each attempt.
+func (r *Recorder) UnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		request, err := r.marshalMessage(req)
+		if err != nil {
+			return err
+		}
+
+		if !r.recording {
+			interaction, err := r.find(Interaction{GRPC: &GRPCInteraction{Method: method, Requests: []json.RawMessage{request}}})
+			if err != nil {
+				return err
+			}
+			recorded := interaction.GRPC
+			setHeader(opts, recorded.Header)
+			if err := recorded.err(); err != nil {
+				return err
+			}
+			if len(recorded.Responses) == 0 {
+				return ErrInteractionNotFound
+			}
+			return unmarshalMessage(recorded.Responses[0], reply)
+		}
+
+		var header metadata.MD
+		err = invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...)
+		interaction := &GRPCInteraction{Method: method, Requests: []json.RawMessage{request}, Header: r.redactHeader(header)}
+		if err == nil {
+			response, marshalErr := r.marshalMessage(reply)
+			if marshalErr != nil {
+				return marshalErr
+			}
+			interaction.Responses = []json.RawMessage{response}
+		} else {
+			interaction.Status = r.marshalStatus(err)
+		}
+		r.record(Interaction{GRPC: interaction})
+		return err
+	}
+}
+
+// StreamInterceptor returns an interceptor recording or replaying streams.
+// It should be the innermost interceptor, so that it sees each attempt.
+func (r *Recorder) StreamInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		if !r.recording {
+			return &replayStream{recorder: r, ctx: ctx, method: method}, nil
+		}
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, err
+		}
+		s := &recordStream{ClientStream: stream, recorder: r, interaction: &GRPCInteraction{Method: method, Stream: true}}
+		s.stop = context.AfterFunc(stream.Context(), func() { s.finish(stream.Context().Err()) })
+		return s, nil
+	}
+}
+
+// err returns the recorded error, or nil.
+func (g *GRPCInteraction) err() error {
+	if len(g.Status) == 0 {
+		return nil
+	}
+	var st status.Status
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(g.Status, &st); err != nil {
+		return err
+	}
+	return grpcstatus.ErrorProto(&st)
+}
+
+// marshalStatus returns the redacted proto JSON of the status of err.
+func (r *Recorder) marshalStatus(err error) json.RawMessage {
+	data, marshalErr := protojson.Marshal(grpcstatus.Convert(err).Proto())
+	if marshalErr != nil {
+		data, _ = protojson.Marshal(grpcstatus.New(grpcstatus.Code(err), err.Error()).Proto())
+	}
+	return canonicalJSON([]byte(r.redact(string(data))))
+}
+
+// setHeader sets the recorded header in the grpc.Header call options.
+func setHeader(opts []grpc.CallOption, header map[string][]string) {
+	for _, opt := range opts {
+		if h, ok := opt.(grpc.HeaderCallOption); ok {
+			*h.HeaderAddr = metadata.MD(header).Copy()
+		}
+	}
+}
+
+// recordStream records a stream as it is read.
+type recordStream struct {
+	grpc.ClientStream
+	recorder *Recorder
+	stop     func() bool
+
+	mu          sync.Mutex
+	interaction *GRPCInteraction
+	done        bool
+}
+
+func (s *recordStream) SendMsg(m interface{}) error {
+	request, err := s.recorder.marshalMessage(m)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.interaction.Requests = append(s.interaction.Requests, request)
+	s.mu.Unlock()
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *recordStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			s.finish(nil)
+		} else {
+			s.finish(err)
+		}
+		return err
+	}
+	response, marshalErr := s.recorder.marshalMessage(m)
+	if marshalErr != nil {
+		return marshalErr
+	}
+	s.mu.Lock()
+	s.interaction.Responses = append(s.interaction.Responses, response)
+	s.mu.Unlock()
+	return nil
+}
+
+// finish records the stream once, when it ends or its context is done.
+func (s *recordStream) finish(err error) {
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return
+	}
+	s.done = true
+	interaction := s.interaction
+	s.mu.Unlock()
+
+	if s.stop != nil {
+		s.stop()
+	}
+	if header, headerErr := s.ClientStream.Header(); headerErr == nil {
+		interaction.Header = s.recorder.redactHeader(header)
+	}
+	if err != nil {
+		interaction.Status = s.recorder.marshalStatus(err)
+	}
+	s.recorder.record(Interaction{GRPC: interaction})
+}
+
+// replayStream replays a recorded stream. The interaction is looked up when
+// the stream is first read, once all requests have been sent.
+type replayStream struct {
+	recorder *Recorder
+	ctx      context.Context
+	method   string
+
+	mu       sync.Mutex
+	requests []json.RawMessage
+	recorded *GRPCInteraction
+	err      error
+	next     int
+}
+
+// match looks up the interaction of the stream; s.mu must be held.
+func (s *replayStream) match() error {
+	if s.recorded == nil && s.err == nil {
+		interaction, err := s.recorder.find(Interaction{GRPC: &GRPCInteraction{Method: s.method, Stream: true, Requests: s.requests}})
+		s.recorded, s.err = interaction.GRPC, err
+	}
+	return s.err
+}
+
+func (s *replayStream) Header() (metadata.MD, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.match(); err != nil {
+		return nil, err
+	}
+	return metadata.MD(s.recorded.Header).Copy(), nil
+}
+
+func (s *replayStream) Trailer() metadata.MD {
+	return nil
+}
+
+func (s *replayStream) CloseSend() error {
+	return nil
+}
+
+func (s *replayStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *replayStream) SendMsg(m interface{}) error {
+	request, err := s.recorder.marshalMessage(m)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *replayStream) RecvMsg(m interface{}) error {
+	if err := s.ctx.Err(); err != nil {
+		return grpcstatus.FromContextError(err).Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.match(); err != nil {
+		return err
+	}
+	if s.next < len(s.recorded.Responses) {
+		s.next++
+		return unmarshalMessage(s.recorded.Responses[s.next-1], m)
+	}
+	if err := s.recorded.err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
@@ -0,0 +1,139 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// multipartBoundary replaces the random boundaries of multipart bodies.
+const multipartBoundary = "cassette-boundary"
+
+// HTTPClient returns a copy of base (default: http.DefaultClient) whose
+// transport records or replays requests.
+func (r *Recorder) HTTPClient(base *http.Client) *http.Client {
+	if base == nil {
+		base = http.DefaultClient
+	}
+	client := *base
+	client.Transport = r.Transport(base.Transport)
+	return &client
+}
+
+// Transport returns a transport recording the requests sent through base
+// (default: http.DefaultTransport), or replaying them.
+func (r *Recorder) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{recorder: r, base: base}
+}
+
+type transport struct {
+	recorder *Recorder
+	base     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := t.recorder
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	url := req.URL.EscapedPath()
+	if req.URL.RawQuery != "" {
+		url += "?" + req.URL.RawQuery
+	}
+	request := &HTTPInteraction{Method: req.Method, URL: r.redact(url), RequestBody: r.requestBody(req, body)}
+
+	if !r.recording {
+		interaction, err := r.find(Interaction{HTTP: request})
+		if err != nil {
+			return nil, err
+		}
+		return interaction.HTTP.response(req)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	request.StatusCode = resp.StatusCode
+	request.Header = r.redactHeader(resp.Header)
+	delete(request.Header, "Set-Cookie")
+	if utf8.Valid(respBody) {
+		request.Body = r.redact(string(respBody))
+	} else {
+		request.Body, request.BodyBase64 = base64.StdEncoding.EncodeToString(respBody), true
+	}
+	r.record(Interaction{HTTP: request})
+	return resp, nil
+}
+
+// readRequestBody reads the body of req, leaving it readable.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cassette: failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// requestBody returns the body of req as recorded: canonical JSON, multipart
+// content with a fixed boundary, other text, or the base64 of binary content.
+func (r *Recorder) requestBody(req *http.Request, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	mediaType, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		body = bytes.ReplaceAll(body, []byte(params["boundary"]), []byte(multipartBoundary))
+	}
+	if !utf8.Valid(body) {
+		return "base64:" + base64.StdEncoding.EncodeToString(body)
+	}
+	return string(canonicalJSON([]byte(r.redact(string(body)))))
+}
+
+// response builds the recorded response to req.
+func (h *HTTPInteraction) response(req *http.Request) (*http.Response, error) {
+	body := []byte(h.Body)
+	if h.BodyBase64 {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(h.Body); err != nil {
+			return nil, fmt.Errorf("cassette: invalid response body: %w", err)
+		}
+	}
+	header := make(http.Header, len(h.Header))
+	for key, values := range h.Header {
+		header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", h.StatusCode, http.StatusText(h.StatusCode)),
+		StatusCode:    h.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,175 @@
+// Package chat provides accumulation of streamed chunks into complete responses.
+package chat
+
+import (
+	"sort"
+	"strings"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// responseAccumulator incrementally builds a GetChatCompletionResponse from
+// streamed chunks so that a streamed turn has the same shape as a sampled one.
+type responseAccumulator struct {
+	proto *xaiv1.GetChatCompletionResponse
+}
+
+func newResponseAccumulator() *responseAccumulator {
+	return &responseAccumulator{proto: &xaiv1.GetChatCompletionResponse{}}
+}
+
+// apply merges a chunk into the accumulated response.
+func (a *responseAccumulator) apply(chunk *xaiv1.GetChatCompletionChunk) {
+	if chunk == nil {
+		return
+	}
+	resp := a.proto
+	if chunk.Id != "" {
+		resp.Id = chunk.Id
+	}
+	if chunk.Model != "" {
+		resp.Model = chunk.Model
+	}
+	if chunk.Created != nil {
+		resp.Created = chunk.Created
+	}
+	if chunk.SystemFingerprint != "" {
+		resp.SystemFingerprint = chunk.SystemFingerprint
+	}
+	if chunk.Usage != nil {
+		resp.Usage = chunk.Usage
+	}
+	if len(chunk.Citations) > 0 {
+		resp.Citations = chunk.Citations
+	}
+	if chunk.DebugOutput != nil {
+		resp.DebugOutput = chunk.DebugOutput
+	}
+
+	for _, output := range chunk.Outputs {
+		if output == nil {
+			continue
+		}
+		mergeOutputChunk(a.output(output.Index), output)
+	}
+}
+
+// output returns the accumulated output with the given index, creating it in index order.
+func (a *responseAccumulator) output(index int32) *xaiv1.CompletionOutput {
+	outputs := a.proto.Outputs
+	pos := sort.Search(len(outputs), func(i int) bool { return outputs[i].Index >= index })
+	if pos < len(outputs) && outputs[pos].Index == index {
+		return outputs[pos]
+	}
+	created := &xaiv1.CompletionOutput{Index: index, Message: &xaiv1.CompletionMessage{}}
+	outputs = append(outputs, nil)
+	copy(outputs[pos+1:], outputs[pos:])
+	outputs[pos] = created
+	a.proto.Outputs = outputs
+	return created
+}
+
+// response returns the accumulated response. The returned value is updated in
+// place as more chunks are applied.
+func (a *responseAccumulator) response() *Response {
+	return &Response{proto: a.proto}
+}
+
+// mergeOutputChunk merges a streamed output chunk into an accumulated output.
+func mergeOutputChunk(current *xaiv1.CompletionOutput, output *xaiv1.CompletionOutputChunk) {
+	current.Index = output.Index
+	if current.Message == nil {
+		current.Message = &xaiv1.CompletionMessage{}
+	}
+	if output.FinishReason != xaiv1.FinishReason_REASON_INVALID {
+		current.FinishReason = output.FinishReason
+	}
+	if output.Logprobs != nil {
+		if current.Logprobs == nil {
+			current.Logprobs = &xaiv1.LogProbs{}
+		}
+		current.Logprobs.Content = append(current.Logprobs.Content, output.Logprobs.Content...)
+	}
+
+	delta := output.Delta
+	if delta == nil {
+		return
+	}
+	msg := current.Message
+	if delta.Role != xaiv1.MessageRole_INVALID_ROLE {
+		msg.Role = delta.Role
+	}
+	msg.Content += delta.GetContent()
+	msg.ReasoningContent += delta.ReasoningContent
+	msg.EncryptedContent += delta.EncryptedContent
+	msg.Citations = append(msg.Citations, delta.Citations...)
+	for _, call := range delta.ToolCalls {
+		msg.ToolCalls = mergeToolCallDelta(msg.ToolCalls, call)
+	}
+}
+
+// mergeToolCallDelta merges a streamed tool call into the accumulated list.
+// Calls are matched by ID; a repeated call updates status and error, and its
+// arguments are treated as cumulative when they extend the accumulated arguments
+// and as an increment otherwise. A call without an ID continues the last call.
+func mergeToolCallDelta(calls []*xaiv1.ToolCall, delta *xaiv1.ToolCall) []*xaiv1.ToolCall {
+	if delta == nil {
+		return calls
+	}
+
+	existing := findToolCall(calls, delta)
+	if existing == nil {
+		cloned, ok := proto.Clone(delta).(*xaiv1.ToolCall)
+		if !ok {
+			return calls
+		}
+		return append(calls, cloned)
+	}
+
+	if delta.Type != xaiv1.ToolCallType_TOOL_CALL_TYPE_INVALID {
+		existing.Type = delta.Type
+	}
+	if delta.Status != xaiv1.ToolCallStatus_TOOL_CALL_STATUS_IN_PROGRESS {
+		existing.Status = delta.Status
+	}
+	if delta.ErrorMessage != nil {
+		errorMessage := delta.GetErrorMessage()
+		existing.ErrorMessage = &errorMessage
+	}
+	fn := delta.GetFunction()
+	if fn == nil {
+		return calls
+	}
+	existingFn := existing.GetFunction()
+	if existingFn == nil {
+		existing.Tool = &xaiv1.ToolCall_Function{Function: &xaiv1.FunctionCall{Name: fn.Name, Arguments: fn.Arguments}}
+		return calls
+	}
+	if fn.Name != "" {
+		existingFn.Name = fn.Name
+	}
+	if strings.HasPrefix(fn.Arguments, existingFn.Arguments) {
+		existingFn.Arguments = fn.Arguments
+	} else {
+		existingFn.Arguments += fn.Arguments
+	}
+	return calls
+}
+
+// findToolCall returns the accumulated call a delta belongs to: the call with the
+// same ID, or the last call when the delta has no ID.
+func findToolCall(calls []*xaiv1.ToolCall, delta *xaiv1.ToolCall) *xaiv1.ToolCall {
+	if delta == nil || len(calls) == 0 {
+		return nil
+	}
+	if delta.Id == "" {
+		return calls[len(calls)-1]
+	}
+	for _, call := range calls {
+		if call.Id == delta.Id {
+			return call
+		}
+	}
+	return nil
+}
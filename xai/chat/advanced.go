@@ -40,6 +40,15 @@ func (rs *RequestSettings) ReasoningEffort() string {
 	return reasoningEffortFromProto(*rs.proto.ReasoningEffort)
 }
 
+// ToolChoice returns the effective tool choice, including the forced function
+// for ToolChoiceFunction choices, or "" if none was reported.
+func (rs *RequestSettings) ToolChoice() ToolChoice {
+	if rs.proto == nil {
+		return ""
+	}
+	return toolChoiceFromProto(rs.proto.ToolChoice)
+}
+
 // Temperature returns the temperature setting.
 func (rs *RequestSettings) Temperature() float32 {
 	if rs.proto == nil || rs.proto.Temperature == nil {
@@ -4,13 +4,16 @@ package chat
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"strings"
 
 	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/cost"
+	xaierrors "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
@@ -173,6 +176,8 @@ type Stream struct {
 	stream  xaiv1.Chat_GetCompletionChunkClient
 	err     error
 	current *Chunk
+	acc     *responseAccumulator
+	cancel  context.CancelFunc
 }
 
 // BatchStream represents a multi-output streaming chat completion response.
@@ -182,6 +187,7 @@ type BatchStream struct {
 	responses     []*Response
 	currentChunks []*Chunk
 	count         int32
+	cancel        context.CancelFunc
 }
 
 // NewRequest creates a new chat completion request.
@@ -327,33 +333,41 @@ func WithServerTool(tools ...*ServerTool) RequestOption {
 // Tool results are added as user messages with tool role.
 func WithToolResults(results ...ToolResult) RequestOption {
 	return func(r *Request) {
-		for _, result := range results {
-			var content string
-			if result.Error() != nil {
-				content = *result.Error()
-			} else if str, ok := result.Result().(string); ok {
-				content = str
-			} else {
-				// Convert to JSON string if not a string
-				if jsonData, err := json.Marshal(result.Result()); err == nil {
-					content = string(jsonData)
-				} else {
-					content = fmt.Sprintf("%v", result.Result())
-				}
-			}
+		for i := range results {
+			r.proto.Messages = append(r.proto.Messages, toolResultMessage(&results[i]))
+		}
+	}
+}
 
-			msg := &xaiv1.Message{
-				Role: xaiv1.MessageRole_ROLE_TOOL,
-				Content: []*xaiv1.Content{{
-					Content: &xaiv1.Content_Text{
-						Text: content,
-					},
-				}},
-				// Additional tool call info would be added here
-			}
-			r.proto.Messages = append(r.proto.Messages, msg)
+// toolResultMessage converts a tool result into a tool-role message that
+// references the originating tool call.
+func toolResultMessage(result *ToolResult) *xaiv1.Message {
+	var content string
+	if result.Error() != nil {
+		content = *result.Error()
+	} else if str, ok := result.Result().(string); ok {
+		content = str
+	} else {
+		// Convert to JSON string if not a string
+		if jsonData, err := json.Marshal(result.Result()); err == nil {
+			content = string(jsonData)
+		} else {
+			content = fmt.Sprintf("%v", result.Result())
 		}
 	}
+
+	msg := &xaiv1.Message{
+		Role: xaiv1.MessageRole_ROLE_TOOL,
+		Content: []*xaiv1.Content{{
+			Content: &xaiv1.Content_Text{
+				Text: content,
+			},
+		}},
+	}
+	if id := result.ToolCallID(); id != "" {
+		msg.ToolCallId = &id
+	}
+	return msg
 }
 
 // SetModel sets the model for the request.
@@ -384,35 +398,41 @@ func (r *Request) AppendResponse(resp *Response) *Request {
 
 	// Append all outputs from the response
 	for _, output := range resp.proto.Outputs {
-		if output == nil || output.Message == nil {
-			continue
+		if msg := outputMessage(output); msg != nil {
+			r.proto.Messages = append(r.proto.Messages, msg)
 		}
+	}
 
-		// Convert string content to Content array
-		var contents []*xaiv1.Content
-		if output.Message.Content != "" {
-			contents = []*xaiv1.Content{
-				{
-					Content: &xaiv1.Content_Text{
-						Text: output.Message.Content,
-					},
-				},
-			}
-		}
+	return r
+}
 
-		// Create message with all fields from the response
-		msg := &xaiv1.Message{
-			Role:             output.Message.Role,
-			Content:          contents,
-			ToolCalls:        output.Message.ToolCalls,
-			ReasoningContent: &output.Message.ReasoningContent,
-			EncryptedContent: output.Message.EncryptedContent,
-		}
+// outputMessage converts a completion output into an assistant message that
+// carries content, tool calls, reasoning content and encrypted content forward.
+func outputMessage(output *xaiv1.CompletionOutput) *xaiv1.Message {
+	if output == nil || output.Message == nil {
+		return nil
+	}
 
-		r.proto.Messages = append(r.proto.Messages, msg)
+	// Convert string content to Content array
+	var contents []*xaiv1.Content
+	if output.Message.Content != "" {
+		contents = []*xaiv1.Content{
+			{
+				Content: &xaiv1.Content_Text{
+					Text: output.Message.Content,
+				},
+			},
+		}
 	}
 
-	return r
+	// Create message with all fields from the response
+	return &xaiv1.Message{
+		Role:             output.Message.Role,
+		Content:          contents,
+		ToolCalls:        output.Message.ToolCalls,
+		ReasoningContent: &output.Message.ReasoningContent,
+		EncryptedContent: output.Message.EncryptedContent,
+	}
 }
 
 // SetMessages sets all messages for the request.
@@ -734,16 +754,18 @@ func (r *Request) SetTools(tools ...Tool) *Request {
 	return r
 }
 
-// SetToolChoice sets the tool choice for the request.
+// SetToolChoice sets the tool choice for the request. Use ToolChoiceFunction
+// to force a call to a specific function tool.
 func (r *Request) SetToolChoice(choice ToolChoice) *Request {
-	r.proto.ToolChoice = &xaiv1.ToolChoice{
-		ToolChoice: &xaiv1.ToolChoice_Mode{
-			Mode: toolModeToProto(string(choice)),
-		},
-	}
+	r.proto.ToolChoice = toolChoiceToProto(choice)
 	return r
 }
 
+// ToolChoice returns the tool choice of the request, or "" if none is set.
+func (r *Request) ToolChoice() ToolChoice {
+	return toolChoiceFromProto(r.proto.GetToolChoice())
+}
+
 // Protos returns the underlying protobuf request.
 func (r *Request) Proto() *xaiv1.GetCompletionsRequest {
 	return r.proto
@@ -805,28 +827,7 @@ func (r *Request) Sample(ctx context.Context, client ServiceClient) (*Response,
 
 	resp, err := client.GetCompletion(ctx, r.proto)
 	if err != nil {
-		// Handle specific gRPC errors
-		if st, ok := status.FromError(err); ok {
-			switch st.Code() {
-			case codes.Unauthenticated:
-				return nil, fmt.Errorf("authentication failed: %s", st.Message())
-			case codes.PermissionDenied:
-				return nil, fmt.Errorf("permission denied: %s", st.Message())
-			case codes.InvalidArgument:
-				return nil, fmt.Errorf("invalid request: %s", st.Message())
-			case codes.NotFound:
-				return nil, fmt.Errorf("model not found: %s", st.Message())
-			case codes.ResourceExhausted:
-				return nil, fmt.Errorf("quota exceeded: %s", st.Message())
-			case codes.Unavailable:
-				return nil, fmt.Errorf("service unavailable: %s", st.Message())
-			case codes.DeadlineExceeded:
-				return nil, fmt.Errorf("request timeout: %s", st.Message())
-			default:
-				return nil, fmt.Errorf("chat completion failed (%s): %s", st.Code().String(), st.Message())
-			}
-		}
-		return nil, fmt.Errorf("chat completion failed: %w", err)
+		return nil, grpcError("chat completion failed", err)
 	}
 
 	if resp == nil {
@@ -855,7 +856,7 @@ func (r *Request) SampleBatch(ctx context.Context, client ServiceClient, n int32
 
 	resp, err := client.GetCompletion(ctx, reqProto)
 	if err != nil {
-		return nil, fmt.Errorf("chat batch completion failed: %w", err)
+		return nil, grpcError("chat batch completion failed", err)
 	}
 	if resp == nil {
 		return nil, fmt.Errorf("received nil response")
@@ -863,6 +864,16 @@ func (r *Request) SampleBatch(ctx context.Context, client ServiceClient, n int32
 	return splitResponses(resp), nil
 }
 
+// grpcError wraps an error returned by the chat service. gRPC status errors are
+// converted to *errors.APIError so that callers can inspect the status code,
+// request ID and retry delay with errors.As or errors.Is.
+func grpcError(op string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		err = xaierrors.FromGRPC(err)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
+
 // Stream performs a streaming chat completion request.
 func (r *Request) Stream(ctx context.Context, client ServiceClient) (*Stream, error) {
 	if client == nil {
@@ -880,37 +891,19 @@ func (r *Request) Stream(ctx context.Context, client ServiceClient) (*Stream, er
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	stream, err := client.GetCompletionChunk(ctx, r.proto)
+	streamCtx, cancel := context.WithCancel(ctx)
+	stream, err := client.GetCompletionChunk(streamCtx, r.proto)
 	if err != nil {
-		// Handle specific gRPC errors
-		if st, ok := status.FromError(err); ok {
-			switch st.Code() {
-			case codes.Unauthenticated:
-				return nil, fmt.Errorf("authentication failed: %s", st.Message())
-			case codes.PermissionDenied:
-				return nil, fmt.Errorf("permission denied: %s", st.Message())
-			case codes.InvalidArgument:
-				return nil, fmt.Errorf("invalid request: %s", st.Message())
-			case codes.NotFound:
-				return nil, fmt.Errorf("model not found: %s", st.Message())
-			case codes.ResourceExhausted:
-				return nil, fmt.Errorf("quota exceeded: %s", st.Message())
-			case codes.Unavailable:
-				return nil, fmt.Errorf("service unavailable: %s", st.Message())
-			case codes.DeadlineExceeded:
-				return nil, fmt.Errorf("request timeout: %s", st.Message())
-			default:
-				return nil, fmt.Errorf("chat completion stream failed (%s): %s", st.Code().String(), st.Message())
-			}
-		}
-		return nil, fmt.Errorf("chat completion stream failed: %w", err)
+		cancel()
+		return nil, grpcError("chat completion stream failed", err)
 	}
 
 	if stream == nil {
+		cancel()
 		return nil, fmt.Errorf("received nil stream")
 	}
 
-	return &Stream{stream: stream}, nil
+	return &Stream{stream: stream, cancel: cancel}, nil
 }
 
 // StreamBatch performs a multi-output streaming chat completion request.
@@ -936,9 +929,11 @@ func (r *Request) StreamBatch(ctx context.Context, client ServiceClient, n int32
 		return nil, err
 	}
 
-	stream, err := client.GetCompletionChunk(ctx, reqProto)
+	streamCtx, cancel := context.WithCancel(ctx)
+	stream, err := client.GetCompletionChunk(streamCtx, reqProto)
 	if err != nil {
-		return nil, fmt.Errorf("chat completion batch stream failed: %w", err)
+		cancel()
+		return nil, grpcError("chat completion batch stream failed", err)
 	}
 
 	responses := make([]*Response, n)
@@ -957,6 +952,7 @@ func (r *Request) StreamBatch(ctx context.Context, client ServiceClient, n int32
 		stream:    stream,
 		responses: responses,
 		count:     n,
+		cancel:    cancel,
 	}, nil
 }
 
@@ -974,6 +970,10 @@ func (r *Request) validate() error {
 		return err
 	}
 
+	if err := r.validateToolChoice(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -1025,6 +1025,20 @@ func (r *Request) validateMessage(msg *xaiv1.Message, index int) error {
 	return nil
 }
 
+// validateToolChoice checks that a forced function is one of the request's tools.
+func (r *Request) validateToolChoice() error {
+	name := r.proto.GetToolChoice().GetFunctionName()
+	if name == "" {
+		return nil
+	}
+	for _, tool := range r.proto.Tools {
+		if tool.GetFunction().GetName() == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("tool choice function %q is not among the request's tools", name)
+}
+
 // WithToolChoice adds tool choice to the request as a functional option.
 func WithToolChoice(choice ToolChoice) RequestOption {
 	return func(r *Request) {
@@ -1044,6 +1058,25 @@ const (
 	ToolChoiceRequired ToolChoice = "required"
 )
 
+// toolChoiceFunctionPrefix marks a tool choice that forces a specific function.
+const toolChoiceFunctionPrefix = "function:"
+
+// ToolChoiceFunction returns a tool choice that forces the model to call the
+// function tool with the given name. The function must be one of the request's
+// tools; this is checked when the request is sent.
+func ToolChoiceFunction(name string) ToolChoice {
+	return ToolChoice(toolChoiceFunctionPrefix + name)
+}
+
+// FunctionName returns the name of the forced function, or "" if the tool
+// choice is a mode such as ToolChoiceAuto.
+func (c ToolChoice) FunctionName() string {
+	if name, ok := strings.CutPrefix(string(c), toolChoiceFunctionPrefix); ok {
+		return name
+	}
+	return ""
+}
+
 // WithResponseFormat adds response format to the request.
 func WithResponseFormat(format ResponseFormat) RequestOption {
 	return func(r *Request) {
@@ -1129,8 +1162,10 @@ func parseToolCall(protoCall *xaiv1.ToolCall) *ToolCall {
 
 	// Get function name
 	name := ""
+	rawArguments := ""
 	if fn != nil {
 		name = fn.Name
+		rawArguments = fn.Arguments
 	}
 
 	// Get error message
@@ -1143,6 +1178,7 @@ func parseToolCall(protoCall *xaiv1.ToolCall) *ToolCall {
 		id:           protoCall.Id,
 		name:         name,
 		arguments:    arguments,
+		rawArguments: rawArguments,
 		status:       protoCall.Status.String(),
 		errorMessage: errorMessage,
 		toolType:     toolCallTypeFromProto(protoCall.Type),
@@ -1541,43 +1577,63 @@ func (s *Stream) Next() bool {
 	// Receive next chunk
 	chunk, err := s.stream.Recv()
 	if err != nil {
+		s.release()
 		if err == io.EOF {
 			// Normal stream termination
 			s.err = io.EOF
 			return false
 		}
 
-		// Handle gRPC stream errors
-		if st, ok := status.FromError(err); ok {
-			switch st.Code() {
-			case codes.Unauthenticated:
-				s.err = fmt.Errorf("authentication failed: %s", st.Message())
-			case codes.PermissionDenied:
-				s.err = fmt.Errorf("permission denied: %s", st.Message())
-			case codes.InvalidArgument:
-				s.err = fmt.Errorf("invalid request: %s", st.Message())
-			case codes.NotFound:
-				s.err = fmt.Errorf("model not found: %s", st.Message())
-			case codes.ResourceExhausted:
-				s.err = fmt.Errorf("quota exceeded: %s", st.Message())
-			case codes.Unavailable:
-				s.err = fmt.Errorf("service unavailable: %s", st.Message())
-			case codes.DeadlineExceeded:
-				s.err = fmt.Errorf("request timeout: %s", st.Message())
-			default:
-				s.err = fmt.Errorf("stream error (%s): %s", st.Code().String(), st.Message())
-			}
-		} else {
-			s.err = fmt.Errorf("stream failed: %w", err)
-		}
+		s.err = grpcError("stream failed", err)
 		return false
 	}
 
-	// Set current chunk
+	// Set current chunk and fold it into the accumulated response
 	s.current = &Chunk{proto: chunk}
+	if s.acc == nil {
+		s.acc = newResponseAccumulator()
+	}
+	s.acc.apply(chunk)
 	return true
 }
 
+// Response returns the response accumulated from all chunks received so far.
+// It has the same shape as the response returned by Sample, so once the stream
+// is exhausted it can be passed to Request.AppendResponse or used for cost accounting.
+func (s *Stream) Response() *Response {
+	if s == nil {
+		return nil
+	}
+	if s.acc == nil {
+		s.acc = newResponseAccumulator()
+	}
+	return s.acc.response()
+}
+
+// All returns an iterator over the remaining chunks of the stream.
+// The end of the stream is not reported as an error; any other stream error is
+// yielded once with a nil chunk. Breaking out of the loop closes the stream.
+func (s *Stream) All() iter.Seq2[*Chunk, error] {
+	return func(yield func(*Chunk, error) bool) {
+		for s.Next() {
+			if !yield(s.current, nil) {
+				_ = s.Close()
+				return
+			}
+		}
+		if err := s.Err(); err != nil && !errors.Is(err, io.EOF) {
+			yield(nil, err)
+		}
+	}
+}
+
+// release cancels the stream context so the underlying gRPC stream is freed.
+func (s *Stream) release() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
 // Current returns the current chunk.
 func (s *Stream) Current() *Chunk {
 	return s.current
@@ -1594,10 +1650,13 @@ func (s *Stream) Close() error {
 		// Try to close the stream gracefully
 		err := s.stream.CloseSend()
 		if err != nil && err != io.EOF {
+			s.release()
 			return fmt.Errorf("failed to close stream: %w", err)
 		}
 	}
 
+	// Cancel the stream context so an unfinished stream is torn down
+	s.release()
 	return nil
 }
 
@@ -1643,28 +1702,7 @@ func applyChunkToResponse(resp *Response, chunk *xaiv1.GetChatCompletionChunk, o
 	if len(resp.proto.Outputs) == 0 || resp.proto.Outputs[0] == nil {
 		resp.proto.Outputs = []*xaiv1.CompletionOutput{{Index: output.Index, Message: &xaiv1.CompletionMessage{}}}
 	}
-	current := resp.proto.Outputs[0]
-	current.Index = output.Index
-	if current.Message == nil {
-		current.Message = &xaiv1.CompletionMessage{}
-	}
-	if output.Delta != nil {
-		if output.Delta.Role != xaiv1.MessageRole_INVALID_ROLE {
-			current.Message.Role = output.Delta.Role
-		}
-		if content := output.Delta.GetContent(); content != "" {
-			current.Message.Content += content
-		}
-		if output.Delta.ReasoningContent != "" {
-			current.Message.ReasoningContent += output.Delta.ReasoningContent
-		}
-		if output.Delta.EncryptedContent != "" {
-			current.Message.EncryptedContent += output.Delta.EncryptedContent
-		}
-		if len(output.Delta.ToolCalls) > 0 {
-			current.Message.ToolCalls = append(current.Message.ToolCalls, output.Delta.ToolCalls...)
-		}
-	}
+	mergeOutputChunk(resp.proto.Outputs[0], output)
 }
 
 // Next reads the next set of chunks from the batch stream.
@@ -1676,10 +1714,11 @@ func (s *BatchStream) Next() bool {
 
 	chunk, err := s.stream.Recv()
 	if err != nil {
+		s.release()
 		if err == io.EOF {
 			return false
 		}
-		s.err = err
+		s.err = grpcError("stream failed", err)
 		return false
 	}
 	if chunk == nil {
@@ -1721,3 +1760,43 @@ func (s *BatchStream) CurrentChunks() []*Chunk {
 func (s *BatchStream) Err() error {
 	return s.err
 }
+
+// All returns an iterator over the remaining per-output chunks of the batch stream.
+// Each step yields the chunks for every output, indexed like CurrentChunks. A
+// stream error is yielded once with nil chunks. Breaking out of the loop closes the stream.
+func (s *BatchStream) All() iter.Seq2[[]*Chunk, error] {
+	return func(yield func([]*Chunk, error) bool) {
+		for s.Next() {
+			if !yield(s.currentChunks, nil) {
+				_ = s.Close()
+				return
+			}
+		}
+		if s.err != nil {
+			yield(nil, s.err)
+		}
+	}
+}
+
+// Close closes the batch stream and releases its resources.
+func (s *BatchStream) Close() error {
+	if s == nil {
+		return nil
+	}
+	var err error
+	if s.stream != nil {
+		err = s.stream.CloseSend()
+	}
+	s.release()
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to close stream: %w", err)
+	}
+	return nil
+}
+
+// release cancels the stream context so the underlying gRPC stream is freed.
+func (s *BatchStream) release() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
@@ -0,0 +1,304 @@
+// Package chat provides stateful conversations for xAI SDK.
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// conversationFormatVersion is the version of the JSON encoding of a Conversation.
+const conversationFormatVersion = 1
+
+// Conversation owns the message history of a multi-turn chat.
+//
+// The system prompt is pinned and always sent first. Each applied response
+// ends a turn; Fork branches the conversation at a turn boundary. When message
+// storage is enabled (WithStoreMessages(true) or EnableServerState), requests
+// after the first only carry the new messages and reference the previous
+// response through previous_response_id, while the full history is still kept
+// locally for forking and serialization.
+//
+// A Conversation is not safe for concurrent use.
+type Conversation struct {
+	settings *xaiv1.GetCompletionsRequest
+	system   []*xaiv1.Message
+	messages []*xaiv1.Message
+	turns    []conversationTurn
+}
+
+// conversationTurn marks the end of a turn in the message history.
+type conversationTurn struct {
+	end        int
+	responseID string
+}
+
+// NewConversation creates a conversation for model. Request options configure
+// the settings used for every turn; system messages passed via WithMessage
+// become the pinned system prompt and any other messages start the history.
+func NewConversation(model string, opts ...RequestOption) *Conversation {
+	req := NewRequest(model, opts...)
+	c := &Conversation{settings: req.proto}
+	for _, msg := range req.proto.Messages {
+		if msg.GetRole() == xaiv1.MessageRole_ROLE_SYSTEM {
+			c.system = append(c.system, msg)
+		} else {
+			c.messages = append(c.messages, msg)
+		}
+	}
+	c.settings.Messages = nil
+	return c
+}
+
+// SetSystem replaces the pinned system prompt.
+func (c *Conversation) SetSystem(messages ...*Message) *Conversation {
+	c.system = c.system[:0]
+	for _, msg := range messages {
+		if msg != nil {
+			c.system = append(c.system, proto.Clone(msg.Proto()).(*xaiv1.Message))
+		}
+	}
+	return c
+}
+
+// EnableServerState turns on server-side message storage so that later turns
+// are chained through previous_response_id instead of resending the history.
+func (c *Conversation) EnableServerState() *Conversation {
+	c.settings.StoreMessages = true
+	return c
+}
+
+// Append adds messages, such as user input or tool results, to the history.
+func (c *Conversation) Append(messages ...*Message) *Conversation {
+	for _, msg := range messages {
+		if msg != nil {
+			c.messages = append(c.messages, proto.Clone(msg.Proto()).(*xaiv1.Message))
+		}
+	}
+	return c
+}
+
+// AppendToolResults adds tool results as tool messages to the history.
+func (c *Conversation) AppendToolResults(results ...*ToolResult) *Conversation {
+	for _, result := range results {
+		if result != nil {
+			c.messages = append(c.messages, toolResultMessage(result))
+		}
+	}
+	return c
+}
+
+// AppendResponse adds the assistant message of the first output of resp,
+// including tool calls and reasoning content, and ends the current turn.
+// Encrypted reasoning is kept only when encrypted content is enabled for the
+// conversation. Use it to apply responses obtained from Request() or a stream.
+func (c *Conversation) AppendResponse(resp *Response) *Conversation {
+	if resp == nil || resp.proto == nil {
+		return c
+	}
+	if len(resp.proto.Outputs) > 0 {
+		if msg := outputMessage(resp.proto.Outputs[0]); msg != nil {
+			msg = proto.Clone(msg).(*xaiv1.Message)
+			if !c.settings.UseEncryptedContent {
+				msg.EncryptedContent = ""
+			}
+			c.messages = append(c.messages, msg)
+		}
+	}
+	c.turns = append(c.turns, conversationTurn{end: len(c.messages), responseID: resp.proto.Id})
+	return c
+}
+
+// Request builds the request for the next turn. It contains the pinned system
+// prompt followed by the history or, when server-side state is in use, only the
+// messages added since the last response together with previous_response_id.
+func (c *Conversation) Request() *Request {
+	reqProto := proto.Clone(c.settings).(*xaiv1.GetCompletionsRequest)
+
+	var messages []*xaiv1.Message
+	if last := c.lastTurn(); c.settings.StoreMessages && last != nil && last.responseID != "" {
+		responseID := last.responseID
+		reqProto.PreviousResponseId = &responseID
+		messages = c.messages[last.end:]
+	} else {
+		messages = append(append(messages, c.system...), c.messages...)
+	}
+	reqProto.Messages = make([]*xaiv1.Message, len(messages))
+	for i, msg := range messages {
+		reqProto.Messages[i] = proto.Clone(msg).(*xaiv1.Message)
+	}
+	return &Request{proto: reqProto}
+}
+
+// Send appends messages, samples the next response and applies it to the conversation.
+func (c *Conversation) Send(ctx context.Context, client ServiceClient, messages ...*Message) (*Response, error) {
+	c.Append(messages...)
+	resp, err := c.Request().Sample(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	c.AppendResponse(resp)
+	return resp, nil
+}
+
+// Messages returns the pinned system prompt followed by the history.
+func (c *Conversation) Messages() []*Message {
+	messages := make([]*Message, 0, len(c.system)+len(c.messages))
+	for _, msg := range c.system {
+		messages = append(messages, &Message{proto: proto.Clone(msg).(*xaiv1.Message)})
+	}
+	for _, msg := range c.messages {
+		messages = append(messages, &Message{proto: proto.Clone(msg).(*xaiv1.Message)})
+	}
+	return messages
+}
+
+// Turns returns the number of completed turns.
+func (c *Conversation) Turns() int {
+	return len(c.turns)
+}
+
+// LastResponseID returns the ID of the last applied response.
+func (c *Conversation) LastResponseID() string {
+	if last := c.lastTurn(); last != nil {
+		return last.responseID
+	}
+	return ""
+}
+
+// Fork returns an independent copy of the conversation that keeps the first
+// turn turns and drops everything after them. Fork(c.Turns()) copies the whole
+// conversation including messages not yet answered.
+func (c *Conversation) Fork(turn int) (*Conversation, error) {
+	if turn < 0 || turn > len(c.turns) {
+		return nil, fmt.Errorf("turn %d out of range [0, %d]", turn, len(c.turns))
+	}
+
+	end := len(c.messages)
+	if turn < len(c.turns) {
+		end = 0
+		if turn > 0 {
+			end = c.turns[turn-1].end
+		}
+	}
+
+	fork := &Conversation{
+		settings: proto.Clone(c.settings).(*xaiv1.GetCompletionsRequest),
+		system:   cloneMessages(c.system),
+		messages: cloneMessages(c.messages[:end]),
+		turns:    append([]conversationTurn(nil), c.turns[:turn]...),
+	}
+	return fork, nil
+}
+
+func (c *Conversation) lastTurn() *conversationTurn {
+	if len(c.turns) == 0 {
+		return nil
+	}
+	return &c.turns[len(c.turns)-1]
+}
+
+func cloneMessages(messages []*xaiv1.Message) []*xaiv1.Message {
+	cloned := make([]*xaiv1.Message, len(messages))
+	for i, msg := range messages {
+		cloned[i] = proto.Clone(msg).(*xaiv1.Message)
+	}
+	return cloned
+}
+
+// conversationJSON is the serialized form of a Conversation.
+type conversationJSON struct {
+	Version  int                    `json:"version"`
+	Settings json.RawMessage        `json:"settings"`
+	System   []json.RawMessage      `json:"system,omitempty"`
+	Messages []json.RawMessage      `json:"messages"`
+	Turns    []conversationTurnJSON `json:"turns,omitempty"`
+}
+
+type conversationTurnJSON struct {
+	End        int    `json:"end"`
+	ResponseID string `json:"response_id,omitempty"`
+}
+
+// MarshalJSON encodes the conversation, including its settings, so that it can
+// be stored and resumed later. Messages are encoded with protojson.
+func (c *Conversation) MarshalJSON() ([]byte, error) {
+	settings, err := protojson.Marshal(c.settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode conversation settings: %w", err)
+	}
+	out := conversationJSON{
+		Version:  conversationFormatVersion,
+		Settings: settings,
+		Messages: make([]json.RawMessage, 0, len(c.messages)),
+	}
+	for _, msg := range c.system {
+		data, err := protojson.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode system message: %w", err)
+		}
+		out.System = append(out.System, data)
+	}
+	for i, msg := range c.messages {
+		data, err := protojson.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode message %d: %w", i, err)
+		}
+		out.Messages = append(out.Messages, data)
+	}
+	for _, turn := range c.turns {
+		out.Turns = append(out.Turns, conversationTurnJSON{End: turn.end, ResponseID: turn.responseID})
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON restores a conversation encoded by MarshalJSON.
+func (c *Conversation) UnmarshalJSON(data []byte) error {
+	var in conversationJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.Version != conversationFormatVersion {
+		return fmt.Errorf("unsupported conversation format version %d", in.Version)
+	}
+
+	settings := &xaiv1.GetCompletionsRequest{}
+	if err := protojson.Unmarshal(in.Settings, settings); err != nil {
+		return fmt.Errorf("failed to decode conversation settings: %w", err)
+	}
+	system, err := unmarshalMessages(in.System)
+	if err != nil {
+		return err
+	}
+	messages, err := unmarshalMessages(in.Messages)
+	if err != nil {
+		return err
+	}
+	turns := make([]conversationTurn, len(in.Turns))
+	for i, turn := range in.Turns {
+		if turn.End < 0 || turn.End > len(messages) || (i > 0 && turn.End < turns[i-1].end) {
+			return fmt.Errorf("invalid end %d for turn %d", turn.End, i)
+		}
+		turns[i] = conversationTurn{end: turn.End, responseID: turn.ResponseID}
+	}
+
+	settings.Messages = nil
+	*c = Conversation{settings: settings, system: system, messages: messages, turns: turns}
+	return nil
+}
+
+func unmarshalMessages(raw []json.RawMessage) ([]*xaiv1.Message, error) {
+	messages := make([]*xaiv1.Message, len(raw))
+	for i, data := range raw {
+		msg := &xaiv1.Message{}
+		if err := protojson.Unmarshal(data, msg); err != nil {
+			return nil, fmt.Errorf("failed to decode message %d: %w", i, err)
+		}
+		messages[i] = msg
+	}
+	return messages, nil
+}
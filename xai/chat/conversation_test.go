@@ -0,0 +1,163 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+)
+
+func responseWithID(id, content string) *xaiv1.GetChatCompletionResponse {
+	resp := textResponse(content)
+	resp.Id = id
+	resp.Outputs[0].Message.ReasoningContent = "thought about " + content
+	resp.Outputs[0].Message.EncryptedContent = "enc-" + id
+	return resp
+}
+
+func TestConversationSendKeepsHistory(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{
+		responseWithID("r1", "Hello"),
+		responseWithID("r2", "Fine"),
+	}}
+	conv := NewConversation("grok-4", WithMessage(System(Text("Be brief"))), WithTemperature(0.2))
+
+	if _, err := conv.Send(context.Background(), client, User(Text("Hi"))); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if _, err := conv.Send(context.Background(), client, User(Text("How are you?"))); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	second := client.requests[1]
+	if len(second.Messages) != 4 {
+		t.Fatalf("second request messages = %d, want 4", len(second.Messages))
+	}
+	if second.Messages[0].Role != xaiv1.MessageRole_ROLE_SYSTEM || second.GetTemperature() != 0.2 {
+		t.Errorf("system prompt or settings not carried: %v", second)
+	}
+	assistant := second.Messages[2]
+	if assistant.GetReasoningContent() != "thought about Hello" {
+		t.Errorf("reasoning content = %q", assistant.GetReasoningContent())
+	}
+	if assistant.EncryptedContent != "" {
+		t.Error("encrypted content must be dropped when it is not enabled")
+	}
+	if second.PreviousResponseId != nil {
+		t.Error("previous_response_id must not be set without message storage")
+	}
+	if conv.Turns() != 2 || conv.LastResponseID() != "r2" {
+		t.Errorf("turns = %d, last response = %q", conv.Turns(), conv.LastResponseID())
+	}
+}
+
+func TestConversationEncryptedContent(t *testing.T) {
+	conv := NewConversation("grok-4", WithUseEncryptedContent(true))
+	conv.Append(User(Text("Hi")))
+	conv.AppendResponse(&Response{proto: responseWithID("r1", "Hello")})
+
+	messages := conv.Request().Proto().Messages
+	if messages[1].EncryptedContent != "enc-r1" {
+		t.Errorf("encrypted content = %q, want enc-r1", messages[1].EncryptedContent)
+	}
+}
+
+func TestConversationServerState(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{
+		responseWithID("r1", "Hello"),
+		responseWithID("r2", "Fine"),
+	}}
+	conv := NewConversation("grok-4", WithMessage(System(Text("Be brief")))).EnableServerState()
+
+	for _, text := range []string{"Hi", "How are you?"} {
+		if _, err := conv.Send(context.Background(), client, User(Text(text))); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	first, second := client.requests[0], client.requests[1]
+	if !first.StoreMessages || first.PreviousResponseId != nil || len(first.Messages) != 2 {
+		t.Errorf("first request = %v", first)
+	}
+	if second.GetPreviousResponseId() != "r1" {
+		t.Errorf("previous_response_id = %q, want r1", second.GetPreviousResponseId())
+	}
+	if len(second.Messages) != 1 || second.Messages[0].Content[0].GetText() != "How are you?" {
+		t.Errorf("second request should only carry the new message: %v", second.Messages)
+	}
+	if len(conv.Messages()) != 5 {
+		t.Errorf("local history = %d messages, want 5", len(conv.Messages()))
+	}
+}
+
+func TestConversationFork(t *testing.T) {
+	conv := NewConversation("grok-4", WithMessage(System(Text("sys")))).EnableServerState()
+	conv.Append(User(Text("one")))
+	conv.AppendResponse(&Response{proto: responseWithID("r1", "1")})
+	conv.Append(User(Text("two")))
+	conv.AppendResponse(&Response{proto: responseWithID("r2", "2")})
+
+	fork, err := conv.Fork(1)
+	if err != nil {
+		t.Fatalf("Fork() error = %v", err)
+	}
+	if fork.Turns() != 1 || len(fork.Messages()) != 3 || fork.LastResponseID() != "r1" {
+		t.Errorf("fork: turns = %d, messages = %d, last = %q", fork.Turns(), len(fork.Messages()), fork.LastResponseID())
+	}
+
+	fork.Append(User(Text("two, differently")))
+	req := fork.Request().Proto()
+	if req.GetPreviousResponseId() != "r1" || len(req.Messages) != 1 {
+		t.Errorf("fork request = %v", req)
+	}
+	if len(conv.Messages()) != 5 {
+		t.Error("forking must not modify the original conversation")
+	}
+
+	if _, err := conv.Fork(3); err == nil {
+		t.Error("expected error for out-of-range turn")
+	}
+}
+
+func TestConversationJSONRoundTrip(t *testing.T) {
+	conv := NewConversation("grok-4",
+		WithMessage(System(Text("sys"))),
+		WithTool(NewTool("lookup", "Look something up")),
+		WithMaxTokens(256),
+	).EnableServerState()
+	conv.Append(User(Text("Hi")))
+	conv.AppendResponse(&Response{proto: toolCallResponse(functionCall("call-1", "lookup", `{"q":"x"}`))})
+	conv.AppendToolResults(NewToolResult("call-1", map[string]string{"answer": "42"}))
+
+	data, err := json.Marshal(conv)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var restored Conversation
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if restored.Turns() != 1 || restored.LastResponseID() != "resp-tools" {
+		t.Errorf("turns = %d, last = %q", restored.Turns(), restored.LastResponseID())
+	}
+	messages := restored.Messages()
+	if len(messages) != 4 {
+		t.Fatalf("messages = %d, want 4", len(messages))
+	}
+	if calls := messages[2].ToolCalls(); len(calls) != 1 || calls[0].ID() != "call-1" {
+		t.Errorf("tool calls = %v", calls)
+	}
+	if messages[3].ToolCallID() != "call-1" {
+		t.Errorf("tool result id = %q", messages[3].ToolCallID())
+	}
+	req := restored.Request().Proto()
+	if req.GetMaxTokens() != 256 || len(req.Tools) != 1 || !req.StoreMessages {
+		t.Errorf("settings not restored: %v", req)
+	}
+
+	if err := json.Unmarshal([]byte(`{"version":99,"settings":{},"messages":[]}`), &restored); err == nil {
+		t.Error("expected error for unknown format version")
+	}
+}
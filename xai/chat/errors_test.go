@@ -0,0 +1,30 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	xaierrors "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSampleReturnsAPIError(t *testing.T) {
+	client := &fakeServiceClient{err: status.Error(codes.InvalidArgument, "This model's maximum prompt length is 131072 but the request contains 200000 tokens.")}
+	req := NewRequest("grok-4", WithMessage(User(Text("hello"))))
+
+	_, err := req.Sample(context.Background(), client)
+	var apiErr *xaierrors.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != codes.InvalidArgument {
+		t.Fatalf("Sample() error = %v, want an APIError", err)
+	}
+	if !errors.Is(err, xaierrors.ErrContextTooLong) || status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Sample() error = %v should match ErrContextTooLong and keep its status code", err)
+	}
+
+	_, err = req.Stream(context.Background(), client)
+	if !errors.Is(err, xaierrors.ErrInvalidRequest) {
+		t.Errorf("Stream() error = %v, want ErrInvalidRequest", err)
+	}
+}
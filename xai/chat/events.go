@@ -0,0 +1,275 @@
+// Package chat provides typed streaming events for xAI SDK.
+package chat
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"strings"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Event is a typed streaming event produced by Stream.Events.
+// It is one of *ReasoningDelta, *ContentDelta, *ToolCallStarted,
+// *ToolCallArgumentsDelta, *ToolCallCompleted, *CitationAdded,
+// *UsageReported or *Finished.
+type Event interface {
+	// OutputIndex returns the index of the output the event belongs to, or -1
+	// for events that apply to the whole response.
+	OutputIndex() int32
+	isEvent()
+}
+
+// ReasoningDelta carries a new piece of the model's reasoning trace.
+type ReasoningDelta struct {
+	Index int32
+	Text  string
+}
+
+// ContentDelta carries a new piece of the answer content.
+type ContentDelta struct {
+	Index int32
+	Text  string
+}
+
+// ToolCallStarted reports the first appearance of a tool call.
+// For server-side tools it marks the start of the tool's execution.
+type ToolCallStarted struct {
+	Index int32
+	Call  *ToolCall
+}
+
+// ToolCallArgumentsDelta carries newly streamed arguments of a tool call.
+type ToolCallArgumentsDelta struct {
+	Index  int32
+	CallID string
+	Delta  string
+}
+
+// ToolCallCompleted reports that a tool call finished. Server-side tool calls
+// complete when their status becomes completed or failed; client-side tool calls
+// complete when their output finishes.
+type ToolCallCompleted struct {
+	Index int32
+	Call  *ToolCall
+	// Failed is true when the server reported the tool call as failed.
+	Failed bool
+}
+
+// CitationAdded reports a new citation. Inline citations belong to an output;
+// URL citations apply to the whole response and have Index -1.
+type CitationAdded struct {
+	Index  int32
+	URL    string
+	Inline *InlineCitation
+}
+
+// UsageReported carries the token usage of the response.
+type UsageReported struct {
+	Usage *TokenUsage
+}
+
+// Finished reports that an output finished.
+type Finished struct {
+	Index        int32
+	FinishReason string
+}
+
+// OutputIndex returns the output index of the event.
+func (e *ReasoningDelta) OutputIndex() int32 { return e.Index }
+
+// OutputIndex returns the output index of the event.
+func (e *ContentDelta) OutputIndex() int32 { return e.Index }
+
+// OutputIndex returns the output index of the event.
+func (e *ToolCallStarted) OutputIndex() int32 { return e.Index }
+
+// OutputIndex returns the output index of the event.
+func (e *ToolCallArgumentsDelta) OutputIndex() int32 { return e.Index }
+
+// OutputIndex returns the output index of the event.
+func (e *ToolCallCompleted) OutputIndex() int32 { return e.Index }
+
+// OutputIndex returns the output index of the event.
+func (e *CitationAdded) OutputIndex() int32 { return e.Index }
+
+// OutputIndex returns -1 because usage applies to the whole response.
+func (e *UsageReported) OutputIndex() int32 { return -1 }
+
+// OutputIndex returns the output index of the event.
+func (e *Finished) OutputIndex() int32 { return e.Index }
+
+func (*ReasoningDelta) isEvent()         {}
+func (*ContentDelta) isEvent()           {}
+func (*ToolCallStarted) isEvent()        {}
+func (*ToolCallArgumentsDelta) isEvent() {}
+func (*ToolCallCompleted) isEvent()      {}
+func (*CitationAdded) isEvent()          {}
+func (*UsageReported) isEvent()          {}
+func (*Finished) isEvent()               {}
+
+// Events returns an iterator over typed events decoded from the remaining chunks
+// of the stream. Chunks are still accumulated into Response. A stream error is
+// yielded once with a nil event; breaking out of the loop closes the stream.
+// Events, All and Next consume the same underlying stream.
+func (s *Stream) Events() iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		decoder := newEventDecoder()
+		for s.Next() {
+			for _, event := range decoder.decode(s.current.proto, s.acc.proto) {
+				if !yield(event, nil) {
+					_ = s.Close()
+					return
+				}
+			}
+		}
+		if err := s.Err(); err != nil && !errors.Is(err, io.EOF) {
+			yield(nil, err)
+		}
+	}
+}
+
+// toolCallKey identifies a tool call within a response.
+type toolCallKey struct {
+	index int32
+	id    string
+}
+
+// toolCallProgress records what has already been reported for a tool call.
+type toolCallProgress struct {
+	arguments string
+	completed bool
+}
+
+// eventDecoder turns chunks into events by comparing them with the response
+// accumulated so far.
+type eventDecoder struct {
+	toolCalls map[toolCallKey]*toolCallProgress
+	citations map[string]struct{}
+	usage     *xaiv1.SamplingUsage
+	finished  map[int32]struct{}
+}
+
+func newEventDecoder() *eventDecoder {
+	return &eventDecoder{
+		toolCalls: make(map[toolCallKey]*toolCallProgress),
+		citations: make(map[string]struct{}),
+		finished:  make(map[int32]struct{}),
+	}
+}
+
+// decode returns the events for chunk. accumulated must already include chunk.
+func (d *eventDecoder) decode(chunk *xaiv1.GetChatCompletionChunk, accumulated *xaiv1.GetChatCompletionResponse) []Event {
+	var events, finished []Event
+	for _, output := range chunk.GetOutputs() {
+		if output == nil {
+			continue
+		}
+		index := output.Index
+		current := accumulatedOutput(accumulated, index)
+		delta := output.Delta
+
+		if text := delta.GetReasoningContent(); text != "" {
+			events = append(events, &ReasoningDelta{Index: index, Text: text})
+		}
+		if text := delta.GetContent(); text != "" {
+			events = append(events, &ContentDelta{Index: index, Text: text})
+		}
+		for _, citation := range delta.GetCitations() {
+			events = append(events, &CitationAdded{Index: index, Inline: &InlineCitation{proto: citation}})
+		}
+		events = append(events, d.toolCallEvents(index, delta.GetToolCalls(), current)...)
+
+		if output.FinishReason != xaiv1.FinishReason_REASON_INVALID {
+			if _, done := d.finished[index]; !done {
+				d.finished[index] = struct{}{}
+				events = append(events, d.completeToolCalls(index, current)...)
+				finished = append(finished, &Finished{Index: index, FinishReason: output.FinishReason.String()})
+			}
+		}
+	}
+
+	for _, url := range chunk.GetCitations() {
+		if _, seen := d.citations[url]; seen {
+			continue
+		}
+		d.citations[url] = struct{}{}
+		events = append(events, &CitationAdded{Index: -1, URL: url})
+	}
+	if chunk.GetUsage() != nil && !proto.Equal(chunk.Usage, d.usage) {
+		d.usage = chunk.Usage
+		events = append(events, &UsageReported{Usage: &TokenUsage{proto: chunk.Usage}})
+	}
+
+	return append(events, finished...)
+}
+
+// toolCallEvents reports the progress of the tool calls touched by a delta.
+func (d *eventDecoder) toolCallEvents(index int32, deltas []*xaiv1.ToolCall, current *xaiv1.CompletionOutput) []Event {
+	var events []Event
+	calls := current.GetMessage().GetToolCalls()
+	for _, delta := range deltas {
+		call := findToolCall(calls, delta)
+		if call == nil {
+			continue
+		}
+		key := toolCallKey{index: index, id: call.Id}
+		progress, ok := d.toolCalls[key]
+		if !ok {
+			progress = &toolCallProgress{}
+			d.toolCalls[key] = progress
+			events = append(events, &ToolCallStarted{Index: index, Call: parseToolCall(call)})
+		}
+
+		arguments := call.GetFunction().GetArguments()
+		if arguments != progress.arguments {
+			events = append(events, &ToolCallArgumentsDelta{
+				Index:  index,
+				CallID: call.Id,
+				Delta:  strings.TrimPrefix(arguments, progress.arguments),
+			})
+			progress.arguments = arguments
+		}
+
+		if !progress.completed && (call.Status == xaiv1.ToolCallStatus_TOOL_CALL_STATUS_COMPLETED ||
+			call.Status == xaiv1.ToolCallStatus_TOOL_CALL_STATUS_FAILED) {
+			progress.completed = true
+			events = append(events, &ToolCallCompleted{
+				Index:  index,
+				Call:   parseToolCall(call),
+				Failed: call.Status == xaiv1.ToolCallStatus_TOOL_CALL_STATUS_FAILED,
+			})
+		}
+	}
+	return events
+}
+
+// completeToolCalls reports every tool call of a finished output that has not completed yet.
+func (d *eventDecoder) completeToolCalls(index int32, current *xaiv1.CompletionOutput) []Event {
+	var events []Event
+	for _, call := range current.GetMessage().GetToolCalls() {
+		progress, ok := d.toolCalls[toolCallKey{index: index, id: call.Id}]
+		if !ok || progress.completed {
+			continue
+		}
+		progress.completed = true
+		events = append(events, &ToolCallCompleted{
+			Index:  index,
+			Call:   parseToolCall(call),
+			Failed: call.Status == xaiv1.ToolCallStatus_TOOL_CALL_STATUS_FAILED,
+		})
+	}
+	return events
+}
+
+// accumulatedOutput returns the accumulated output with the given index.
+func accumulatedOutput(resp *xaiv1.GetChatCompletionResponse, index int32) *xaiv1.CompletionOutput {
+	for _, output := range resp.GetOutputs() {
+		if output.Index == index {
+			return output
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+)
+
+func collectEvents(t *testing.T, chunks ...*xaiv1.GetChatCompletionChunk) []Event {
+	t.Helper()
+	client := &fakeServiceClient{chunks: [][]*xaiv1.GetChatCompletionChunk{chunks}}
+	stream, err := NewRequest("grok-4", WithMessage(User(Text("hi")))).Stream(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var events []Event
+	for event, err := range stream.Events() {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func describeEvents(events []Event) string {
+	parts := make([]string, 0, len(events))
+	for _, event := range events {
+		switch e := event.(type) {
+		case *ReasoningDelta:
+			parts = append(parts, fmt.Sprintf("reasoning[%d]:%s", e.Index, e.Text))
+		case *ContentDelta:
+			parts = append(parts, fmt.Sprintf("content[%d]:%s", e.Index, e.Text))
+		case *ToolCallStarted:
+			parts = append(parts, fmt.Sprintf("started[%d]:%s", e.Index, e.Call.ID()))
+		case *ToolCallArgumentsDelta:
+			parts = append(parts, fmt.Sprintf("args[%d]:%s:%s", e.Index, e.CallID, e.Delta))
+		case *ToolCallCompleted:
+			parts = append(parts, fmt.Sprintf("completed[%d]:%s:%t", e.Index, e.Call.ID(), e.Failed))
+		case *CitationAdded:
+			if e.Inline != nil {
+				parts = append(parts, fmt.Sprintf("inline[%d]:%s", e.Index, e.Inline.ID()))
+			} else {
+				parts = append(parts, fmt.Sprintf("url[%d]:%s", e.Index, e.URL))
+			}
+		case *UsageReported:
+			parts = append(parts, fmt.Sprintf("usage:%d", e.Usage.TotalTokens()))
+		case *Finished:
+			parts = append(parts, fmt.Sprintf("finished[%d]:%s", e.Index, e.FinishReason))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestStreamEventsContentAndReasoning(t *testing.T) {
+	events := collectEvents(t,
+		deltaChunk(0, &xaiv1.Delta{Role: xaiv1.MessageRole_ROLE_ASSISTANT, ReasoningContent: "hmm"}, xaiv1.FinishReason_REASON_INVALID),
+		deltaChunk(0, &xaiv1.Delta{Content: "Hi", Citations: []*xaiv1.InlineCitation{{Id: "c1"}}}, xaiv1.FinishReason_REASON_INVALID),
+		&xaiv1.GetChatCompletionChunk{
+			Citations: []string{"https://x.ai"},
+			Usage:     &xaiv1.SamplingUsage{TotalTokens: 7},
+			Outputs:   []*xaiv1.CompletionOutputChunk{{Index: 0, FinishReason: xaiv1.FinishReason_REASON_STOP}},
+		},
+	)
+
+	want := "reasoning[0]:hmm content[0]:Hi inline[0]:c1 url[-1]:https://x.ai usage:7 finished[0]:REASON_STOP"
+	if got := describeEvents(events); got != want {
+		t.Errorf("events:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestStreamEventsToolCallLifecycle(t *testing.T) {
+	search := &xaiv1.ToolCall{
+		Id:     "ws-1",
+		Type:   xaiv1.ToolCallType_TOOL_CALL_TYPE_WEB_SEARCH_TOOL,
+		Status: xaiv1.ToolCallStatus_TOOL_CALL_STATUS_IN_PROGRESS,
+		Tool:   &xaiv1.ToolCall_Function{Function: &xaiv1.FunctionCall{Name: "web_search", Arguments: `{"q":"go"}`}},
+	}
+	searchDone := &xaiv1.ToolCall{Id: "ws-1", Status: xaiv1.ToolCallStatus_TOOL_CALL_STATUS_FAILED}
+
+	events := collectEvents(t,
+		deltaChunk(0, &xaiv1.Delta{ToolCalls: []*xaiv1.ToolCall{search}}, xaiv1.FinishReason_REASON_INVALID),
+		deltaChunk(0, &xaiv1.Delta{ToolCalls: []*xaiv1.ToolCall{searchDone}}, xaiv1.FinishReason_REASON_INVALID),
+		deltaChunk(0, &xaiv1.Delta{ToolCalls: []*xaiv1.ToolCall{functionCall("call-1", "get_weather", `{"city":`)}}, xaiv1.FinishReason_REASON_INVALID),
+		deltaChunk(0, &xaiv1.Delta{ToolCalls: []*xaiv1.ToolCall{functionCall("call-1", "", `"Oslo"}`)}}, xaiv1.FinishReason_REASON_TOOL_CALLS),
+	)
+
+	want := `started[0]:ws-1 args[0]:ws-1:{"q":"go"} completed[0]:ws-1:true ` +
+		`started[0]:call-1 args[0]:call-1:{"city": args[0]:call-1:"Oslo"} completed[0]:call-1:false finished[0]:REASON_TOOL_CALLS`
+	if got := describeEvents(events); got != want {
+		t.Errorf("events:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestStreamEventsPerOutputIndex(t *testing.T) {
+	events := collectEvents(t,
+		deltaChunk(1, &xaiv1.Delta{Content: "b"}, xaiv1.FinishReason_REASON_STOP),
+		deltaChunk(0, &xaiv1.Delta{Content: "a"}, xaiv1.FinishReason_REASON_STOP),
+	)
+
+	want := "content[1]:b finished[1]:REASON_STOP content[0]:a finished[0]:REASON_STOP"
+	if got := describeEvents(events); got != want {
+		t.Errorf("events:\n got %s\nwant %s", got, want)
+	}
+	for _, event := range events {
+		if _, ok := event.(*UsageReported); ok {
+			t.Error("no usage was streamed")
+		}
+	}
+}
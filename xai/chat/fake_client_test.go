@@ -0,0 +1,127 @@
+package chat
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeServiceClient is a scripted ServiceClient used by the chat tests.
+type fakeServiceClient struct {
+	mu        sync.Mutex
+	responses []*xaiv1.GetChatCompletionResponse
+	chunks    [][]*xaiv1.GetChatCompletionChunk
+	err       error
+	requests  []*xaiv1.GetCompletionsRequest
+}
+
+func (f *fakeServiceClient) GetCompletion(_ context.Context, in *xaiv1.GetCompletionsRequest, _ ...grpc.CallOption) (*xaiv1.GetChatCompletionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, proto.Clone(in).(*xaiv1.GetCompletionsRequest))
+	if f.err != nil {
+		return nil, f.err
+	}
+	if len(f.responses) == 0 {
+		return &xaiv1.GetChatCompletionResponse{}, nil
+	}
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+	return resp, nil
+}
+
+func (f *fakeServiceClient) GetCompletionChunk(ctx context.Context, in *xaiv1.GetCompletionsRequest, _ ...grpc.CallOption) (xaiv1.Chat_GetCompletionChunkClient, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, proto.Clone(in).(*xaiv1.GetCompletionsRequest))
+	if f.err != nil {
+		return nil, f.err
+	}
+	var chunks []*xaiv1.GetChatCompletionChunk
+	if len(f.chunks) > 0 {
+		chunks = f.chunks[0]
+		f.chunks = f.chunks[1:]
+	}
+	return &fakeChunkStream{ctx: ctx, chunks: chunks}, nil
+}
+
+func (f *fakeServiceClient) StartDeferredCompletion(context.Context, *xaiv1.GetCompletionsRequest, ...grpc.CallOption) (*xaiv1.StartDeferredResponse, error) {
+	return &xaiv1.StartDeferredResponse{}, nil
+}
+
+func (f *fakeServiceClient) GetDeferredCompletion(context.Context, *xaiv1.GetDeferredRequest, ...grpc.CallOption) (*xaiv1.GetDeferredCompletionResponse, error) {
+	return &xaiv1.GetDeferredCompletionResponse{}, nil
+}
+
+// fakeChunkStream replays a fixed list of chunks and then returns io.EOF.
+type fakeChunkStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	chunks []*xaiv1.GetChatCompletionChunk
+	closed bool
+}
+
+func (s *fakeChunkStream) Recv() (*xaiv1.GetChatCompletionChunk, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *fakeChunkStream) CloseSend() error {
+	s.closed = true
+	return nil
+}
+
+func (s *fakeChunkStream) Header() (metadata.MD, error) { return nil, nil }
+func (s *fakeChunkStream) Trailer() metadata.MD         { return nil }
+func (s *fakeChunkStream) Context() context.Context     { return s.ctx }
+
+// toolCallResponse builds a response in which the assistant calls the given client-side tools.
+func toolCallResponse(calls ...*xaiv1.ToolCall) *xaiv1.GetChatCompletionResponse {
+	return &xaiv1.GetChatCompletionResponse{
+		Id: "resp-tools",
+		Outputs: []*xaiv1.CompletionOutput{{
+			FinishReason: xaiv1.FinishReason_REASON_TOOL_CALLS,
+			Message: &xaiv1.CompletionMessage{
+				Role:      xaiv1.MessageRole_ROLE_ASSISTANT,
+				ToolCalls: calls,
+			},
+		}},
+	}
+}
+
+// textResponse builds a plain assistant text response.
+func textResponse(content string) *xaiv1.GetChatCompletionResponse {
+	return &xaiv1.GetChatCompletionResponse{
+		Id: "resp-text",
+		Outputs: []*xaiv1.CompletionOutput{{
+			FinishReason: xaiv1.FinishReason_REASON_STOP,
+			Message: &xaiv1.CompletionMessage{
+				Role:    xaiv1.MessageRole_ROLE_ASSISTANT,
+				Content: content,
+			},
+		}},
+	}
+}
+
+// functionCall builds a client-side function tool call.
+func functionCall(id, name, arguments string) *xaiv1.ToolCall {
+	return &xaiv1.ToolCall{
+		Id:   id,
+		Type: xaiv1.ToolCallType_TOOL_CALL_TYPE_CLIENT_SIDE_TOOL,
+		Tool: &xaiv1.ToolCall_Function{
+			Function: &xaiv1.FunctionCall{Name: name, Arguments: arguments},
+		},
+	}
+}
@@ -0,0 +1,89 @@
+// Package chat provides typed function tools for xAI SDK.
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// TypedToolHandler handles a tool call whose arguments were decoded into Args.
+type TypedToolHandler[Args any] func(ctx context.Context, args Args) (any, error)
+
+// FunctionTool creates a strict function tool whose parameter schema is derived
+// from the Args struct (see JSONSchemaFor for the supported tags). The returned
+// tool carries a handler that decodes and validates the call arguments into Args
+// before invoking fn, so it can be registered directly with ToolRunner.RegisterTool.
+func FunctionTool[Args any](name, description string, fn TypedToolHandler[Args]) (*Tool, error) {
+	argsType := reflect.TypeOf((*Args)(nil)).Elem()
+	for argsType.Kind() == reflect.Pointer {
+		argsType = argsType.Elem()
+	}
+	if argsType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("tool %s: arguments type must be a struct, got %s", name, argsType)
+	}
+
+	schema, err := JSONSchemaFor[Args]()
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", name, err)
+	}
+
+	tool := &Tool{
+		name:        name,
+		description: description,
+		parameters:  make(map[string]interface{}),
+		strict:      true,
+		schema:      schema,
+	}
+	if fn != nil {
+		tool.handler = func(ctx context.Context, call *ToolCall) (any, error) {
+			args, err := decodeArguments[Args](call, schema)
+			if err != nil {
+				return nil, err
+			}
+			return fn(ctx, args)
+		}
+	}
+	return tool, nil
+}
+
+// MustFunctionTool is like FunctionTool but panics if the tool cannot be created.
+func MustFunctionTool[Args any](name, description string, fn TypedToolHandler[Args]) *Tool {
+	tool, err := FunctionTool(name, description, fn)
+	if err != nil {
+		panic(err)
+	}
+	return tool
+}
+
+// DecodeArguments validates the tool call arguments against the schema derived
+// from Args and decodes them. Schema violations are reported as a *ValidationError
+// with one FieldError per offending field.
+func DecodeArguments[Args any](call *ToolCall) (Args, error) {
+	var zero Args
+	schema, err := JSONSchemaFor[Args]()
+	if err != nil {
+		return zero, err
+	}
+	return decodeArguments[Args](call, schema)
+}
+
+func decodeArguments[Args any](call *ToolCall, schema map[string]interface{}) (Args, error) {
+	var args Args
+	if call == nil {
+		return args, fmt.Errorf("tool call is nil")
+	}
+
+	raw := call.ArgumentsJSON()
+	if raw == "" {
+		raw = "{}"
+	}
+	if err := ValidateJSON([]byte(raw), schema); err != nil {
+		return args, fmt.Errorf("invalid arguments for tool %s: %w", call.Name(), err)
+	}
+	if err := json.Unmarshal([]byte(raw), &args); err != nil {
+		return args, fmt.Errorf("failed to decode arguments for tool %s: %w", call.Name(), err)
+	}
+	return args, nil
+}
@@ -0,0 +1,250 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+)
+
+type weatherLocation struct {
+	City    string `json:"city" jsonschema:"description=City name,minLength=1"`
+	Country string `json:"country,omitempty"`
+}
+
+type weatherArgs struct {
+	Location weatherLocation `json:"location"`
+	Unit     string          `json:"unit" jsonschema:"enum=celsius,enum=fahrenheit"`
+	Days     int             `json:"days" jsonschema:"minimum=1,maximum=14"`
+	Tags     []string        `json:"tags,omitempty" jsonschema:"maxItems=3"`
+	Notes    *string         `json:"notes" jsonschema_description:"Free text, may contain commas"`
+	Since    time.Time       `json:"since,omitempty"`
+	internal string          //nolint:unused // verifies unexported fields are skipped
+}
+
+func TestJSONSchemaForStruct(t *testing.T) {
+	schema, err := JSONSchemaFor[weatherArgs]()
+	if err != nil {
+		t.Fatalf("JSONSchemaFor() error = %v", err)
+	}
+
+	if schema["type"] != "object" || schema["additionalProperties"] != false {
+		t.Errorf("unexpected root schema: %v", schema)
+	}
+	required := schema["required"].([]string)
+	want := []string{"location", "unit", "days"}
+	if len(required) != len(want) {
+		t.Fatalf("required = %v, want %v", required, want)
+	}
+	for i := range want {
+		if required[i] != want[i] {
+			t.Errorf("required[%d] = %q, want %q", i, required[i], want[i])
+		}
+	}
+
+	props := schema["properties"].(map[string]interface{})
+	if _, ok := props["internal"]; ok {
+		t.Error("unexported fields must not be part of the schema")
+	}
+	location := props["location"].(map[string]interface{})
+	city := location["properties"].(map[string]interface{})["city"].(map[string]interface{})
+	if city["description"] != "City name" || city["minLength"] != 1 {
+		t.Errorf("city schema = %v", city)
+	}
+	unit := props["unit"].(map[string]interface{})
+	if enum := unit["enum"].([]interface{}); len(enum) != 2 || enum[1] != "fahrenheit" {
+		t.Errorf("unit enum = %v", unit["enum"])
+	}
+	days := props["days"].(map[string]interface{})
+	if days["type"] != "integer" || days["minimum"] != 1.0 || days["maximum"] != 14.0 {
+		t.Errorf("days schema = %v", days)
+	}
+	tags := props["tags"].(map[string]interface{})
+	if tags["type"] != "array" || tags["items"].(map[string]interface{})["type"] != "string" {
+		t.Errorf("tags schema = %v", tags)
+	}
+	notes := props["notes"].(map[string]interface{})
+	if notes["description"] != "Free text, may contain commas" {
+		t.Errorf("notes schema = %v", notes)
+	}
+	if props["since"].(map[string]interface{})["format"] != "date-time" {
+		t.Errorf("since schema = %v", props["since"])
+	}
+
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatalf("schema is not JSON serializable: %v", err)
+	}
+}
+
+func TestJSONSchemaForRejectsUnsupportedTypes(t *testing.T) {
+	type recursive struct {
+		Children []recursive `json:"children"`
+	}
+	if _, err := JSONSchemaFor[recursive](); err == nil {
+		t.Error("expected error for recursive type")
+	}
+	if _, err := JSONSchemaFor[map[int]string](); err == nil {
+		t.Error("expected error for non-string map keys")
+	}
+	type badTag struct {
+		Name string `json:"name" jsonschema:"colour=red"`
+	}
+	if _, err := JSONSchemaFor[badTag](); err == nil {
+		t.Error("expected error for unknown tag key")
+	}
+}
+
+func TestFunctionToolDefinition(t *testing.T) {
+	tool, err := FunctionTool("get_weather", "Get the weather", func(context.Context, weatherArgs) (any, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("FunctionTool() error = %v", err)
+	}
+	if !tool.Strict() {
+		t.Error("function tools must be strict")
+	}
+	if _, ok := tool.Parameters()["location"]; !ok {
+		t.Error("Parameters() should expose the generated properties")
+	}
+
+	req := NewRequest("grok-4", WithTool(tool))
+	fn := req.Proto().Tools[0].GetFunction()
+	if !fn.Strict {
+		t.Error("proto function should be strict")
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal([]byte(fn.Parameters), &params); err != nil {
+		t.Fatalf("parameters are not JSON: %v", err)
+	}
+	if params["type"] != "object" {
+		t.Errorf("parameters = %v", params)
+	}
+
+	if _, err := FunctionTool[string]("bad", "bad", nil); err == nil {
+		t.Error("expected error for non-struct arguments")
+	}
+}
+
+func TestFunctionToolStrictSchema(t *testing.T) {
+	tool := MustFunctionTool("get_weather", "Get the weather", func(context.Context, weatherArgs) (any, error) {
+		return nil, nil
+	})
+	schema := tool.ToJSONSchema()
+
+	// Strict mode lists every property as required, optional ones after the others
+	want := []string{"location", "unit", "days", "notes", "since", "tags"}
+	required := schema["required"].([]string)
+	if len(required) != len(want) {
+		t.Fatalf("required = %v, want %v", required, want)
+	}
+	for i := range want {
+		if required[i] != want[i] {
+			t.Errorf("required[%d] = %q, want %q", i, required[i], want[i])
+		}
+	}
+
+	props := schema["properties"].(map[string]interface{})
+	if typ := props["unit"].(map[string]interface{})["type"]; typ != "string" {
+		t.Errorf("required unit type = %v, want string", typ)
+	}
+	tags := props["tags"].(map[string]interface{})
+	if typ := tags["type"].([]string); len(typ) != 2 || typ[0] != "array" || typ[1] != "null" {
+		t.Errorf("optional tags type = %v, want [array null]", tags["type"])
+	}
+	location := props["location"].(map[string]interface{})
+	if nested := location["required"].([]string); len(nested) != 2 || nested[1] != "country" {
+		t.Errorf("nested required = %v, want [city country]", nested)
+	}
+
+	// The generated schema itself is left as is
+	if got := tool.Parameters()["tags"].(map[string]interface{})["type"]; got != "array" {
+		t.Errorf("Parameters() tags type = %v, want array", got)
+	}
+
+	// Arguments following the strict schema pass validation
+	call := parseToolCall(functionCall("call-1", "get_weather",
+		`{"location":{"city":"Oslo","country":null},"unit":"celsius","days":2,"tags":null,"notes":null,"since":null}`))
+	if _, err := DecodeArguments[weatherArgs](call); err != nil {
+		t.Errorf("DecodeArguments() error = %v", err)
+	}
+	err := ValidateJSON([]byte(`{"location":{"city":"Oslo","country":null},"unit":null,"days":2,"tags":null,"notes":null,"since":null}`), schema)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Errors) != 1 || verr.Errors[0].Path != "unit" {
+		t.Errorf("ValidateJSON() = %v, want a single error for unit", err)
+	}
+}
+
+func TestStrictToolParameters(t *testing.T) {
+	tool := NewTool("get_weather", "Get the weather").
+		WithParameter("city", "string", "City name", true).
+		WithParameter("unit", "string", "Temperature unit", false).
+		WithStrict(true)
+
+	schema := tool.ToJSONSchema()
+	required := schema["required"].([]string)
+	if len(required) != 2 || required[0] != "city" || required[1] != "unit" {
+		t.Errorf("required = %v, want [city unit]", required)
+	}
+	if schema["additionalProperties"] != false {
+		t.Errorf("additionalProperties = %v, want false", schema["additionalProperties"])
+	}
+	unit := schema["properties"].(map[string]interface{})["unit"].(map[string]interface{})
+	if typ, ok := unit["type"].([]string); !ok || len(typ) != 2 || typ[1] != "null" {
+		t.Errorf("unit type = %v, want [string null]", unit["type"])
+	}
+}
+
+func TestFunctionToolRejectsParameters(t *testing.T) {
+	tool := MustFunctionTool("get_weather", "Get the weather", func(context.Context, weatherArgs) (any, error) {
+		return nil, nil
+	})
+	if err := tool.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	tool.WithParameter("city", "string", "City name", true)
+	if err := tool.Validate(); err == nil {
+		t.Error("expected error for parameters added to a function tool")
+	}
+}
+
+func TestDecodeArgumentsFieldErrors(t *testing.T) {
+	call := parseToolCall(functionCall("call-1", "get_weather", `{"location":{"city":""},"unit":"kelvin","days":30,"extra":true}`))
+	_, err := DecodeArguments[weatherArgs](call)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("DecodeArguments() error = %v, want *ValidationError", err)
+	}
+	paths := map[string]bool{}
+	for _, fe := range verr.Errors {
+		paths[fe.Path] = true
+	}
+	for _, path := range []string{"location.city", "unit", "days", "extra"} {
+		if !paths[path] {
+			t.Errorf("missing field error for %q in %v", path, verr.Errors)
+		}
+	}
+}
+
+func TestFunctionToolWithRunner(t *testing.T) {
+	tool := MustFunctionTool("get_weather", "Get the weather", func(_ context.Context, args weatherArgs) (any, error) {
+		return args.Location.City + ":" + args.Unit, nil
+	})
+
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{
+		toolCallResponse(functionCall("call-1", "get_weather", `{"location":{"city":"Oslo"},"unit":"celsius","days":2}`)),
+		textResponse("done"),
+	}}
+	runner := NewToolRunner(client).RegisterTool(tool)
+	result, err := runner.Run(context.Background(), NewRequest("grok-4", WithMessage(User(Text("hi"))), WithTool(tool)))
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := result.Steps[0].Result.Result(); got != "Oslo:celsius" {
+		t.Errorf("result = %v, want Oslo:celsius", got)
+	}
+}
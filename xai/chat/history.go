@@ -0,0 +1,465 @@
+// Package chat provides token-budget-aware history trimming for xAI SDK.
+package chat
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/models"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/tokenizer"
+)
+
+// ErrHistoryTooLong is returned when the request does not fit the token budget
+// even after all droppable messages were removed.
+var ErrHistoryTooLong = errors.New("chat history exceeds the token budget")
+
+// DefaultMessageTokenOverhead is the number of tokens added per message for
+// role and formatting markers.
+const DefaultMessageTokenOverhead = 4
+
+// DefaultSummaryMaxTokens bounds the length of generated history summaries.
+const DefaultSummaryMaxTokens = 512
+
+// defaultSummaryPrompt instructs the model how to summarize dropped turns.
+const defaultSummaryPrompt = "Summarize the following earlier part of a conversation. " +
+	"Keep facts, decisions, open questions and tool results that later turns may rely on. " +
+	"Reply with the summary only."
+
+// TrimStrategy selects how HistoryFitter removes messages that do not fit.
+type TrimStrategy int
+
+const (
+	// TrimDropOldestTurns drops whole turns, oldest first. A turn starts at a
+	// user message and includes every following message up to the next one.
+	TrimDropOldestTurns TrimStrategy = iota
+	// TrimKeepToolPairs drops single messages, oldest first, but keeps an
+	// assistant tool call together with its tool results.
+	TrimKeepToolPairs
+	// TrimSummarize drops messages like TrimKeepToolPairs and replaces them with
+	// a model-generated summary inserted after the system prompt.
+	TrimSummarize
+)
+
+// TokenCounter tokenizes text for a model. *tokenizer.Client implements it.
+type TokenCounter interface {
+	TokenizeText(ctx context.Context, text, model string) ([]*tokenizer.Token, error)
+}
+
+// ModelLimits looks up language model metadata. *models.Client implements it.
+type ModelLimits interface {
+	GetLanguageModel(ctx context.Context, name string) (*models.LanguageModel, error)
+}
+
+// HistoryFitter trims the messages of a request so that its prompt fits a token
+// budget. Token counts are cached per model and message content, so repeated
+// fitting of a growing conversation only tokenizes new messages. A HistoryFitter
+// is safe for concurrent use.
+type HistoryFitter struct {
+	counter          TokenCounter
+	limits           ModelLimits
+	budget           int
+	reserve          int
+	overhead         int
+	strategy         TrimStrategy
+	summarizer       ServiceClient
+	summaryModel     string
+	summaryMaxTokens int32
+
+	mu          sync.Mutex
+	tokenCache  map[[sha256.Size]byte]int
+	promptLimit map[string]int
+}
+
+// HistoryOption configures a HistoryFitter.
+type HistoryOption func(*HistoryFitter)
+
+// WithTokenBudget sets a fixed prompt token budget instead of the model limit.
+func WithTokenBudget(tokens int) HistoryOption {
+	return func(f *HistoryFitter) {
+		f.budget = tokens
+	}
+}
+
+// WithModelLimits derives the budget from the model's MaxPromptLength.
+func WithModelLimits(limits ModelLimits) HistoryOption {
+	return func(f *HistoryFitter) {
+		f.limits = limits
+	}
+}
+
+// WithCompletionReserve keeps tokens free for the completion when the budget is
+// derived from the model limit. By default the request's max_tokens is reserved.
+func WithCompletionReserve(tokens int) HistoryOption {
+	return func(f *HistoryFitter) {
+		f.reserve = tokens
+	}
+}
+
+// WithMessageTokenOverhead sets the number of tokens counted per message in
+// addition to its content (default DefaultMessageTokenOverhead).
+func WithMessageTokenOverhead(tokens int) HistoryOption {
+	return func(f *HistoryFitter) {
+		f.overhead = tokens
+	}
+}
+
+// WithTrimStrategy sets how messages are removed (default TrimDropOldestTurns).
+func WithTrimStrategy(strategy TrimStrategy) HistoryOption {
+	return func(f *HistoryFitter) {
+		f.strategy = strategy
+	}
+}
+
+// WithSummarizer sets the client used by TrimSummarize to generate summaries.
+// An empty model uses the model of the request being fitted.
+func WithSummarizer(client ServiceClient, model string) HistoryOption {
+	return func(f *HistoryFitter) {
+		f.summarizer = client
+		f.summaryModel = model
+	}
+}
+
+// WithSummaryMaxTokens bounds the length of generated summaries (default DefaultSummaryMaxTokens).
+func WithSummaryMaxTokens(tokens int32) HistoryOption {
+	return func(f *HistoryFitter) {
+		f.summaryMaxTokens = tokens
+	}
+}
+
+// NewHistoryFitter creates a HistoryFitter that counts tokens with counter.
+// Either WithTokenBudget or WithModelLimits must be provided.
+func NewHistoryFitter(counter TokenCounter, opts ...HistoryOption) *HistoryFitter {
+	f := &HistoryFitter{
+		counter:          counter,
+		reserve:          -1,
+		overhead:         DefaultMessageTokenOverhead,
+		summaryMaxTokens: DefaultSummaryMaxTokens,
+		tokenCache:       make(map[[sha256.Size]byte]int),
+		promptLimit:      make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// FitResult describes the outcome of HistoryFitter.Fit.
+type FitResult struct {
+	// Budget is the prompt token budget the request was fitted to.
+	Budget int
+	// PromptTokens is the estimated prompt size after fitting.
+	PromptTokens int
+	// Dropped holds the messages that were removed, oldest first.
+	Dropped []*Message
+	// Summary is the generated summary message, if any.
+	Summary *Message
+}
+
+// Fit trims req's messages in place until the prompt fits the token budget.
+// System messages and the last turn are never dropped; when trimming, system
+// messages are placed ahead of the remaining history. If the kept messages alone
+// exceed the budget, ErrHistoryTooLong is returned and req is left unchanged.
+func (f *HistoryFitter) Fit(ctx context.Context, req *Request) (*FitResult, error) {
+	if err := f.checkFit(req); err != nil {
+		return nil, err
+	}
+	budget, err := f.tokenBudget(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	system, history := pinSystemMessages(req.proto.Messages)
+	trim, err := f.newHistoryTrim(ctx, req.proto.Model, system, history)
+	if err != nil {
+		return nil, err
+	}
+	result := &FitResult{Budget: budget, PromptTokens: trim.total}
+	if trim.total <= budget {
+		return result, nil
+	}
+
+	// Drop units oldest first, leaving room for the summary if there is one.
+	summaryReserve := 0
+	if f.strategy == TrimSummarize {
+		summaryReserve = int(f.summaryMaxTokens) + f.overhead
+	}
+	trim.dropOldest(budget - summaryReserve)
+
+	var summary *xaiv1.Message
+	if f.strategy == TrimSummarize && trim.dropped > 0 {
+		if summary, err = f.summarizeDropped(ctx, req, trim, budget); err != nil {
+			return nil, err
+		}
+	}
+	if trim.total > budget {
+		return nil, fmt.Errorf("%w: %d tokens required, budget is %d", ErrHistoryTooLong, trim.total, budget)
+	}
+
+	cut := trim.cut()
+	for _, msg := range history[:cut] {
+		result.Dropped = append(result.Dropped, &Message{proto: msg})
+	}
+	messages := make([]*xaiv1.Message, 0, len(system)+1+len(history)-cut)
+	messages = append(messages, system...)
+	if summary != nil {
+		messages = append(messages, summary)
+		result.Summary = &Message{proto: summary}
+	}
+	req.proto.Messages = append(messages, history[cut:]...)
+	result.PromptTokens = trim.total
+	return result, nil
+}
+
+// checkFit reports whether req can be fitted with the fitter's configuration.
+func (f *HistoryFitter) checkFit(req *Request) error {
+	if f.counter == nil {
+		return fmt.Errorf("history fitter has no token counter")
+	}
+	if req == nil || req.proto == nil {
+		return fmt.Errorf("request is nil")
+	}
+	if f.strategy == TrimSummarize && f.summarizer == nil {
+		return fmt.Errorf("summarize strategy requires WithSummarizer")
+	}
+	return nil
+}
+
+// pinSystemMessages separates the system messages, which are never dropped,
+// from the rest of the history.
+func pinSystemMessages(messages []*xaiv1.Message) (system, history []*xaiv1.Message) {
+	for _, msg := range messages {
+		if msg.GetRole() == xaiv1.MessageRole_ROLE_SYSTEM {
+			system = append(system, msg)
+		} else {
+			history = append(history, msg)
+		}
+	}
+	return system, history
+}
+
+// historyTrim tracks the history units dropped while fitting a request.
+type historyTrim struct {
+	history []*xaiv1.Message
+	// counts holds the tokens of each history message.
+	counts []int
+	units  []historyUnit
+	// dropped is the number of units dropped, oldest first.
+	dropped int
+	// total is the prompt size of the kept messages.
+	total int
+}
+
+// newHistoryTrim counts the tokens of the messages of a request.
+func (f *HistoryFitter) newHistoryTrim(ctx context.Context, model string, system, history []*xaiv1.Message) (*historyTrim, error) {
+	systemTokens, err := f.countMessages(ctx, model, system)
+	if err != nil {
+		return nil, err
+	}
+	t := &historyTrim{history: history, counts: make([]int, len(history)), units: f.units(history), total: systemTokens}
+	for i, msg := range history {
+		if t.counts[i], err = f.countMessage(ctx, model, msg); err != nil {
+			return nil, err
+		}
+		t.total += t.counts[i]
+	}
+	return t, nil
+}
+
+// canDrop reports whether a unit other than the last one is left to drop.
+func (t *historyTrim) canDrop() bool {
+	return t.dropped < len(t.units)-1
+}
+
+// dropUnit drops the oldest unit still kept.
+func (t *historyTrim) dropUnit() {
+	u := t.units[t.dropped]
+	for i := u.start; i < u.end; i++ {
+		t.total -= t.counts[i]
+	}
+	t.dropped++
+}
+
+// dropOldest drops units oldest first until the kept messages fit budget,
+// always keeping the last one.
+func (t *historyTrim) dropOldest(budget int) {
+	for t.canDrop() && t.total > budget {
+		t.dropUnit()
+	}
+}
+
+// cut returns the number of history messages dropped.
+func (t *historyTrim) cut() int {
+	if t.dropped == 0 {
+		return 0
+	}
+	return t.units[t.dropped-1].end
+}
+
+// summarizeDropped summarizes the dropped messages, dropping more units while
+// the summary does not fit the budget.
+func (f *HistoryFitter) summarizeDropped(ctx context.Context, req *Request, trim *historyTrim, budget int) (*xaiv1.Message, error) {
+	for {
+		summary, err := f.summarize(ctx, req, trim.history[:trim.cut()])
+		if err != nil {
+			return nil, err
+		}
+		summaryTokens, err := f.countMessage(ctx, req.proto.Model, summary)
+		if err != nil {
+			return nil, err
+		}
+		if trim.total+summaryTokens <= budget || !trim.canDrop() {
+			trim.total += summaryTokens
+			return summary, nil
+		}
+		trim.dropUnit()
+	}
+}
+
+// tokenBudget returns the prompt budget for req.
+func (f *HistoryFitter) tokenBudget(ctx context.Context, req *Request) (int, error) {
+	if f.budget > 0 {
+		return f.budget, nil
+	}
+	if f.limits == nil {
+		return 0, fmt.Errorf("history fitter needs WithTokenBudget or WithModelLimits")
+	}
+
+	model := req.proto.Model
+	f.mu.Lock()
+	limit, ok := f.promptLimit[model]
+	f.mu.Unlock()
+	if !ok {
+		info, err := f.limits.GetLanguageModel(ctx, model)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get prompt limit for %s: %w", model, err)
+		}
+		limit = int(info.MaxPromptLength())
+		f.mu.Lock()
+		f.promptLimit[model] = limit
+		f.mu.Unlock()
+	}
+
+	reserve := f.reserve
+	if reserve < 0 {
+		reserve = int(req.proto.GetMaxTokens())
+	}
+	return limit - reserve, nil
+}
+
+// historyUnit is a range of history messages that is dropped as a whole.
+type historyUnit struct {
+	start, end int
+}
+
+// units groups history messages according to the trim strategy.
+func (f *HistoryFitter) units(history []*xaiv1.Message) []historyUnit {
+	var units []historyUnit
+	for i, msg := range history {
+		startsUnit := len(units) == 0
+		switch f.strategy {
+		case TrimDropOldestTurns:
+			startsUnit = startsUnit || msg.GetRole() == xaiv1.MessageRole_ROLE_USER
+		default:
+			// Tool results stay with the assistant message that requested them.
+			startsUnit = startsUnit || msg.GetRole() != xaiv1.MessageRole_ROLE_TOOL
+		}
+		if startsUnit {
+			units = append(units, historyUnit{start: i, end: i + 1})
+		} else {
+			units[len(units)-1].end = i + 1
+		}
+	}
+	return units
+}
+
+// summarize asks the summarizer for a summary of messages and returns it as a
+// system message.
+func (f *HistoryFitter) summarize(ctx context.Context, req *Request, messages []*xaiv1.Message) (*xaiv1.Message, error) {
+	model := f.summaryModel
+	if model == "" {
+		model = req.proto.Model
+	}
+	summaryReq := NewRequest(model,
+		WithMessage(System(Text(defaultSummaryPrompt))),
+		WithMessage(User(Text(transcript(messages)))),
+		WithMaxTokens(f.summaryMaxTokens),
+	)
+	resp, err := summaryReq.Sample(ctx, f.summarizer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize history: %w", err)
+	}
+	return System(Text("Summary of the earlier conversation:\n" + resp.Content())).Proto(), nil
+}
+
+// transcript renders messages as plain text for summarization.
+func transcript(messages []*xaiv1.Message) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		b.WriteString(roleFromProto(msg.GetRole()))
+		b.WriteString(": ")
+		b.WriteString(messageText(msg))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// messageText returns the text of a message that contributes to the prompt.
+func messageText(msg *xaiv1.Message) string {
+	var b strings.Builder
+	for _, content := range msg.GetContent() {
+		b.WriteString(content.GetText())
+	}
+	if reasoning := msg.GetReasoningContent(); reasoning != "" {
+		b.WriteString("\n")
+		b.WriteString(reasoning)
+	}
+	for _, call := range msg.GetToolCalls() {
+		fn := call.GetFunction()
+		b.WriteString("\n")
+		b.WriteString(fn.GetName())
+		b.WriteString(fn.GetArguments())
+	}
+	return b.String()
+}
+
+func (f *HistoryFitter) countMessages(ctx context.Context, model string, messages []*xaiv1.Message) (int, error) {
+	total := 0
+	for _, msg := range messages {
+		n, err := f.countMessage(ctx, model, msg)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// countMessage returns the token count of msg, using the cache when possible.
+func (f *HistoryFitter) countMessage(ctx context.Context, model string, msg *xaiv1.Message) (int, error) {
+	text := messageText(msg)
+	key := sha256.Sum256([]byte(model + "\x00" + text))
+
+	f.mu.Lock()
+	n, ok := f.tokenCache[key]
+	f.mu.Unlock()
+	if ok {
+		return n + f.overhead, nil
+	}
+
+	if text != "" {
+		tokens, err := f.counter.TokenizeText(ctx, text, model)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count tokens: %w", err)
+		}
+		n = len(tokens)
+	}
+	f.mu.Lock()
+	f.tokenCache[key] = n
+	f.mu.Unlock()
+	return n + f.overhead, nil
+}
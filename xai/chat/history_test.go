@@ -0,0 +1,177 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/models"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/tokenizer"
+	"google.golang.org/grpc"
+)
+
+// wordCounter counts one token per whitespace-separated word.
+type wordCounter struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (w *wordCounter) TokenizeText(_ context.Context, text, _ string) ([]*tokenizer.Token, error) {
+	w.mu.Lock()
+	w.calls++
+	w.mu.Unlock()
+	fields := strings.Fields(text)
+	tokens := make([]*tokenizer.Token, len(fields))
+	for i, field := range fields {
+		tokens[i] = &tokenizer.Token{StringToken: field}
+	}
+	return tokens, nil
+}
+
+type limitModelsClient struct {
+	xaiv1.ModelsClient
+	limit int32
+}
+
+func (m *limitModelsClient) GetLanguageModel(_ context.Context, in *xaiv1.GetModelRequest, _ ...grpc.CallOption) (*xaiv1.LanguageModel, error) {
+	return &xaiv1.LanguageModel{Name: in.Name, MaxPromptLength: m.limit}, nil
+}
+
+func words(n int) string {
+	return strings.TrimSpace(strings.Repeat("w ", n))
+}
+
+func historyRequest() *Request {
+	return NewRequest("grok-4",
+		WithMessage(System(Text(words(5)))),
+		WithMessage(User(Text(words(10)))),
+		WithMessage(Assistant(Text(words(10)))),
+		WithMessage(User(Text(words(10)))),
+		WithMessage(Assistant(Text("")).WithToolCalls([]*ToolCall{parseToolCall(functionCall("call-1", "lookup", "{}"))})),
+		WithMessage(NewMessage("tool", Text(words(10))).WithToolCallID("call-1")),
+		WithMessage(Assistant(Text(words(10)))),
+		WithMessage(User(Text(words(10)))),
+	)
+}
+
+func TestHistoryFitterNoTrimWhenWithinBudget(t *testing.T) {
+	req := historyRequest()
+	result, err := NewHistoryFitter(&wordCounter{}, WithTokenBudget(1000), WithMessageTokenOverhead(0)).Fit(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	if len(result.Dropped) != 0 || len(req.Proto().Messages) != 8 {
+		t.Errorf("dropped = %d, messages = %d", len(result.Dropped), len(req.Proto().Messages))
+	}
+	if result.PromptTokens != 66 {
+		t.Errorf("PromptTokens = %d, want 66", result.PromptTokens)
+	}
+}
+
+func TestHistoryFitterDropOldestTurns(t *testing.T) {
+	req := historyRequest()
+	fitter := NewHistoryFitter(&wordCounter{}, WithTokenBudget(40), WithMessageTokenOverhead(0))
+	result, err := fitter.Fit(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	messages := req.Proto().Messages
+	// The first two turns are dropped entirely; system and the last turn remain.
+	if len(result.Dropped) != 6 || len(messages) != 2 {
+		t.Fatalf("dropped = %d, remaining = %d", len(result.Dropped), len(messages))
+	}
+	if messages[0].Role != xaiv1.MessageRole_ROLE_SYSTEM || result.PromptTokens != 15 {
+		t.Errorf("messages = %v, tokens = %d", messages, result.PromptTokens)
+	}
+}
+
+func TestHistoryFitterKeepsToolPairs(t *testing.T) {
+	req := historyRequest()
+	fitter := NewHistoryFitter(&wordCounter{}, WithTokenBudget(45), WithMessageTokenOverhead(0), WithTrimStrategy(TrimKeepToolPairs))
+	if _, err := fitter.Fit(context.Background(), req); err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	for i, msg := range req.Proto().Messages {
+		if msg.Role == xaiv1.MessageRole_ROLE_TOOL {
+			prev := req.Proto().Messages[i-1]
+			if len(prev.ToolCalls) == 0 && prev.Role != xaiv1.MessageRole_ROLE_TOOL {
+				t.Fatalf("tool result at %d lost its tool call", i)
+			}
+		}
+	}
+	if first := req.Proto().Messages[1]; first.Role == xaiv1.MessageRole_ROLE_TOOL {
+		t.Error("history must not start with an orphaned tool result")
+	}
+}
+
+func TestHistoryFitterSummarize(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{textResponse("short recap")}}
+	req := historyRequest()
+	fitter := NewHistoryFitter(&wordCounter{},
+		WithTokenBudget(45),
+		WithMessageTokenOverhead(0),
+		WithTrimStrategy(TrimSummarize),
+		WithSummarizer(client, ""),
+		WithSummaryMaxTokens(10),
+	)
+	result, err := fitter.Fit(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	if result.Summary == nil || !strings.Contains(result.Summary.Content(), "short recap") {
+		t.Fatalf("summary = %v", result.Summary)
+	}
+	messages := req.Proto().Messages
+	if messages[1].Role != xaiv1.MessageRole_ROLE_SYSTEM || messages[1].Content[0].GetText() != result.Summary.Content() {
+		t.Errorf("summary should follow the system prompt: %v", messages[1])
+	}
+	sent := client.requests[0]
+	if sent.GetMaxTokens() != 10 || !strings.Contains(sent.Messages[1].Content[0].GetText(), "user: ") {
+		t.Errorf("summary request = %v", sent)
+	}
+	if result.PromptTokens > 45 {
+		t.Errorf("PromptTokens = %d exceeds budget", result.PromptTokens)
+	}
+}
+
+func TestHistoryFitterModelLimitsAndCache(t *testing.T) {
+	counter := &wordCounter{}
+	limits := models.NewClient(&limitModelsClient{limit: 60})
+	fitter := NewHistoryFitter(counter, WithModelLimits(limits), WithMessageTokenOverhead(0))
+
+	req := historyRequest()
+	req.SetMaxTokens(20)
+	result, err := fitter.Fit(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	if result.Budget != 40 {
+		t.Errorf("Budget = %d, want 40", result.Budget)
+	}
+
+	calls := counter.calls
+	if _, err := fitter.Fit(context.Background(), historyRequest()); err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	if counter.calls != calls {
+		t.Errorf("token counts should be cached: %d tokenize calls after refit, want %d", counter.calls, calls)
+	}
+}
+
+func TestHistoryFitterTooLong(t *testing.T) {
+	req := historyRequest()
+	before := len(req.Proto().Messages)
+	_, err := NewHistoryFitter(&wordCounter{}, WithTokenBudget(10)).Fit(context.Background(), req)
+	if !errors.Is(err, ErrHistoryTooLong) {
+		t.Fatalf("Fit() error = %v, want ErrHistoryTooLong", err)
+	}
+	if len(req.Proto().Messages) != before {
+		t.Error("request must be unchanged on error")
+	}
+}
@@ -5,6 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"google.golang.org/protobuf/proto"
 )
 
 // ResponseFormat represents the desired response format.
@@ -61,7 +65,15 @@ func parseContent(content string, v any) error {
 		}
 		return nil
 	default:
-		return fmt.Errorf("unsupported target type: %T", v)
+		// Any other non-nil pointer is decoded as JSON, e.g. a pointer to a struct.
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Pointer || rv.IsNil() {
+			return fmt.Errorf("unsupported target type: %T", v)
+		}
+		if err := json.Unmarshal([]byte(content), v); err != nil {
+			return fmt.Errorf("failed to parse JSON response: %w", err)
+		}
+		return nil
 	}
 }
 
@@ -158,3 +170,94 @@ func (rfo *ResponseFormatOption) Validate() error {
 
 	return nil
 }
+
+// DefaultParseRepairAttempts is the number of times ParseInto re-asks the model
+// after a response fails schema validation.
+const DefaultParseRepairAttempts = 1
+
+// ParseOption configures ParseInto.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	repairAttempts int
+}
+
+// WithRepairAttempts sets how many times ParseInto re-asks the model with the
+// validation error when a response does not match the schema. Zero disables repair.
+func WithRepairAttempts(attempts int) ParseOption {
+	return func(c *parseConfig) {
+		if attempts >= 0 {
+			c.repairAttempts = attempts
+		}
+	}
+}
+
+// ParseInto performs a chat completion request constrained to the JSON schema
+// derived from T (see JSONSchemaFor), validates the response against that schema
+// and decodes it into a T.
+//
+// The schema is attached to a copy of the request, so req itself is not modified.
+// When validation fails, the model is re-asked with the validation error up to
+// the configured number of repair attempts.
+func ParseInto[T any](ctx context.Context, client ServiceClient, req *Request, opts ...ParseOption) (T, error) {
+	value, _, err := ParseIntoWithResponse[T](ctx, client, req, opts...)
+	return value, err
+}
+
+// ParseIntoWithResponse is like ParseInto but also returns the raw response
+// that produced the decoded value.
+func ParseIntoWithResponse[T any](ctx context.Context, client ServiceClient, req *Request, opts ...ParseOption) (T, *Response, error) {
+	var value T
+	if req == nil || req.proto == nil {
+		return value, nil, fmt.Errorf("request proto is nil")
+	}
+
+	cfg := &parseConfig{repairAttempts: DefaultParseRepairAttempts}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	schema, err := JSONSchemaFor[T]()
+	if err != nil {
+		return value, nil, fmt.Errorf("failed to derive JSON schema: %w", err)
+	}
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return value, nil, fmt.Errorf("failed to marshal JSON schema: %w", err)
+	}
+	schemaStr := string(schemaJSON)
+
+	cloned, ok := proto.Clone(req.proto).(*xaiv1.GetCompletionsRequest)
+	if !ok {
+		return value, nil, fmt.Errorf("failed to clone request proto")
+	}
+	cloned.ResponseFormat = &xaiv1.ResponseFormat{
+		FormatType: xaiv1.FormatType_FORMAT_TYPE_JSON_SCHEMA,
+		Schema:     &schemaStr,
+	}
+	attemptReq := &Request{proto: cloned, batchRequestID: req.batchRequestID}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := attemptReq.Sample(ctx, client)
+		if err != nil {
+			return value, nil, err
+		}
+
+		content := resp.Content()
+		validationErr := ValidateJSON([]byte(content), schema)
+		if validationErr == nil {
+			if err := json.Unmarshal([]byte(content), &value); err != nil {
+				return value, resp, fmt.Errorf("failed to parse JSON response: %w", err)
+			}
+			return value, resp, nil
+		}
+		if attempt >= cfg.repairAttempts {
+			return value, resp, fmt.Errorf("response does not match schema after %d attempt(s): %w", attempt+1, validationErr)
+		}
+
+		attemptReq.AppendResponse(resp)
+		attemptReq.AppendMessage(*User(Text(fmt.Sprintf(
+			"Your previous response did not match the required JSON schema: %v. "+
+				"Respond again with only a JSON value that matches the schema.", validationErr))))
+	}
+}
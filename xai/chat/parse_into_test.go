@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+)
+
+type movieReview struct {
+	Title  string   `json:"title"`
+	Rating int      `json:"rating" jsonschema:"minimum=1,maximum=5"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+func TestParseIntoAttachesSchemaAndDecodes(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{
+		textResponse(`{"title":"Heat","rating":5,"tags":["crime"]}`),
+	}}
+	req := NewRequest("grok-4", WithMessage(User(Text("Review Heat"))))
+
+	review, err := ParseInto[movieReview](context.Background(), client, req)
+	if err != nil {
+		t.Fatalf("ParseInto() error = %v", err)
+	}
+	if review.Title != "Heat" || review.Rating != 5 || len(review.Tags) != 1 {
+		t.Errorf("review = %+v", review)
+	}
+
+	sent := client.requests[0].GetResponseFormat()
+	if sent.GetFormatType() != xaiv1.FormatType_FORMAT_TYPE_JSON_SCHEMA {
+		t.Fatalf("format type = %v", sent.GetFormatType())
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(sent.GetSchema()), &schema); err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+	if _, ok := schema["properties"].(map[string]interface{})["rating"]; !ok {
+		t.Errorf("schema = %v", schema)
+	}
+	if req.Proto().ResponseFormat != nil {
+		t.Error("ParseInto must not modify the caller's request")
+	}
+}
+
+func TestParseIntoRepairsInvalidResponse(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{
+		textResponse(`{"title":"Heat","rating":9}`),
+		textResponse(`{"title":"Heat","rating":4}`),
+	}}
+	req := NewRequest("grok-4", WithMessage(User(Text("Review Heat"))))
+
+	review, resp, err := ParseIntoWithResponse[movieReview](context.Background(), client, req)
+	if err != nil {
+		t.Fatalf("ParseIntoWithResponse() error = %v", err)
+	}
+	if review.Rating != 4 || resp == nil {
+		t.Errorf("review = %+v, resp = %v", review, resp)
+	}
+
+	retry := client.requests[1].Messages
+	if len(retry) != 3 {
+		t.Fatalf("repair request messages = %d, want 3", len(retry))
+	}
+	if !strings.Contains(retry[2].Content[0].GetText(), "rating") {
+		t.Errorf("repair message should mention the failing field: %q", retry[2].Content[0].GetText())
+	}
+}
+
+func TestParseIntoValidationFailure(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{
+		textResponse(`{"title":"Heat"}`),
+	}}
+	req := NewRequest("grok-4", WithMessage(User(Text("Review Heat"))))
+
+	_, err := ParseInto[movieReview](context.Background(), client, req, WithRepairAttempts(0))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("ParseInto() error = %v, want *ValidationError", err)
+	}
+	if verr.Errors[0].Path != "rating" {
+		t.Errorf("field errors = %v", verr.Errors)
+	}
+	if len(client.requests) != 1 {
+		t.Errorf("requests = %d, want 1", len(client.requests))
+	}
+}
+
+func TestParseFillsStruct(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{
+		textResponse(`{"title":"Heat","rating":5}`),
+	}}
+	var review movieReview
+	if err := NewRequest("grok-4", WithMessage(User(Text("hi")))).Parse(context.Background(), client, &review); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if review.Title != "Heat" {
+		t.Errorf("review = %+v", review)
+	}
+}
@@ -0,0 +1,402 @@
+// Package chat provides incremental decoding of streamed JSON outputs for xAI SDK.
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+)
+
+// StreamPartial decodes the JSON answer of a stream while it is being generated.
+// Each time more of the document becomes decodable it yields a snapshot of T
+// filled from the received prefix: open strings are closed, incomplete keys and
+// literals are dropped and open objects and arrays are closed. The last snapshot
+// is decoded from the complete answer; if that fails, the decoding error is
+// yielded instead. Only output 0 is decoded. Breaking out of the loop closes the stream.
+func StreamPartial[T any](s *Stream) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		scanner := newPartialJSONScanner(nil)
+		last := ""
+		for s.Next() {
+			text := outputContentDelta(s.current, 0)
+			if text == "" {
+				continue
+			}
+			scanner.feed(text)
+			doc, ok := scanner.snapshot()
+			if !ok || doc == last {
+				continue
+			}
+			var value T
+			if err := json.Unmarshal([]byte(doc), &value); err != nil {
+				// The prefix does not fit T yet, e.g. a number that is still growing.
+				continue
+			}
+			last = doc
+			if !yield(value, nil) {
+				_ = s.Close()
+				return
+			}
+		}
+		if err := s.Err(); err != nil && !errors.Is(err, io.EOF) {
+			yield(zero, err)
+			return
+		}
+
+		doc := scanner.document()
+		if doc == last {
+			return
+		}
+		var value T
+		if err := json.Unmarshal([]byte(doc), &value); err != nil {
+			yield(zero, fmt.Errorf("failed to parse JSON response: %w", err))
+			return
+		}
+		yield(value, nil)
+	}
+}
+
+// StreamArrayItems decodes a streamed JSON answer whose top-level value is an
+// array and yields each element as soon as it is complete, so long list
+// extractions can be processed item by item. Only output 0 is decoded.
+// Breaking out of the loop closes the stream.
+func StreamArrayItems[T any](s *Stream) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		var items []string
+		scanner := newPartialJSONScanner(func(raw string) { items = append(items, raw) })
+		for s.Next() {
+			text := outputContentDelta(s.current, 0)
+			if text == "" {
+				continue
+			}
+			scanner.feed(text)
+			if scanner.root != 0 && scanner.root != '[' {
+				_ = s.Close()
+				yield(zero, fmt.Errorf("streamed JSON is not an array"))
+				return
+			}
+			for _, raw := range items {
+				var value T
+				if err := json.Unmarshal([]byte(raw), &value); err != nil {
+					_ = s.Close()
+					yield(zero, fmt.Errorf("failed to parse array element: %w", err))
+					return
+				}
+				if !yield(value, nil) {
+					_ = s.Close()
+					return
+				}
+			}
+			items = items[:0]
+		}
+		if err := s.Err(); err != nil && !errors.Is(err, io.EOF) {
+			yield(zero, err)
+			return
+		}
+		if !scanner.closed() {
+			yield(zero, fmt.Errorf("streamed JSON array is incomplete"))
+		}
+	}
+}
+
+// outputContentDelta returns the content delta of the output with the given index.
+func outputContentDelta(chunk *Chunk, index int32) string {
+	if chunk == nil || chunk.proto == nil {
+		return ""
+	}
+	for _, output := range chunk.proto.Outputs {
+		if output != nil && output.Index == index {
+			return output.Delta.GetContent()
+		}
+	}
+	return ""
+}
+
+// scanState is the lexical state of the partial JSON scanner.
+type scanState int
+
+const (
+	scanValue   scanState = iota // between tokens
+	scanString                   // inside a string
+	scanNumber                   // inside a number
+	scanLiteral                  // inside true, false or null
+)
+
+// partialJSONScanner incrementally scans a JSON document and remembers the
+// longest prefix that can be turned into valid JSON by closing open containers.
+type partialJSONScanner struct {
+	buf strings.Builder
+	// pos is the number of bytes of buf already scanned.
+	pos int
+	// started is false until the first '{' or '[' is seen; anything before it,
+	// such as a Markdown code fence, is ignored.
+	started bool
+	start   int
+	root    byte
+	// stack holds the open containers, '{' or '['.
+	stack []byte
+	// expectKey reports, per open object, whether the next string is a key.
+	expectKey []bool
+	state     scanState
+	tokStart  int
+	// escape is set after a backslash inside a string and hexLeft counts the
+	// digits still missing from a \u escape that starts at escStart.
+	escape   bool
+	hexLeft  int
+	escStart int
+	// done is set once the root value is closed; later input is ignored.
+	done bool
+	// safeEnd is the end of the longest prefix made of complete members and
+	// elements, and safeClosers closes its open containers.
+	safeEnd     int
+	safeClosers string
+	// elemStart is the start of the current top-level array element.
+	elemStart int
+	onElement func(raw string)
+}
+
+func newPartialJSONScanner(onElement func(raw string)) *partialJSONScanner {
+	return &partialJSONScanner{elemStart: -1, onElement: onElement}
+}
+
+// feed appends data to the document and scans it.
+func (p *partialJSONScanner) feed(data string) {
+	p.buf.WriteString(data)
+	doc := p.buf.String()
+	for ; p.pos < len(doc); p.pos++ {
+		p.scan(doc, p.pos)
+	}
+}
+
+func (p *partialJSONScanner) scan(doc string, i int) {
+	c := doc[i]
+	if p.done || !p.begin(c, i) {
+		return
+	}
+
+	switch p.state {
+	case scanString:
+		p.scanString(c, i)
+	case scanNumber:
+		p.scanNumber(c, i)
+	case scanLiteral:
+		p.scanLiteral(c, i)
+	default:
+		p.scanValue(c, i)
+	}
+}
+
+// begin reports whether the document has started, starting it at the first
+// '{' or '['.
+func (p *partialJSONScanner) begin(c byte, i int) bool {
+	if p.started {
+		return true
+	}
+	if c != '{' && c != '[' {
+		return false
+	}
+	p.started = true
+	p.start = i
+	p.root = c
+	return true
+}
+
+// scanString scans byte c of a string.
+func (p *partialJSONScanner) scanString(c byte, i int) {
+	switch {
+	case p.hexLeft > 0:
+		p.hexLeft--
+	case p.escape:
+		p.escape = false
+		if c == 'u' {
+			p.hexLeft = 4
+		}
+	case c == '\\':
+		p.escape = true
+		p.escStart = i
+	case c == '"':
+		p.state = scanValue
+		if p.inObject() && p.expectKey[len(p.expectKey)-1] {
+			p.expectKey[len(p.expectKey)-1] = false
+		} else {
+			p.valueDone(i + 1)
+		}
+	}
+}
+
+// scanNumber scans byte c of a number; any other byte ends the number.
+func (p *partialJSONScanner) scanNumber(c byte, i int) {
+	if strings.IndexByte("0123456789+-.eE", c) < 0 {
+		p.endToken(i)
+		p.scanValue(c, i)
+	}
+}
+
+// scanLiteral scans byte c of true, false or null; any other byte ends the literal.
+func (p *partialJSONScanner) scanLiteral(c byte, i int) {
+	if c < 'a' || c > 'z' {
+		p.endToken(i)
+		p.scanValue(c, i)
+	}
+}
+
+// scanValue scans byte c between tokens.
+func (p *partialJSONScanner) scanValue(c byte, i int) {
+	switch {
+	case c == '{' || c == '[':
+		p.openContainer(c, i)
+	case c == '}' || c == ']':
+		p.closeContainer(i)
+	case c == ',':
+		if p.inObject() {
+			p.expectKey[len(p.expectKey)-1] = true
+		}
+	case c == '"':
+		p.startToken(scanString, i)
+	case c == '-' || (c >= '0' && c <= '9'):
+		p.startToken(scanNumber, i)
+	case c >= 'a' && c <= 'z':
+		p.startToken(scanLiteral, i)
+	}
+}
+
+func (p *partialJSONScanner) openContainer(c byte, i int) {
+	if p.inRootArray() {
+		p.elemStart = i
+	}
+	p.stack = append(p.stack, c)
+	if c == '{' {
+		p.expectKey = append(p.expectKey, true)
+	}
+	p.markSafe(i + 1)
+}
+
+func (p *partialJSONScanner) closeContainer(i int) {
+	if len(p.stack) == 0 {
+		return
+	}
+	if p.inObject() {
+		p.expectKey = p.expectKey[:len(p.expectKey)-1]
+	}
+	p.stack = p.stack[:len(p.stack)-1]
+	p.valueDone(i + 1)
+	p.done = len(p.stack) == 0
+}
+
+// startToken starts a string, number or literal at i.
+func (p *partialJSONScanner) startToken(state scanState, i int) {
+	p.state = state
+	p.tokStart = i
+	if p.inRootArray() {
+		p.elemStart = i
+	}
+}
+
+// endToken ends the number or literal being scanned before i.
+func (p *partialJSONScanner) endToken(i int) {
+	p.state = scanValue
+	p.valueDone(i)
+}
+
+// inRootArray reports whether the scanner is directly inside a root array.
+func (p *partialJSONScanner) inRootArray() bool {
+	return len(p.stack) == 1 && p.stack[0] == '['
+}
+
+func (p *partialJSONScanner) inObject() bool {
+	return len(p.stack) > 0 && p.stack[len(p.stack)-1] == '{'
+}
+
+// valueDone records that a value ending at end is complete.
+func (p *partialJSONScanner) valueDone(end int) {
+	if p.inRootArray() && p.elemStart >= 0 {
+		if p.onElement != nil {
+			p.onElement(p.buf.String()[p.elemStart:end])
+		}
+		p.elemStart = -1
+	}
+	p.markSafe(end)
+}
+
+func (p *partialJSONScanner) markSafe(end int) {
+	p.safeEnd = end
+	closers := make([]byte, len(p.stack))
+	for i, open := range p.stack {
+		closer := byte('}')
+		if open == '[' {
+			closer = ']'
+		}
+		closers[len(p.stack)-1-i] = closer
+	}
+	p.safeClosers = string(closers)
+}
+
+// closed reports whether the root value has been completely received.
+func (p *partialJSONScanner) closed() bool {
+	return p.done
+}
+
+// document returns the received document without surrounding noise such as
+// Markdown code fences.
+func (p *partialJSONScanner) document() string {
+	doc := p.buf.String()
+	switch {
+	case !p.started:
+		return strings.TrimSpace(doc)
+	case p.done:
+		return doc[p.start:p.safeEnd]
+	default:
+		return doc[p.start:]
+	}
+}
+
+// snapshot returns the received prefix completed into valid JSON.
+func (p *partialJSONScanner) snapshot() (string, bool) {
+	if !p.started {
+		return "", false
+	}
+	doc := p.buf.String()
+	if p.done {
+		return doc[p.start:p.safeEnd], true
+	}
+
+	switch p.state {
+	case scanString:
+		if p.inObject() && p.expectKey[len(p.expectKey)-1] {
+			break
+		}
+		// Close an open string value, dropping an incomplete escape sequence.
+		value := doc[p.tokStart:]
+		if p.escape || p.hexLeft > 0 {
+			value = doc[p.tokStart:p.escStart]
+		}
+		return doc[p.start:p.tokStart] + value + `"` + p.closers(), true
+	case scanNumber:
+		number := strings.TrimRight(doc[p.tokStart:], "+-.eE")
+		if json.Valid([]byte(number)) {
+			return doc[p.start:p.tokStart] + number + p.closers(), true
+		}
+	case scanLiteral:
+		if literal := doc[p.tokStart:]; literal == "true" || literal == "false" || literal == "null" {
+			return doc[p.start:] + p.closers(), true
+		}
+	}
+	return doc[p.start:p.safeEnd] + p.safeClosers, true
+}
+
+func (p *partialJSONScanner) closers() string {
+	var b strings.Builder
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if p.stack[i] == '{' {
+			b.WriteByte('}')
+		} else {
+			b.WriteByte(']')
+		}
+	}
+	return b.String()
+}
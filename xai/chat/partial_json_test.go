@@ -0,0 +1,121 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+)
+
+func contentStream(t *testing.T, pieces ...string) *Stream {
+	t.Helper()
+	chunks := make([]*xaiv1.GetChatCompletionChunk, len(pieces))
+	for i, piece := range pieces {
+		chunks[i] = deltaChunk(0, &xaiv1.Delta{Content: piece}, xaiv1.FinishReason_REASON_INVALID)
+	}
+	client := &fakeServiceClient{chunks: [][]*xaiv1.GetChatCompletionChunk{chunks}}
+	stream, err := NewRequest("grok-4", WithMessage(User(Text("hi")))).Stream(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	return stream
+}
+
+func TestPartialJSONSnapshot(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{`{"title": "He`, `{"title": "He"}`},
+		{`{"title": "Heat", "rat`, `{"title": "Heat"}`},
+		{`{"title": "Heat", "rating":`, `{"title": "Heat"}`},
+		{`{"title": "Heat", "rating": 4`, `{"title": "Heat", "rating": 4}`},
+		{`{"title": "Heat", "rating": 4.`, `{"title": "Heat", "rating": 4}`},
+		{`{"ok": tr`, `{}`},
+		{`{"ok": true`, `{"ok": true}`},
+		{`{"tags": ["a", "b`, `{"tags": ["a", "b"]}`},
+		{`{"tags": ["a",`, `{"tags": ["a"]}`},
+		{`{"nested": {"x": [1, {"y": "z`, `{"nested": {"x": [1, {"y": "z"}]}}`},
+		{`{"text": "line\`, `{"text": "line"}`},
+		{`{"text": "caf\u00`, `{"text": "caf"}`},
+		{`{"text": "a\\`, `{"text": "a\\"}`},
+		{"```json\n{\"a\": 1}\n```", `{"a": 1}`},
+	}
+
+	for _, tt := range tests {
+		scanner := newPartialJSONScanner(nil)
+		scanner.feed(tt.prefix)
+		got, ok := scanner.snapshot()
+		if !ok || got != tt.want {
+			t.Errorf("snapshot(%q) = %q, want %q", tt.prefix, got, tt.want)
+			continue
+		}
+		if !json.Valid([]byte(got)) {
+			t.Errorf("snapshot(%q) is not valid JSON: %q", tt.prefix, got)
+		}
+	}
+}
+
+func TestStreamPartialYieldsGrowingSnapshots(t *testing.T) {
+	stream := contentStream(t, `{"title": "He`, `at", "rating`, `": 5, "tags": ["cr`, `ime"]}`)
+
+	var snapshots []movieReview
+	for review, err := range StreamPartial[movieReview](stream) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		snapshots = append(snapshots, review)
+	}
+
+	if len(snapshots) != 4 {
+		t.Fatalf("snapshots = %+v, want 4", snapshots)
+	}
+	if snapshots[0].Title != "He" || snapshots[1].Title != "Heat" || snapshots[1].Rating != 0 {
+		t.Errorf("early snapshots = %+v", snapshots[:2])
+	}
+	last := snapshots[len(snapshots)-1]
+	if last.Title != "Heat" || last.Rating != 5 || len(last.Tags) != 1 || last.Tags[0] != "crime" {
+		t.Errorf("final snapshot = %+v", last)
+	}
+}
+
+func TestStreamPartialReportsInvalidFinalDocument(t *testing.T) {
+	stream := contentStream(t, `{"title": "Heat"`)
+
+	var lastErr error
+	for _, err := range StreamPartial[movieReview](stream) {
+		lastErr = err
+	}
+	if lastErr == nil {
+		t.Error("expected an error for a truncated document")
+	}
+}
+
+func TestStreamArrayItems(t *testing.T) {
+	stream := contentStream(t, `[{"title": "Heat", "rating": 5}, {"ti`, `tle": "Ronin", "rating": 4}`, `, {"title": "Alien", "rating": 5}]`)
+
+	var titles []string
+	for review, err := range StreamArrayItems[movieReview](stream) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		titles = append(titles, review.Title)
+	}
+	if len(titles) != 3 || titles[0] != "Heat" || titles[2] != "Alien" {
+		t.Errorf("titles = %v", titles)
+	}
+}
+
+func TestStreamArrayItemsEarlyBreak(t *testing.T) {
+	stream := contentStream(t, `[1, 2, `, `3]`)
+	for n, err := range StreamArrayItems[int](stream) {
+		if err != nil || n != 1 {
+			t.Fatalf("first item = %d, %v", n, err)
+		}
+		break
+	}
+	if !stream.stream.(*fakeChunkStream).closed {
+		t.Error("breaking out of the loop should close the stream")
+	}
+}
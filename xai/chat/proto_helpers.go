@@ -89,6 +89,40 @@ func toolModeToProto(mode string) xaiv1.ToolMode {
 	}
 }
 
+// toolModeFromProto converts ToolMode enum to string
+func toolModeFromProto(mode xaiv1.ToolMode) string {
+	switch mode {
+	case xaiv1.ToolMode_TOOL_MODE_AUTO:
+		return "auto"
+	case xaiv1.ToolMode_TOOL_MODE_NONE:
+		return "none"
+	case xaiv1.ToolMode_TOOL_MODE_REQUIRED:
+		return "required"
+	default:
+		return ""
+	}
+}
+
+// toolChoiceToProto converts a ToolChoice to its proto form, using the
+// function_name variant for ToolChoiceFunction choices.
+func toolChoiceToProto(choice ToolChoice) *xaiv1.ToolChoice {
+	if name := choice.FunctionName(); name != "" {
+		return &xaiv1.ToolChoice{ToolChoice: &xaiv1.ToolChoice_FunctionName{FunctionName: name}}
+	}
+	return &xaiv1.ToolChoice{ToolChoice: &xaiv1.ToolChoice_Mode{Mode: toolModeToProto(string(choice))}}
+}
+
+// toolChoiceFromProto converts a proto ToolChoice to a ToolChoice
+func toolChoiceFromProto(choice *xaiv1.ToolChoice) ToolChoice {
+	if choice == nil {
+		return ""
+	}
+	if name := choice.GetFunctionName(); name != "" {
+		return ToolChoiceFunction(name)
+	}
+	return ToolChoice(toolModeFromProto(choice.GetMode()))
+}
+
 // searchModeToProto converts a string to SearchMode enum
 func searchModeToProto(mode string) xaiv1.SearchMode {
 	switch mode {
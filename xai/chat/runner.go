@@ -0,0 +1,252 @@
+// Package chat provides automatic client-side tool execution for xAI SDK.
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultToolRunnerMaxTurns is the number of sampling rounds a ToolRunner performs
+// when the request does not set WithMaxTurns.
+const DefaultToolRunnerMaxTurns = 10
+
+// ErrToolHandlerNotFound is returned when the model calls a client-side tool
+// that has no registered handler.
+var ErrToolHandlerNotFound = errors.New("no handler registered for tool")
+
+// ErrMaxTurnsExceeded is returned when the model is still calling client-side
+// tools after the maximum number of turns.
+var ErrMaxTurnsExceeded = errors.New("tool runner exceeded maximum number of turns")
+
+// ToolHandler executes a client-side tool call and returns its result.
+// A string result is sent to the model verbatim; any other value is JSON-encoded.
+// A returned error is reported back to the model as a tool error result.
+type ToolHandler func(ctx context.Context, call *ToolCall) (any, error)
+
+// ToolRunner drives the sample, execute, append, sample cycle for client-side tools.
+type ToolRunner struct {
+	client         ServiceClient
+	handlers       map[string]ToolHandler
+	timeouts       map[string]time.Duration
+	defaultTimeout time.Duration
+}
+
+// ToolRunnerOption configures a ToolRunner.
+type ToolRunnerOption func(*ToolRunner)
+
+// WithToolTimeout sets the default timeout applied to every handler invocation.
+// A zero timeout means handlers only inherit the deadline of the Run context.
+func WithToolTimeout(timeout time.Duration) ToolRunnerOption {
+	return func(r *ToolRunner) {
+		r.defaultTimeout = timeout
+	}
+}
+
+// NewToolRunner creates a new tool runner that samples with the given client.
+func NewToolRunner(client ServiceClient, opts ...ToolRunnerOption) *ToolRunner {
+	runner := &ToolRunner{
+		client:   client,
+		handlers: make(map[string]ToolHandler),
+		timeouts: make(map[string]time.Duration),
+	}
+	for _, opt := range opts {
+		opt(runner)
+	}
+	return runner
+}
+
+// Register registers the handler for the named tool.
+func (r *ToolRunner) Register(name string, handler ToolHandler) *ToolRunner {
+	r.handlers[name] = handler
+	return r
+}
+
+// RegisterTool registers the handlers of tools created with FunctionTool.
+// Tools without a handler are ignored.
+func (r *ToolRunner) RegisterTool(tools ...*Tool) *ToolRunner {
+	for _, tool := range tools {
+		if tool != nil && tool.handler != nil {
+			r.handlers[tool.name] = tool.handler
+		}
+	}
+	return r
+}
+
+// RegisterWithTimeout registers the handler for the named tool with its own timeout,
+// overriding the runner's default timeout.
+func (r *ToolRunner) RegisterWithTimeout(name string, timeout time.Duration, handler ToolHandler) *ToolRunner {
+	r.handlers[name] = handler
+	r.timeouts[name] = timeout
+	return r
+}
+
+// ToolRunStep records a single tool execution performed by a ToolRunner.
+type ToolRunStep struct {
+	// Turn is the zero-based sampling round that produced the call.
+	Turn int
+	// Call is the tool call requested by the model.
+	Call *ToolCall
+	// Result is the result sent back to the model.
+	Result *ToolResult
+	// Err is the error returned by the handler, if any.
+	Err error
+	// Duration is the wall-clock time spent in the handler.
+	Duration time.Duration
+}
+
+// ToolRunResult is the outcome of a ToolRunner.Run invocation.
+type ToolRunResult struct {
+	// Response is the last response returned by the model.
+	Response *Response
+	// Responses contains the response of every sampling round, in order.
+	Responses []*Response
+	// Steps is the transcript of all tool calls executed and their results.
+	Steps []*ToolRunStep
+}
+
+// Run samples the request and executes client-side tool calls until the model
+// stops calling them. The request is updated in place, so it holds the complete
+// conversation afterwards and can be continued with further messages.
+//
+// The number of sampling rounds is bounded by the request's WithMaxTurns setting
+// (DefaultToolRunnerMaxTurns if unset). When the request enables
+// WithParallelToolCalls, the handlers of a single turn run concurrently.
+// Server-side tool calls are left in the conversation untouched.
+func (r *ToolRunner) Run(ctx context.Context, req *Request) (*ToolRunResult, error) {
+	return r.run(ctx, r.client, req)
+}
+
+// run implements Run, sampling with client.
+func (r *ToolRunner) run(ctx context.Context, client ServiceClient, req *Request) (*ToolRunResult, error) {
+	if req == nil || req.proto == nil {
+		return nil, fmt.Errorf("request proto is nil")
+	}
+
+	maxTurns := DefaultToolRunnerMaxTurns
+	if req.proto.MaxTurns != nil && *req.proto.MaxTurns > 0 {
+		maxTurns = int(*req.proto.MaxTurns)
+	}
+	parallel := req.proto.GetParallelToolCalls()
+
+	result := &ToolRunResult{}
+	for turn := 0; turn < maxTurns; turn++ {
+		resp, err := req.Sample(ctx, client)
+		if err != nil {
+			return result, err
+		}
+		result.Response = resp
+		result.Responses = append(result.Responses, resp)
+		req.AppendResponse(resp)
+
+		calls := clientSideToolCalls(resp)
+		if len(calls) == 0 {
+			return result, nil
+		}
+		for _, call := range calls {
+			if _, ok := r.handlers[call.Name()]; !ok {
+				return result, fmt.Errorf("%w: %s", ErrToolHandlerNotFound, call.Name())
+			}
+		}
+
+		steps := r.execute(ctx, turn, calls, parallel)
+		for _, step := range steps {
+			req.proto.Messages = append(req.proto.Messages, toolResultMessage(step.Result))
+		}
+		result.Steps = append(result.Steps, steps...)
+
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+	}
+
+	return result, fmt.Errorf("%w (%d)", ErrMaxTurnsExceeded, maxTurns)
+}
+
+// execute runs the handlers for the given calls, preserving call order in the returned steps.
+func (r *ToolRunner) execute(ctx context.Context, turn int, calls []*ToolCall, parallel bool) []*ToolRunStep {
+	steps := make([]*ToolRunStep, len(calls))
+	if !parallel || len(calls) == 1 {
+		for i, call := range calls {
+			steps[i] = r.invoke(ctx, turn, call)
+		}
+		return steps
+	}
+
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(idx int, tc *ToolCall) {
+			defer wg.Done()
+			steps[idx] = r.invoke(ctx, turn, tc)
+		}(i, call)
+	}
+	wg.Wait()
+	return steps
+}
+
+// invoke runs a single handler with its timeout and converts the outcome into a ToolResult.
+func (r *ToolRunner) invoke(ctx context.Context, turn int, call *ToolCall) (step *ToolRunStep) {
+	step = &ToolRunStep{Turn: turn, Call: call}
+
+	timeout := r.defaultTimeout
+	if t, ok := r.timeouts[call.Name()]; ok {
+		timeout = t
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			step.Err = fmt.Errorf("tool %s panicked: %v", call.Name(), p)
+		}
+		step.Duration = time.Since(start)
+		if step.Err != nil {
+			step.Result = NewToolResultError(call.ID(), step.Err.Error())
+		}
+	}()
+
+	value, err := r.handlers[call.Name()](ctx, call)
+	if err != nil {
+		step.Err = fmt.Errorf("tool %s failed: %w", call.Name(), err)
+		return step
+	}
+	step.Result = NewToolResult(call.ID(), value)
+	return step
+}
+
+// clientSideToolCalls returns the tool calls in the response that must be executed by the client.
+func clientSideToolCalls(resp *Response) []*ToolCall {
+	var calls []*ToolCall
+	for _, call := range resp.ToolCalls() {
+		if call.IsClientSide() {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// NewToolRunner creates a tool runner backed by this client.
+func (c *Client) NewToolRunner(opts ...ToolRunnerOption) *ToolRunner {
+	return NewToolRunner(c.grpcClient, opts...)
+}
+
+// RunTools samples the request with the given runner's handlers until the model
+// stops calling client-side tools. A runner without a client samples with this
+// client; the runner itself is not modified, so it may be shared.
+func (c *Client) RunTools(ctx context.Context, req *Request, runner *ToolRunner) (*ToolRunResult, error) {
+	if runner == nil {
+		return nil, fmt.Errorf("tool runner is nil")
+	}
+	client := runner.client
+	if client == nil {
+		client = c.grpcClient
+	}
+	return runner.run(ctx, client, req)
+}
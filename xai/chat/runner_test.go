@@ -0,0 +1,219 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"google.golang.org/grpc"
+)
+
+func TestToolRunnerExecutesUntilFinalAnswer(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{
+		toolCallResponse(functionCall("call-1", "get_weather", `{"city":"Oslo"}`)),
+		textResponse("It is sunny in Oslo."),
+	}}
+
+	runner := NewToolRunner(client).Register("get_weather", func(_ context.Context, call *ToolCall) (any, error) {
+		return map[string]any{"city": call.Arguments()["city"], "forecast": "sunny"}, nil
+	})
+
+	req := NewRequest("grok-4", WithMessage(User(Text("Weather in Oslo?"))))
+	result, err := runner.Run(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Response.Content() != "It is sunny in Oslo." {
+		t.Errorf("final content = %q", result.Response.Content())
+	}
+	if len(result.Responses) != 2 {
+		t.Errorf("responses = %d, want 2", len(result.Responses))
+	}
+	if len(result.Steps) != 1 || result.Steps[0].Call.Name() != "get_weather" || result.Steps[0].Err != nil {
+		t.Fatalf("unexpected steps: %+v", result.Steps)
+	}
+
+	second := client.requests[1].Messages
+	if len(second) != 3 {
+		t.Fatalf("second request messages = %d, want 3", len(second))
+	}
+	toolMsg := second[2]
+	if toolMsg.Role != xaiv1.MessageRole_ROLE_TOOL {
+		t.Errorf("tool message role = %v", toolMsg.Role)
+	}
+	if toolMsg.GetToolCallId() != "call-1" {
+		t.Errorf("tool call id = %q, want call-1", toolMsg.GetToolCallId())
+	}
+	if got := toolMsg.Content[0].GetText(); got != `{"city":"Oslo","forecast":"sunny"}` {
+		t.Errorf("tool message content = %q", got)
+	}
+	if len(req.Proto().Messages) != 4 {
+		t.Errorf("request should hold the full conversation, got %d messages", len(req.Proto().Messages))
+	}
+}
+
+func TestToolRunnerHandlerErrorIsReportedToModel(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{
+		toolCallResponse(functionCall("call-1", "lookup", `{}`)),
+		textResponse("Sorry."),
+	}}
+	runner := NewToolRunner(client).Register("lookup", func(context.Context, *ToolCall) (any, error) {
+		return nil, errors.New("backend down")
+	})
+
+	result, err := runner.Run(context.Background(), NewRequest("grok-4", WithMessage(User(Text("hi")))))
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Steps[0].Err == nil {
+		t.Fatal("expected step error")
+	}
+	if result.Steps[0].Result.Error() == nil {
+		t.Fatal("expected error tool result")
+	}
+	if got := client.requests[1].Messages[2].Content[0].GetText(); got != "tool lookup failed: backend down" {
+		t.Errorf("tool message = %q", got)
+	}
+}
+
+func TestToolRunnerMaxTurns(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{
+		toolCallResponse(functionCall("call-1", "loop", `{}`)),
+		toolCallResponse(functionCall("call-2", "loop", `{}`)),
+		toolCallResponse(functionCall("call-3", "loop", `{}`)),
+	}}
+	runner := NewToolRunner(client).Register("loop", func(context.Context, *ToolCall) (any, error) {
+		return "again", nil
+	})
+
+	req := NewRequest("grok-4", WithMessage(User(Text("hi"))), WithMaxTurns(2))
+	result, err := runner.Run(context.Background(), req)
+	if !errors.Is(err, ErrMaxTurnsExceeded) {
+		t.Fatalf("Run() error = %v, want ErrMaxTurnsExceeded", err)
+	}
+	if len(result.Responses) != 2 {
+		t.Errorf("responses = %d, want 2", len(result.Responses))
+	}
+}
+
+func TestToolRunnerUnknownTool(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{
+		toolCallResponse(functionCall("call-1", "missing", `{}`)),
+	}}
+	_, err := NewToolRunner(client).Run(context.Background(), NewRequest("grok-4", WithMessage(User(Text("hi")))))
+	if !errors.Is(err, ErrToolHandlerNotFound) {
+		t.Fatalf("Run() error = %v, want ErrToolHandlerNotFound", err)
+	}
+}
+
+// completionClient is a ChatClient answering GetCompletion with a fake.
+type completionClient struct {
+	xaiv1.ChatClient
+	fake *fakeServiceClient
+}
+
+func (c completionClient) GetCompletion(ctx context.Context, in *xaiv1.GetCompletionsRequest, opts ...grpc.CallOption) (*xaiv1.GetChatCompletionResponse, error) {
+	return c.fake.GetCompletion(ctx, in, opts...)
+}
+
+func TestClientRunToolsSharedRunner(t *testing.T) {
+	fake := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{textResponse("one"), textResponse("two")}}
+	client := NewClient(completionClient{fake: fake})
+	runner := NewToolRunner(nil)
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.RunTools(context.Background(), NewRequest("grok-4", WithMessage(User(Text("hi")))), runner); err != nil {
+				t.Errorf("RunTools() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if runner.client != nil {
+		t.Error("RunTools() should not modify the runner")
+	}
+}
+
+func TestToolRunnerPassesThroughServerSideCalls(t *testing.T) {
+	serverCall := &xaiv1.ToolCall{
+		Id:     "ws-1",
+		Type:   xaiv1.ToolCallType_TOOL_CALL_TYPE_WEB_SEARCH_TOOL,
+		Status: xaiv1.ToolCallStatus_TOOL_CALL_STATUS_COMPLETED,
+		Tool: &xaiv1.ToolCall_Function{
+			Function: &xaiv1.FunctionCall{Name: "web_search", Arguments: `{"query":"go"}`},
+		},
+	}
+	resp := toolCallResponse(serverCall)
+	resp.Outputs[0].Message.Content = "Go is a language."
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{resp}}
+
+	req := NewRequest("grok-4", WithMessage(User(Text("What is Go?"))))
+	result, err := NewToolRunner(client).Run(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(result.Steps) != 0 {
+		t.Errorf("server-side calls must not be executed, got %d steps", len(result.Steps))
+	}
+	assistant := req.Proto().Messages[1]
+	if len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].Id != "ws-1" {
+		t.Errorf("server-side call not preserved: %+v", assistant.ToolCalls)
+	}
+}
+
+func TestToolRunnerParallelAndTimeout(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{
+		toolCallResponse(
+			functionCall("call-1", "slow", `{}`),
+			functionCall("call-2", "slow", `{}`),
+			functionCall("call-3", "hang", `{}`),
+		),
+		textResponse("done"),
+	}}
+
+	var running, peak int32
+	runner := NewToolRunner(client).
+		Register("slow", func(context.Context, *ToolCall) (any, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return "ok", nil
+		}).
+		RegisterWithTimeout("hang", 10*time.Millisecond, func(ctx context.Context, _ *ToolCall) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+	req := NewRequest("grok-4", WithMessage(User(Text("hi"))), WithParallelToolCalls(true))
+	result, err := runner.Run(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if atomic.LoadInt32(&peak) < 2 {
+		t.Errorf("handlers did not run concurrently, peak = %d", peak)
+	}
+	if len(result.Steps) != 3 {
+		t.Fatalf("steps = %d, want 3", len(result.Steps))
+	}
+	for i, id := range []string{"call-1", "call-2", "call-3"} {
+		if result.Steps[i].Call.ID() != id {
+			t.Errorf("step %d call id = %q, want %q", i, result.Steps[i].Call.ID(), id)
+		}
+	}
+	if !errors.Is(result.Steps[2].Err, context.DeadlineExceeded) {
+		t.Errorf("hang step error = %v, want deadline exceeded", result.Steps[2].Err)
+	}
+}
@@ -0,0 +1,612 @@
+// Package chat provides JSON schema generation and validation for Go types.
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONSchemaFor derives a JSON schema from the Go type T.
+//
+// Struct fields are named after their json tag and are required unless they are
+// pointers or tagged omitempty. Additional constraints are read from the
+// jsonschema tag as comma-separated key=value pairs:
+//
+//	type Args struct {
+//	    City  string  `json:"city" jsonschema:"description=City name,minLength=1"`
+//	    Unit  string  `json:"unit" jsonschema:"enum=celsius,enum=fahrenheit"`
+//	    Days  int     `json:"days" jsonschema:"minimum=1,maximum=14"`
+//	    Notes *string `json:"notes"`
+//	}
+//
+// Supported keys are description, title, enum, format, pattern, minimum,
+// maximum, exclusiveMinimum, exclusiveMaximum, minLength, maxLength, minItems,
+// maxItems and required. Descriptions containing commas can be supplied with a
+// separate jsonschema_description tag.
+func JSONSchemaFor[T any]() (map[string]interface{}, error) {
+	return jsonSchemaForType(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func jsonSchemaForType(t reflect.Type) (map[string]interface{}, error) {
+	g := &schemaGenerator{visiting: make(map[reflect.Type]bool)}
+	return g.schema(t)
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+type schemaGenerator struct {
+	visiting map[reflect.Type]bool
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	case rawMessageType:
+		return map[string]interface{}{}, nil
+	}
+
+	if schema, ok := scalarSchema(t.Kind()); ok {
+		return schema, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s: only string keys are allowed", t.Key())
+		}
+		values, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return g.structSchema(t)
+	default:
+		return nil, fmt.Errorf("unsupported type %s for JSON schema", t)
+	}
+}
+
+// scalarSchema returns the schema of a kind without elements or fields.
+func scalarSchema(kind reflect.Kind) (map[string]interface{}, bool) {
+	switch kind {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, true
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, true
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, true
+	case reflect.Interface:
+		return map[string]interface{}{}, true
+	}
+	return nil, false
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) (map[string]interface{}, error) {
+	if g.visiting[t] {
+		return nil, fmt.Errorf("recursive type %s is not supported for JSON schema", t)
+	}
+	g.visiting[t] = true
+	defer delete(g.visiting, t)
+
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+	if err := g.addFields(t, properties, &required); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}, nil
+}
+
+func (g *schemaGenerator) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitempty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		// Embedded structs without a json name are flattened, like encoding/json does.
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := g.addFields(ft, properties, required); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		prop, err := g.schema(field.Type)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		isRequired := field.Type.Kind() != reflect.Pointer && !omitempty
+		if isRequired, err = applySchemaTag(prop, field, isRequired); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		properties[name] = prop
+		if isRequired {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+// jsonFieldName returns the JSON name of a struct field as encoding/json would.
+func jsonFieldName(field reflect.StructField) (name string, omitempty, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" || opt == "omitzero" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
+
+// applySchemaTag merges jsonschema tag constraints into prop and reports whether the field is required.
+func applySchemaTag(prop map[string]interface{}, field reflect.StructField, required bool) (bool, error) {
+	if desc := field.Tag.Get("jsonschema_description"); desc != "" {
+		prop["description"] = desc
+	}
+	tag := field.Tag.Get("jsonschema")
+	if tag == "" {
+		return required, nil
+	}
+
+	schemaType, _ := prop["type"].(string)
+	var enum []interface{}
+	for _, part := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "":
+			continue
+		case "required":
+			required = value == "" || value == "true"
+		case "enum":
+			v, err := parseSchemaValue(schemaType, value)
+			if err != nil {
+				return required, fmt.Errorf("invalid enum value %q: %w", value, err)
+			}
+			enum = append(enum, v)
+		default:
+			if err := applySchemaConstraint(prop, key, value); err != nil {
+				return required, err
+			}
+		}
+	}
+	if len(enum) > 0 {
+		prop["enum"] = enum
+	}
+	return required, nil
+}
+
+// applySchemaConstraint sets the jsonschema tag constraint key on prop.
+func applySchemaConstraint(prop map[string]interface{}, key, value string) error {
+	switch key {
+	case "description", "title", "format", "pattern":
+		if key == "pattern" {
+			if _, err := regexp.Compile(value); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", value, err)
+			}
+		}
+		prop[key] = value
+	case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+		prop[key] = n
+	case "minLength", "maxLength", "minItems", "maxItems":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s %q", key, value)
+		}
+		prop[key] = n
+	default:
+		return fmt.Errorf("unknown jsonschema tag key %q", key)
+	}
+	return nil
+}
+
+// strictSchema returns a copy of schema suitable for strict mode, which
+// requires every property of an object to be listed in required and objects
+// to reject additional properties: optional properties become nullable
+// instead. The schema itself is not modified.
+func strictSchema(schema map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		out[key] = value
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		out["items"] = strictSchema(items)
+	}
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		return out
+	}
+
+	required := schemaStrings(schema["required"])
+	all := slices.Clone(required)
+	strictProperties := make(map[string]interface{}, len(properties))
+	for name, prop := range properties {
+		propSchema, ok := prop.(map[string]interface{})
+		if !ok {
+			strictProperties[name] = prop
+		} else if propSchema = strictSchema(propSchema); slices.Contains(required, name) {
+			strictProperties[name] = propSchema
+		} else {
+			strictProperties[name] = nullableSchema(propSchema)
+		}
+		if !slices.Contains(required, name) {
+			all = append(all, name)
+		}
+	}
+	// Optional properties follow the required ones in a stable order
+	sort.Strings(all[len(required):])
+
+	out["properties"] = strictProperties
+	out["required"] = all
+	out["additionalProperties"] = false
+	return out
+}
+
+// nullableSchema makes a property schema also accept null. Untyped schemas
+// already accept any value.
+func nullableSchema(prop map[string]interface{}) map[string]interface{} {
+	schemaType, ok := prop["type"].(string)
+	if !ok {
+		return prop
+	}
+	prop["type"] = []string{schemaType, "null"}
+	if enum, ok := prop["enum"].([]interface{}); ok {
+		prop["enum"] = append(slices.Clone(enum), nil)
+	}
+	return prop
+}
+
+func parseSchemaValue(schemaType, value string) (interface{}, error) {
+	switch schemaType {
+	case "integer":
+		return strconv.ParseInt(value, 10, 64)
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	default:
+		return value, nil
+	}
+}
+
+// FieldError describes a single schema violation at a JSON path.
+type FieldError struct {
+	// Path is the location of the offending value, e.g. "items[2].name".
+	// It is empty for the root value.
+	Path string
+	// Message describes the violation.
+	Message string
+}
+
+// String returns a human-readable representation of the field error.
+func (e FieldError) String() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError is returned when a JSON value does not conform to a schema.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.String()
+	}
+	return "schema validation failed: " + strings.Join(msgs, "; ")
+}
+
+// ValidateJSON validates a JSON document against a JSON schema produced by JSONSchemaFor
+// (or any schema using the same subset of keywords). It returns a *ValidationError
+// listing every violation.
+func ValidateJSON(data []byte, schema map[string]interface{}) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	var errs []FieldError
+	validateValue(value, schema, "", &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func validateValue(value interface{}, schema map[string]interface{}, path string, errs *[]FieldError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && !enumContains(enum, value) {
+		fail("value %v is not one of %v", value, enum)
+		return
+	}
+
+	schemaType, nullable := schemaTypeOf(schema)
+	if value == nil && nullable {
+		return
+	}
+	switch schemaType {
+	case "string":
+		validateString(value, schema, fail)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("expected boolean, got %s", jsonTypeName(value))
+		}
+	case "integer", "number":
+		validateNumber(value, schemaType, schema, fail)
+	case "array":
+		validateArray(value, schema, path, errs, fail)
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			fail("expected object, got %s", jsonTypeName(value))
+			return
+		}
+		validateObject(obj, schema, path, errs)
+	}
+}
+
+// schemaTypeOf returns the type of a schema and whether it also accepts null,
+// for both single types and [type, "null"] lists.
+func schemaTypeOf(schema map[string]interface{}) (schemaType string, nullable bool) {
+	if s, ok := schema["type"].(string); ok {
+		return s, false
+	}
+	for _, t := range schemaStrings(schema["type"]) {
+		if t == "null" {
+			nullable = true
+		} else if schemaType == "" {
+			schemaType = t
+		}
+	}
+	return schemaType, nullable
+}
+
+func validateArray(value interface{}, schema map[string]interface{}, path string, errs *[]FieldError, fail func(string, ...interface{})) {
+	items, ok := value.([]interface{})
+	if !ok {
+		fail("expected array, got %s", jsonTypeName(value))
+		return
+	}
+	if limit, ok := schemaInt(schema, "minItems"); ok && len(items) < limit {
+		fail("expected at least %d items, got %d", limit, len(items))
+	}
+	if limit, ok := schemaInt(schema, "maxItems"); ok && len(items) > limit {
+		fail("expected at most %d items, got %d", limit, len(items))
+	}
+	if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range items {
+			validateValue(item, itemSchema, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func validateString(value interface{}, schema map[string]interface{}, fail func(string, ...interface{})) {
+	s, ok := value.(string)
+	if !ok {
+		fail("expected string, got %s", jsonTypeName(value))
+		return
+	}
+	length := len([]rune(s))
+	if limit, ok := schemaInt(schema, "minLength"); ok && length < limit {
+		fail("length %d is shorter than %d", length, limit)
+	}
+	if limit, ok := schemaInt(schema, "maxLength"); ok && length > limit {
+		fail("length %d is longer than %d", length, limit)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(s) {
+			fail("value %q does not match pattern %q", s, pattern)
+		}
+	}
+	if format, ok := schema["format"].(string); ok && format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			fail("value %q is not an RFC 3339 date-time", s)
+		}
+	}
+}
+
+func validateNumber(value interface{}, schemaType string, schema map[string]interface{}, fail func(string, ...interface{})) {
+	n, ok := value.(float64)
+	if !ok {
+		fail("expected %s, got %s", schemaType, jsonTypeName(value))
+		return
+	}
+	if schemaType == "integer" && n != math.Trunc(n) {
+		fail("expected integer, got %v", n)
+		return
+	}
+	if limit, ok := schemaFloat(schema, "minimum"); ok && n < limit {
+		fail("value %v is less than minimum %v", n, limit)
+	}
+	if limit, ok := schemaFloat(schema, "maximum"); ok && n > limit {
+		fail("value %v is greater than maximum %v", n, limit)
+	}
+	if limit, ok := schemaFloat(schema, "exclusiveMinimum"); ok && n <= limit {
+		fail("value %v must be greater than %v", n, limit)
+	}
+	if limit, ok := schemaFloat(schema, "exclusiveMaximum"); ok && n >= limit {
+		fail("value %v must be less than %v", n, limit)
+	}
+}
+
+func validateObject(obj map[string]interface{}, schema map[string]interface{}, path string, errs *[]FieldError) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, name := range schemaStrings(schema["required"]) {
+		propSchema, _ := properties[name].(map[string]interface{})
+		if _, nullable := schemaTypeOf(propSchema); nullable {
+			if _, ok := obj[name]; !ok {
+				*errs = append(*errs, FieldError{Path: joinPath(path, name), Message: "required field is missing"})
+			}
+		} else if v, ok := obj[name]; !ok || v == nil {
+			*errs = append(*errs, FieldError{Path: joinPath(path, name), Message: "required field is missing"})
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := obj[k]
+		if propSchema, ok := properties[k].(map[string]interface{}); ok {
+			if v == nil {
+				continue
+			}
+			validateValue(v, propSchema, joinPath(path, k), errs)
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra && properties != nil {
+				*errs = append(*errs, FieldError{Path: joinPath(path, k), Message: "unknown field"})
+			}
+		case map[string]interface{}:
+			validateValue(v, extra, joinPath(path, k), errs)
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func enumContains(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		switch ev := e.(type) {
+		case int64:
+			if n, ok := value.(float64); ok && n == float64(ev) {
+				return true
+			}
+		case int:
+			if n, ok := value.(float64); ok && n == float64(ev) {
+				return true
+			}
+		default:
+			if reflect.DeepEqual(e, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func schemaInt(schema map[string]interface{}, key string) (int, bool) {
+	switch v := schema[key].(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+func schemaFloat(schema map[string]interface{}, key string) (float64, bool) {
+	switch v := schema[key].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func schemaStrings(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,202 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+)
+
+func deltaChunk(index int32, delta *xaiv1.Delta, finish xaiv1.FinishReason) *xaiv1.GetChatCompletionChunk {
+	return &xaiv1.GetChatCompletionChunk{
+		Id:    "resp-1",
+		Model: "grok-4",
+		Outputs: []*xaiv1.CompletionOutputChunk{{
+			Index:        index,
+			Delta:        delta,
+			FinishReason: finish,
+		}},
+	}
+}
+
+func drainStream(t *testing.T, client ServiceClient, req *Request) *Stream {
+	t.Helper()
+	stream, err := req.Stream(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	for stream.Next() {
+	}
+	if err := stream.Err(); err != nil && !errors.Is(err, io.EOF) {
+		t.Fatalf("stream error = %v", err)
+	}
+	return stream
+}
+
+func TestStreamResponseAccumulatesChunks(t *testing.T) {
+	client := &fakeServiceClient{chunks: [][]*xaiv1.GetChatCompletionChunk{{
+		deltaChunk(0, &xaiv1.Delta{Role: xaiv1.MessageRole_ROLE_ASSISTANT, ReasoningContent: "Thinking"}, xaiv1.FinishReason_REASON_INVALID),
+		deltaChunk(0, &xaiv1.Delta{ReasoningContent: " hard", EncryptedContent: "enc"}, xaiv1.FinishReason_REASON_INVALID),
+		deltaChunk(0, &xaiv1.Delta{Content: "Hello"}, xaiv1.FinishReason_REASON_INVALID),
+		deltaChunk(0, &xaiv1.Delta{Content: ", world"}, xaiv1.FinishReason_REASON_INVALID),
+		{
+			Id:        "resp-1",
+			Usage:     &xaiv1.SamplingUsage{PromptTokens: 10, CompletionTokens: 4, TotalTokens: 14},
+			Citations: []string{"https://x.ai"},
+			Outputs: []*xaiv1.CompletionOutputChunk{{
+				Index:        0,
+				FinishReason: xaiv1.FinishReason_REASON_STOP,
+			}},
+		},
+	}}}
+
+	stream := drainStream(t, client, NewRequest("grok-4", WithMessage(User(Text("hi")))))
+	resp := stream.Response()
+
+	if resp.ID() != "resp-1" || resp.Model() != "grok-4" {
+		t.Errorf("id = %q, model = %q", resp.ID(), resp.Model())
+	}
+	if resp.Content() != "Hello, world" {
+		t.Errorf("Content() = %q", resp.Content())
+	}
+	if resp.ReasoningContent() != "Thinking hard" {
+		t.Errorf("ReasoningContent() = %q", resp.ReasoningContent())
+	}
+	if resp.EncryptedContent() != "enc" {
+		t.Errorf("EncryptedContent() = %q", resp.EncryptedContent())
+	}
+	if resp.Role() != "assistant" {
+		t.Errorf("Role() = %q", resp.Role())
+	}
+	if resp.FinishReason() != xaiv1.FinishReason_REASON_STOP.String() {
+		t.Errorf("FinishReason() = %q", resp.FinishReason())
+	}
+	if resp.Usage().TotalTokens() != 14 {
+		t.Errorf("TotalTokens() = %d", resp.Usage().TotalTokens())
+	}
+	if len(resp.Citations()) != 1 {
+		t.Errorf("Citations() = %v", resp.Citations())
+	}
+}
+
+func TestStreamResponseMergesToolCallDeltas(t *testing.T) {
+	call := func(id, name, args string) *xaiv1.ToolCall {
+		tc := functionCall(id, name, args)
+		tc.Status = xaiv1.ToolCallStatus_TOOL_CALL_STATUS_IN_PROGRESS
+		return tc
+	}
+	client := &fakeServiceClient{chunks: [][]*xaiv1.GetChatCompletionChunk{{
+		deltaChunk(0, &xaiv1.Delta{Role: xaiv1.MessageRole_ROLE_ASSISTANT, ToolCalls: []*xaiv1.ToolCall{call("call-1", "get_weather", `{"city":`)}}, xaiv1.FinishReason_REASON_INVALID),
+		deltaChunk(0, &xaiv1.Delta{ToolCalls: []*xaiv1.ToolCall{call("call-1", "", `"Oslo"}`)}}, xaiv1.FinishReason_REASON_INVALID),
+		deltaChunk(0, &xaiv1.Delta{ToolCalls: []*xaiv1.ToolCall{call("call-2", "get_time", `{"tz":"UTC"}`)}}, xaiv1.FinishReason_REASON_INVALID),
+		deltaChunk(0, &xaiv1.Delta{ToolCalls: []*xaiv1.ToolCall{call("call-2", "get_time", `{"tz":"UTC"}`)}}, xaiv1.FinishReason_REASON_TOOL_CALLS),
+	}}}
+
+	req := NewRequest("grok-4", WithMessage(User(Text("hi"))))
+	stream := drainStream(t, client, req)
+
+	calls := stream.Response().ToolCalls()
+	if len(calls) != 2 {
+		t.Fatalf("ToolCalls() = %d, want 2", len(calls))
+	}
+	if calls[0].Name() != "get_weather" || calls[0].ArgumentsJSON() != `{"city":"Oslo"}` {
+		t.Errorf("first call = %s(%s)", calls[0].Name(), calls[0].ArgumentsJSON())
+	}
+	if calls[1].ArgumentsJSON() != `{"tz":"UTC"}` {
+		t.Errorf("cumulative arguments should not be duplicated: %s", calls[1].ArgumentsJSON())
+	}
+
+	req.AppendResponse(stream.Response())
+	last := req.Proto().Messages[len(req.Proto().Messages)-1]
+	if last.Role != xaiv1.MessageRole_ROLE_ASSISTANT || len(last.ToolCalls) != 2 {
+		t.Errorf("appended message = %v", last)
+	}
+}
+
+func TestStreamResponseKeepsOutputsInIndexOrder(t *testing.T) {
+	client := &fakeServiceClient{chunks: [][]*xaiv1.GetChatCompletionChunk{{
+		deltaChunk(1, &xaiv1.Delta{Role: xaiv1.MessageRole_ROLE_ASSISTANT, Content: "second"}, xaiv1.FinishReason_REASON_STOP),
+		deltaChunk(0, &xaiv1.Delta{Role: xaiv1.MessageRole_ROLE_ASSISTANT, Content: "first"}, xaiv1.FinishReason_REASON_STOP),
+	}}}
+
+	stream := drainStream(t, client, NewRequest("grok-4", WithMessage(User(Text("hi"))), WithN(2)))
+	outputs := stream.Response().Proto().Outputs
+	if len(outputs) != 2 {
+		t.Fatalf("outputs = %d, want 2", len(outputs))
+	}
+	if outputs[0].Message.Content != "first" || outputs[1].Message.Content != "second" {
+		t.Errorf("outputs = %v", outputs)
+	}
+}
+
+func TestStreamAllYieldsChunksAndHidesEOF(t *testing.T) {
+	client := &fakeServiceClient{chunks: [][]*xaiv1.GetChatCompletionChunk{{
+		deltaChunk(0, &xaiv1.Delta{Content: "a"}, xaiv1.FinishReason_REASON_INVALID),
+		deltaChunk(0, &xaiv1.Delta{Content: "b"}, xaiv1.FinishReason_REASON_STOP),
+	}}}
+	stream, err := NewRequest("grok-4", WithMessage(User(Text("hi")))).Stream(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	var content string
+	for chunk, err := range stream.All() {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		content += chunk.Content()
+	}
+	if content != "ab" || stream.Response().Content() != "ab" {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestStreamAllEarlyBreakClosesStream(t *testing.T) {
+	client := &fakeServiceClient{chunks: [][]*xaiv1.GetChatCompletionChunk{{
+		deltaChunk(0, &xaiv1.Delta{Content: "a"}, xaiv1.FinishReason_REASON_INVALID),
+		deltaChunk(0, &xaiv1.Delta{Content: "b"}, xaiv1.FinishReason_REASON_STOP),
+	}}}
+	stream, err := NewRequest("grok-4", WithMessage(User(Text("hi")))).Stream(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	for range stream.All() {
+		break
+	}
+	underlying := stream.stream.(*fakeChunkStream)
+	if !underlying.closed {
+		t.Error("breaking out of All() should close the stream")
+	}
+	if underlying.ctx.Err() == nil {
+		t.Error("breaking out of All() should cancel the stream context")
+	}
+}
+
+func TestBatchStreamAll(t *testing.T) {
+	client := &fakeServiceClient{chunks: [][]*xaiv1.GetChatCompletionChunk{{
+		deltaChunk(0, &xaiv1.Delta{Content: "x"}, xaiv1.FinishReason_REASON_STOP),
+		deltaChunk(1, &xaiv1.Delta{Content: "y"}, xaiv1.FinishReason_REASON_STOP),
+	}}}
+	stream, err := NewRequest("grok-4", WithMessage(User(Text("hi")))).StreamBatch(context.Background(), client, 2)
+	if err != nil {
+		t.Fatalf("StreamBatch() error = %v", err)
+	}
+
+	steps := 0
+	for chunks, err := range stream.All() {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(chunks) != 2 {
+			t.Fatalf("chunks = %d, want 2", len(chunks))
+		}
+		steps++
+	}
+	responses := stream.CurrentResponses()
+	if steps != 2 || responses[0].Content() != "x" || responses[1].Content() != "y" {
+		t.Errorf("steps = %d, responses = %q, %q", steps, responses[0].Content(), responses[1].Content())
+	}
+}
@@ -16,6 +16,8 @@ type Tool struct {
 	description string
 	parameters  map[string]interface{}
 	strict      bool // Enable strict schema validation
+	schema      map[string]interface{}
+	handler     ToolHandler
 }
 
 // NewTool creates a new tool with the given name and description.
@@ -27,7 +29,9 @@ func NewTool(name, description string) *Tool {
 	}
 }
 
-// WithParameter adds a parameter to the tool.
+// WithParameter adds a parameter to the tool. Tools created with FunctionTool
+// take their parameters from the arguments type instead: Validate reports
+// parameters added to them.
 func (t *Tool) WithParameter(name string, paramType string, description string, required bool) *Tool {
 	if t.parameters == nil {
 		t.parameters = make(map[string]interface{})
@@ -41,7 +45,8 @@ func (t *Tool) WithParameter(name string, paramType string, description string,
 	return t
 }
 
-// WithParameters adds multiple parameters to the tool.
+// WithParameters adds multiple parameters to the tool. As with WithParameter,
+// they cannot be combined with the schema of a FunctionTool.
 func (t *Tool) WithParameters(params map[string]interface{}) *Tool {
 	if t.parameters == nil {
 		t.parameters = make(map[string]interface{})
@@ -63,8 +68,14 @@ func (t *Tool) Description() string {
 	return t.description
 }
 
-// Parameters returns the tool parameters.
+// Parameters returns the tool parameters. For tools created with
+// FunctionTool, these are the properties of the generated schema.
 func (t *Tool) Parameters() map[string]interface{} {
+	if t.schema != nil {
+		if properties, ok := t.schema["properties"].(map[string]interface{}); ok {
+			return properties
+		}
+	}
 	if t.parameters == nil {
 		return make(map[string]interface{})
 	}
@@ -73,6 +84,8 @@ func (t *Tool) Parameters() map[string]interface{} {
 
 // WithStrict enables strict schema validation for this tool.
 // When enabled, the model will strictly validate function arguments against the schema.
+// Strict mode requires every property to be listed as required, so
+// ToJSONSchema makes optional parameters nullable instead.
 func (t *Tool) WithStrict(strict bool) *Tool {
 	t.strict = strict
 	return t
@@ -83,8 +96,25 @@ func (t *Tool) Strict() bool {
 	return t.strict
 }
 
+// Handler returns the handler attached to the tool, if it was created with FunctionTool.
+func (t *Tool) Handler() ToolHandler {
+	return t.handler
+}
+
 // ToJSONSchema converts the tool to JSON schema format.
 func (t *Tool) ToJSONSchema() map[string]interface{} {
+	schema := t.schema
+	if schema == nil {
+		schema = t.parametersSchema()
+	}
+	if t.strict {
+		return strictSchema(schema)
+	}
+	return schema
+}
+
+// parametersSchema builds the schema of the parameters added with WithParameter.
+func (t *Tool) parametersSchema() map[string]interface{} {
 	properties := make(map[string]interface{})
 	required := make([]string, 0)
 
@@ -138,6 +168,9 @@ func (t *Tool) Validate() error {
 	if len(t.parameters) == 0 {
 		return nil // Tools without parameters are valid
 	}
+	if t.schema != nil {
+		return fmt.Errorf("tool '%s' takes its parameters from its arguments type and cannot have parameters added", t.name)
+	}
 
 	for name, param := range t.parameters {
 		if name == "" {
@@ -166,6 +199,7 @@ type ToolCall struct {
 	id           string
 	name         string
 	arguments    map[string]interface{}
+	rawArguments string
 	status       string
 	errorMessage string
 	toolType     ToolCallType
@@ -198,6 +232,18 @@ func (tc *ToolCall) Arguments() map[string]interface{} {
 	return tc.arguments
 }
 
+// ArgumentsJSON returns the tool call arguments as the raw JSON string sent by the model.
+func (tc *ToolCall) ArgumentsJSON() string {
+	if tc.rawArguments != "" {
+		return tc.rawArguments
+	}
+	data, err := json.Marshal(tc.Arguments())
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
 // Status returns the tool call status.
 func (tc *ToolCall) Status() string {
 	return tc.status
@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
@@ -543,3 +544,64 @@ func TestGetToolCallType(t *testing.T) {
 		t.Errorf("Expected 'attachment_search', got %q", result)
 	}
 }
+
+func TestToolChoiceFunction(t *testing.T) {
+	req := NewRequest("grok-4",
+		WithMessage(User(Text("Extract the invoice"))),
+		WithTool(NewTool("extract_invoice", "Extract invoice fields")),
+		WithToolChoice(ToolChoiceFunction("extract_invoice")),
+	)
+
+	if got := req.Proto().GetToolChoice().GetFunctionName(); got != "extract_invoice" {
+		t.Fatalf("function_name = %q, want extract_invoice", got)
+	}
+	if choice := req.ToolChoice(); choice != ToolChoiceFunction("extract_invoice") || choice.FunctionName() != "extract_invoice" {
+		t.Errorf("ToolChoice() = %q", choice)
+	}
+	if ToolChoiceRequired.FunctionName() != "" {
+		t.Error("modes must not report a function name")
+	}
+
+	req.SetToolChoice(ToolChoiceNone)
+	if req.Proto().GetToolChoice().GetMode() != xaiv1.ToolMode_TOOL_MODE_NONE || req.ToolChoice() != ToolChoiceNone {
+		t.Errorf("tool choice = %v", req.Proto().GetToolChoice())
+	}
+}
+
+func TestToolChoiceFunctionMustExist(t *testing.T) {
+	client := &fakeServiceClient{responses: []*xaiv1.GetChatCompletionResponse{textResponse("ok")}}
+	req := NewRequest("grok-4",
+		WithMessage(User(Text("hi"))),
+		WithTool(NewTool("lookup", "Look something up")),
+		WithToolChoice(ToolChoiceFunction("missing")),
+	)
+
+	if _, err := req.Sample(context.Background(), client); err == nil {
+		t.Fatal("expected error for a tool choice naming an unknown function")
+	}
+	if len(client.requests) != 0 {
+		t.Error("invalid request must not be sent")
+	}
+
+	req.SetToolChoice(ToolChoiceFunction("lookup"))
+	if _, err := req.Sample(context.Background(), client); err != nil {
+		t.Fatalf("Sample() error = %v", err)
+	}
+}
+
+func TestRequestSettingsToolChoice(t *testing.T) {
+	settings := &RequestSettings{proto: &xaiv1.RequestSettings{
+		ToolChoice: &xaiv1.ToolChoice{ToolChoice: &xaiv1.ToolChoice_FunctionName{FunctionName: "lookup"}},
+	}}
+	if got := settings.ToolChoice(); got.FunctionName() != "lookup" {
+		t.Errorf("ToolChoice() = %q", got)
+	}
+
+	settings.proto.ToolChoice = &xaiv1.ToolChoice{ToolChoice: &xaiv1.ToolChoice_Mode{Mode: xaiv1.ToolMode_TOOL_MODE_AUTO}}
+	if got := settings.ToolChoice(); got != ToolChoiceAuto {
+		t.Errorf("ToolChoice() = %q, want auto", got)
+	}
+	if (&RequestSettings{}).ToolChoice() != "" {
+		t.Error("nil settings should report no tool choice")
+	}
+}
@@ -4,6 +4,7 @@ package xai
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/ZaguanLabs/xai-sdk-go/xai/tokenizer"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/video"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 )
 
 // Client represents the main xAI SDK client.
@@ -40,6 +42,7 @@ type Client struct {
 	isClosed             bool
 	createdAt            time.Time
 	metadata             *metadata.SDKMetadata
+	logger               *slog.Logger
 }
 
 // NewClient creates a new xAI client with the given configuration.
@@ -60,33 +63,57 @@ func NewClient(config *Config) (*Client, error) {
 		config:    config,
 		metadata:  metadata,
 		createdAt: time.Now(),
+		logger:    config.logger(),
+	}
+
+	// Create REST clients sharing the TLS and proxy settings of the gRPC connection
+	transportOptions := config.transportOptions()
+	tlsConfig, err := transportOptions.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", errors.NewConfigError(err.Error()))
+	}
+	proxy, err := transportOptions.Proxy()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", errors.NewConfigError(err.Error()))
+	}
+
+	httpClient := config.HTTPClient
+	if config.Cassette != nil {
+		httpClient = config.Cassette.HTTPClient(httpClient)
 	}
 
-	// Create REST client
 	baseURL := fmt.Sprintf("https://%s/v1", config.HTTPHost)
 	if config.Insecure {
 		baseURL = fmt.Sprintf("http://%s/v1", config.HTTPHost)
 	}
 	client.restClient = rest.NewClient(rest.Config{
-		BaseURL:   baseURL,
-		APIKey:    config.APIKey,
-		UserAgent: metadata.UserAgent,
-		Timeout:   config.Timeout,
+		BaseURL:     baseURL,
+		Credentials: config.CredentialsProvider(),
+		UserAgent:   metadata.UserAgent,
+		Timeout:     config.Timeout,
+		Retry:       config.RetryPolicy(),
+		RateLimiter: config.RateLimiter,
+		TLSConfig:   tlsConfig,
+		Proxy:       proxy,
+		HTTPClient:  httpClient,
+		Observer:    config.observer(),
 	})
 
 	managementBaseURL := fmt.Sprintf("https://%s/v1", config.ManagementAPIHost)
 	if config.Insecure {
 		managementBaseURL = fmt.Sprintf("http://%s/v1", config.ManagementAPIHost)
 	}
-	managementAPIKey := config.ManagementAPIKey
-	if managementAPIKey == "" {
-		managementAPIKey = config.APIKey
-	}
 	client.managementRestClient = rest.NewClient(rest.Config{
-		BaseURL:   managementBaseURL,
-		APIKey:    managementAPIKey,
-		UserAgent: metadata.UserAgent,
-		Timeout:   config.Timeout,
+		BaseURL:     managementBaseURL,
+		Credentials: config.managementCredentialsProvider(),
+		UserAgent:   metadata.UserAgent,
+		Timeout:     config.Timeout,
+		Retry:       config.RetryPolicy(),
+		RateLimiter: config.RateLimiter,
+		TLSConfig:   tlsConfig,
+		Proxy:       proxy,
+		HTTPClient:  httpClient,
+		Observer:    config.observer(),
 	})
 
 	// Create gRPC connection
@@ -116,7 +143,11 @@ func NewClientFromEnvironment() (*Client, error) {
 func (c *Client) createGRPCConnection() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.createGRPCConnectionLocked()
+}
 
+// createGRPCConnectionLocked creates the gRPC connection; c.mu must be held.
+func (c *Client) createGRPCConnectionLocked() error {
 	if c.isClosed {
 		return errors.NewConfigError("client is closed")
 	}
@@ -131,13 +162,15 @@ func (c *Client) createGRPCConnection() error {
 	// Note: NewClient doesn't establish connection immediately - it's lazy
 	// Connection happens on first RPC call
 	grpcConn, err := grpc.NewClient(
-		c.config.GRPCAddress(),
+		c.config.grpcTarget(),
 		dialOptions...,
 	)
 
 	if err != nil {
+		c.logger.Error("xai gRPC connection failed", "address", c.config.GRPCAddress(), "error", err)
 		return fmt.Errorf("failed to dial gRPC server at %s: %w", c.config.GRPCAddress(), err)
 	}
+	c.logger.Debug("xai gRPC connection created", "address", c.config.GRPCAddress(), "insecure", c.config.Insecure)
 
 	c.grpcConn = grpcConn
 	c.grpcClient = grpcConn // Alias for compatibility
@@ -268,12 +301,14 @@ func (c *Client) Close() error {
 	// Close gRPC connection
 	if c.grpcConn != nil {
 		if err := c.grpcConn.Close(); err != nil {
+			c.logger.Error("xai client close failed", "error", err)
 			return fmt.Errorf("failed to close gRPC connection: %w", err)
 		}
 		c.grpcConn = nil
 		c.grpcClient = nil
 	}
 
+	c.logger.Debug("xai client closed")
 	return nil
 }
 
@@ -306,12 +341,13 @@ func (c *Client) EnsureGRPCConnection() error {
 	}
 
 	// Check if connection is healthy
-	if c.grpcConn != nil && c.grpcConn.GetState().String() != "TRANSIENT_FAILURE" {
+	if c.grpcConn != nil && c.grpcConn.GetState() != connectivity.TransientFailure {
 		return nil
 	}
 
 	// Recreate connection if needed
-	if err := c.createGRPCConnection(); err != nil {
+	c.logger.Warn("xai gRPC connection unhealthy, reconnecting", "address", c.config.GRPCAddress())
+	if err := c.createGRPCConnectionLocked(); err != nil {
 		return fmt.Errorf("failed to recreate gRPC connection: %w", err)
 	}
 
@@ -353,10 +389,15 @@ func (c *Client) WithTimeout(timeout time.Duration) *Client {
 		modelsClient:         c.modelsClient,
 		createdAt:            c.createdAt,
 		isClosed:             c.isClosed,
+		logger:               c.logger,
 	}
 }
 
-// WithAPIKey creates a new client with a different API key.
+// WithAPIKey creates a new client with a different API key. Like WithTimeout,
+// the new client shares the connections of c, which keep authenticating with
+// the credentials of c; the key is only sent in the metadata added by
+// NewContext. Use CloneWithAPIKey for a client whose calls authenticate with
+// the new key.
 func (c *Client) WithAPIKey(apiKey string) *Client {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -364,12 +405,12 @@ func (c *Client) WithAPIKey(apiKey string) *Client {
 	newConfig := *c.config
 	newConfig.APIKey = apiKey
 
-	newMetadata := c.metadata
+	newMetadata := *c.metadata
 	newMetadata.APIKey = apiKey
 
 	return &Client{
 		config:               &newConfig,
-		metadata:             newMetadata,
+		metadata:             &newMetadata,
 		grpcConn:             c.grpcConn,
 		grpcClient:           c.grpcConn,
 		restClient:           c.restClient,
@@ -378,9 +419,25 @@ func (c *Client) WithAPIKey(apiKey string) *Client {
 		modelsClient:         c.modelsClient,
 		createdAt:            c.createdAt,
 		isClosed:             c.isClosed,
+		logger:               c.logger,
 	}
 }
 
+// CloneWithAPIKey creates a new client with the configuration of c whose calls
+// authenticate with apiKey. It opens its own connections, so it must be closed
+// separately.
+//
+// To rotate the key of a running client instead, set Config.Credentials.
+func (c *Client) CloneWithAPIKey(apiKey string) (*Client, error) {
+	c.mu.RLock()
+	newConfig := *c.config
+	c.mu.RUnlock()
+
+	newConfig.APIKey = apiKey
+	newConfig.Credentials = nil
+	return NewClient(&newConfig)
+}
+
 // String returns a string representation of the client.
 func (c *Client) String() string {
 	c.mu.RLock()
@@ -481,11 +538,18 @@ func (c *Client) Embed() *embed.Client {
 	return embed.NewClient(c.restClient)
 }
 
-// Files returns the files service client.
+// Files returns the files service client. Uploads are streamed over the gRPC
+// connection, which must reach the API host and port; the other operations use
+// the REST API. Without a gRPC connection, such as after Close, uploads fall
+// back to the REST API.
 func (c *Client) Files() *files.Client {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return files.NewClient(c.restClient)
+	var grpcClient xaiv1.FilesClient
+	if c.grpcConn != nil {
+		grpcClient = xaiv1.NewFilesClient(c.grpcConn)
+	}
+	return files.NewClientWithGRPC(c.restClient, grpcClient)
 }
 
 // Collections returns the collections service client.
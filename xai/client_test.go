@@ -1,9 +1,21 @@
 package xai
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai/cassette"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/chat"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/files"
 )
 
 func TestNewClient(t *testing.T) {
@@ -59,6 +71,131 @@ func TestNewClient(t *testing.T) {
 	})
 }
 
+func TestNewClientRESTTransport(t *testing.T) {
+	t.Run("Proxy", func(t *testing.T) {
+		requests := make(chan *http.Request, 1)
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests <- r
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer proxy.Close()
+
+		config := NewConfigWithAPIKey("test-api-key").WithHost("api.example.com").WithInsecure(true).WithProxy(proxy.URL)
+		config.MaxRetries = 0
+		client, err := NewClient(config)
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		defer client.Close()
+
+		_, _ = client.Files().Get(context.Background(), "file-1")
+		r := <-requests
+		if r.URL.Hostname() != "api.example.com" {
+			t.Errorf("proxied request URL = %s, want the API host", r.URL)
+		}
+	})
+
+	t.Run("HTTPClient", func(t *testing.T) {
+		var requested string
+		httpClient := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			requested = r.URL.String()
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"id":"file-1"}`)), Header: http.Header{}}, nil
+		})}
+
+		client, err := NewClient(NewConfigWithAPIKey("test-api-key").WithHTTPClient(httpClient))
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		defer client.Close()
+
+		if _, err := client.Files().Get(context.Background(), "file-1"); err != nil {
+			t.Errorf("Get() error = %v", err)
+		}
+		if !strings.HasSuffix(requested, "/files/file-1") {
+			t.Errorf("requested %q through the custom HTTP client", requested)
+		}
+	})
+
+	t.Run("MissingCABundle", func(t *testing.T) {
+		config := NewConfigWithAPIKey("test-api-key").WithCABundle("/nonexistent/ca.pem")
+		if _, err := NewClient(config); err == nil {
+			t.Error("Should return error for a missing CA bundle")
+		}
+	})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestNewClientLogger(t *testing.T) {
+	const apiKey = "xai-test-0123456789abcdefghij"
+	httpClient := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		header := http.Header{"X-Request-Id": []string{"req-1"}}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"id":"file-1"}`)), Header: header}, nil
+	})}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client, err := NewClient(NewConfigWithAPIKey(apiKey).WithHTTPClient(httpClient).WithLogger(logger, false))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, err := client.Files().Get(context.Background(), "file-1"); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+	client.Close()
+
+	out := buf.String()
+	for _, want := range []string{"xai gRPC connection created", `msg="xai call finished" method="GET /files/file-1"`, "request_id=req-1", "xai client closed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, apiKey) {
+		t.Errorf("log contains the API key:\n%s", out)
+	}
+}
+
+func TestNewClientCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	err := os.WriteFile(path, []byte(`{"version": 1, "interactions": [
+		{"grpc": {"method": "/xai_api.Chat/GetCompletion", "requests": [{}],
+			"responses": [{"id": "resp-1", "outputs": [{"message": {"content": "Hello!"}}]}]}},
+		{"http": {"method": "GET", "url": "/v1/files/file-1", "status_code": 200, "body": "{\"id\":\"file-1\"}"}}
+	]}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byMethod := func(recorded, request cassette.Interaction) bool {
+		if recorded.GRPC != nil && request.GRPC != nil {
+			return recorded.GRPC.Method == request.GRPC.Method
+		}
+		return cassette.DefaultMatcher(recorded, request)
+	}
+	recorder, err := cassette.New(path, cassette.ModeReplay, cassette.WithMatcher(byMethod))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The host does not exist: every call is answered from the cassette.
+	client, err := NewClient(NewConfigWithAPIKey("test-api-key").WithHost("api.invalid").WithCassette(recorder))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+
+	resp, err := chat.NewRequest("grok-4", chat.WithMessages(chat.User(chat.Text("Hi")))).Sample(context.Background(), client.Chat())
+	if err != nil || resp.Content() != "Hello!" {
+		t.Errorf("Sample() = %v, %v", resp, err)
+	}
+	if file, err := client.Files().Get(context.Background(), "file-1"); err != nil || file.ID != "file-1" {
+		t.Errorf("Get() = %v, %v", file, err)
+	}
+}
+
 func TestNewClientWithAPIKey(t *testing.T) {
 	apiKey := "test-api-key"
 	client, err := NewClientWithAPIKey(apiKey)
@@ -356,6 +493,67 @@ func TestClientWithAPIKey(t *testing.T) {
 	if newClient.Config().APIKey != newAPIKey {
 		t.Errorf("Expected API key %s, got %s", newAPIKey, newClient.Config().APIKey)
 	}
+
+	if client.Metadata().APIKey != "original-api-key" || newClient.Metadata().APIKey != newAPIKey {
+		t.Errorf("metadata API keys = %q, %q; the original client must keep its key", client.Metadata().APIKey, newClient.Metadata().APIKey)
+	}
+}
+
+func TestClientCloneWithAPIKey(t *testing.T) {
+	authorization := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization <- r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"id":"file-1"}`))
+	}))
+	defer server.Close()
+
+	config := NewConfigWithAPIKey("original-api-key").WithHost(strings.TrimPrefix(server.URL, "http://")).WithInsecure(true)
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	clone, err := client.CloneWithAPIKey("new-api-key")
+	if err != nil {
+		t.Fatalf("CloneWithAPIKey() error = %v", err)
+	}
+	defer clone.Close()
+
+	if _, err := clone.Files().Get(context.Background(), "file-1"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got := <-authorization; got != "Bearer new-api-key" {
+		t.Errorf("Authorization = %q, want the new key", got)
+	}
+	if client.Config().APIKey != "original-api-key" {
+		t.Errorf("original API key = %q", client.Config().APIKey)
+	}
+}
+
+func TestClientFilesAfterClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"file-1","filename":"notes.txt"}`))
+	}))
+	defer server.Close()
+
+	config := NewConfigWithAPIKey("test-api-key").WithHost(strings.TrimPrefix(server.URL, "http://")).WithInsecure(true)
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Without a gRPC connection the upload falls back to REST.
+	file, err := client.Files().Upload(context.Background(), strings.NewReader("hello"), files.UploadOptions{Name: "notes.txt"})
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if file.ID != "file-1" {
+		t.Errorf("file = %+v", file)
+	}
 }
 
 func TestClientString(t *testing.T) {
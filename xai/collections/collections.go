@@ -5,11 +5,14 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"iter"
+	"slices"
 	"time"
 
 	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/documents"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/files"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/pager"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/rest"
 	"google.golang.org/protobuf/encoding/protojson"
 )
@@ -49,14 +52,18 @@ func NewClient(restClient *rest.Client) *Client {
 	}
 }
 
-// Collection represents a document collection.
+// Collection represents a document collection. The metric space set at
+// creation is not reported back by the API.
 type Collection struct {
-	ID             string
-	Name           string
-	CreatedAt      time.Time
-	DocumentsCount int32
-	Description    string
-	TotalFileSize  int64
+	ID                 string
+	Name               string
+	CreatedAt          time.Time
+	DocumentsCount     int32
+	Description        string
+	TotalFileSize      int64
+	IndexConfiguration *IndexConfiguration
+	ChunkConfiguration *ChunkConfiguration
+	FieldDefinitions   []FieldDefinition
 }
 
 // Document represents a document in a collection.
@@ -75,12 +82,47 @@ type Document struct {
 
 // IndexConfiguration contains index settings.
 type IndexConfiguration struct {
-	// Configuration fields from types.proto
+	// ModelName is the embedding model, one of ListAvailableEmbeddingModels.
+	ModelName string
 }
 
-// ChunkConfiguration contains chunking settings.
+// ChunkConfiguration contains chunking settings. Documents are split by
+// characters or by tokens; at most one of Chars and Tokens may be set.
 type ChunkConfiguration struct {
-	// Configuration fields from types.proto
+	Chars  *CharsConfiguration
+	Tokens *TokensConfiguration
+	// StripWhitespace strips leading and trailing whitespace from chunks.
+	StripWhitespace bool
+	// InjectNameIntoChunks prefixes each chunk with the document name.
+	InjectNameIntoChunks bool
+}
+
+// CharsConfiguration splits documents into chunks of characters.
+type CharsConfiguration struct {
+	MaxChunkSizeChars int32
+	ChunkOverlapChars int32
+}
+
+// TokensConfiguration splits documents into chunks of tokens.
+type TokensConfiguration struct {
+	MaxChunkSizeTokens int32
+	ChunkOverlapTokens int32
+	// EncodingName is the tokenizer, one of ListAvailableTokenEncodings.
+	EncodingName string
+}
+
+// FieldDefinition declares a metadata field of the documents of a
+// collection.
+type FieldDefinition struct {
+	Key string
+	// Required rejects documents without the field.
+	Required bool
+	// Unique rejects documents whose value is already used in the
+	// collection.
+	Unique bool
+	// InjectIntoChunk adds the field to the content of each chunk.
+	InjectIntoChunk bool
+	Description     string
 }
 
 // CreateCollectionOptions contains options for creating a collection.
@@ -89,7 +131,25 @@ type CreateCollectionOptions struct {
 	TeamID             string
 	IndexConfiguration *IndexConfiguration
 	ChunkConfiguration *ChunkConfiguration
+	// MetricSpace is the distance metric of the index; the API default is
+	// used if unset.
+	MetricSpace      xaiv1.HNSWMetric
+	FieldDefinitions []FieldDefinition
+	Description      string
+}
+
+// UpdateCollectionOptions contains options for updating a collection. Empty
+// fields are left unchanged.
+type UpdateCollectionOptions struct {
+	CollectionID       string
+	TeamID             string
+	Name               string
 	Description        string
+	ChunkConfiguration *ChunkConfiguration
+	// AddFields adds field definitions.
+	AddFields []FieldDefinition
+	// DeleteFields deletes the field definitions with these keys.
+	DeleteFields []string
 }
 
 // ListCollectionsOptions contains options for listing collections.
@@ -153,11 +213,21 @@ func (c *Client) CreateCollection(ctx context.Context, opts CreateCollectionOpti
 		return nil, ErrClientNotInitialized
 	}
 
+	chunkConfig, err := opts.ChunkConfiguration.proto()
+	if err != nil {
+		return nil, err
+	}
 	req := &xaiv1.CreateCollectionRequest{
 		TeamId:                stringPtr(opts.TeamID),
 		CollectionName:        opts.Name,
+		IndexConfiguration:    opts.IndexConfiguration.proto(),
+		ChunkConfiguration:    chunkConfig,
+		FieldDefinitions:      fieldDefinitionsProto(opts.FieldDefinitions),
 		CollectionDescription: stringPtr(opts.Description),
 	}
+	if opts.MetricSpace != xaiv1.HNSWMetric_HNSW_METRIC_UNKNOWN {
+		req.MetricSpace = &opts.MetricSpace
+	}
 
 	jsonData, err := protojson.Marshal(req)
 	if err != nil {
@@ -251,25 +321,71 @@ func (c *Client) List(ctx context.Context, opts *ListCollectionsOptions) ([]*Col
 	return c.ListCollections(ctx, opts)
 }
 
-// UpdateCollection updates a collection's configuration.
+// AllCollections returns an iterator over every collection matching opts,
+// transparently following pagination tokens. Iteration stops at the first error
+// or when the loop breaks.
+func (c *Client) AllCollections(ctx context.Context, opts *ListCollectionsOptions) iter.Seq2[*Collection, error] {
+	var pageOpts ListCollectionsOptions
+	if opts != nil {
+		pageOpts = *opts
+	}
+	return pager.All(ctx, pageOpts.PaginationToken, func(ctx context.Context, token string) ([]*Collection, string, error) {
+		pageOpts.PaginationToken = token
+		return c.ListCollections(ctx, &pageOpts)
+	})
+}
+
+// UpdateCollection updates a collection's configuration. The field
+// definitions of opts are added; the index configuration and metric space
+// cannot be changed after creation and are ignored.
 func (c *Client) UpdateCollection(ctx context.Context, collectionID, teamID string, opts CreateCollectionOptions) (*Collection, error) {
+	return c.UpdateCollectionWithOptions(ctx, UpdateCollectionOptions{
+		CollectionID:       collectionID,
+		TeamID:             teamID,
+		Name:               opts.Name,
+		Description:        opts.Description,
+		ChunkConfiguration: opts.ChunkConfiguration,
+		AddFields:          opts.FieldDefinitions,
+	})
+}
+
+// UpdateCollectionWithOptions updates a collection's name, description,
+// chunk configuration and field definitions.
+func (c *Client) UpdateCollectionWithOptions(ctx context.Context, opts UpdateCollectionOptions) (*Collection, error) {
 	if c.restClient == nil {
 		return nil, ErrClientNotInitialized
 	}
 
+	chunkConfig, err := opts.ChunkConfiguration.proto()
+	if err != nil {
+		return nil, err
+	}
 	req := &xaiv1.UpdateCollectionRequest{
-		CollectionId:          collectionID,
-		TeamId:                stringPtr(teamID),
+		CollectionId:          opts.CollectionID,
+		TeamId:                stringPtr(opts.TeamID),
 		CollectionName:        stringPtr(opts.Name),
+		ChunkConfiguration:    chunkConfig,
 		CollectionDescription: stringPtr(opts.Description),
 	}
+	for _, field := range fieldDefinitionsProto(opts.AddFields) {
+		req.FieldDefinitionUpdates = append(req.FieldDefinitionUpdates, &xaiv1.FieldDefinitionUpdate{
+			FieldDefinition: field,
+			Operation:       xaiv1.FieldDefinitionOperation_FIELD_DEFINITION_ADD,
+		})
+	}
+	for _, key := range opts.DeleteFields {
+		req.FieldDefinitionUpdates = append(req.FieldDefinitionUpdates, &xaiv1.FieldDefinitionUpdate{
+			FieldDefinition: &xaiv1.FieldDefinition{Key: key},
+			Operation:       xaiv1.FieldDefinitionOperation_FIELD_DEFINITION_DELETE,
+		})
+	}
 
 	jsonData, err := protojson.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.restClient.Put(ctx, fmt.Sprintf("/collections/%s", collectionID), jsonData)
+	resp, err := c.restClient.Put(ctx, fmt.Sprintf("/collections/%s", opts.CollectionID), jsonData)
 	if err != nil {
 		return nil, err
 	}
@@ -300,6 +416,92 @@ func (c *Client) Delete(ctx context.Context, collectionID, teamID string) error
 	return c.DeleteCollection(ctx, collectionID, teamID)
 }
 
+// ListAvailableEmbeddingModels lists the embedding models available for the
+// IndexConfiguration of a collection.
+func (c *Client) ListAvailableEmbeddingModels(ctx context.Context, teamID string) ([]string, error) {
+	if c.restClient == nil {
+		return nil, ErrClientNotInitialized
+	}
+
+	jsonData, err := protojson.Marshal(&xaiv1.ListAvailableEmbeddingModelsRequest{TeamId: stringPtr(teamID)})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.restClient.Post(ctx, "/collections/embedding-models", jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	var listResp xaiv1.ListAvailableEmbeddingModelsResponse
+	if err := protojson.Unmarshal(resp.Body, &listResp); err != nil {
+		return nil, err
+	}
+	return listResp.EmbeddingModels, nil
+}
+
+// ListAvailableTokenEncodings lists the token encodings available for the
+// TokensConfiguration of a collection.
+func (c *Client) ListAvailableTokenEncodings(ctx context.Context, teamID string) ([]string, error) {
+	if c.restClient == nil {
+		return nil, ErrClientNotInitialized
+	}
+
+	jsonData, err := protojson.Marshal(&xaiv1.ListAvailableTokenEncodingsRequest{TeamId: stringPtr(teamID)})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.restClient.Post(ctx, "/collections/token-encodings", jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	var listResp xaiv1.ListAvailableTokenEncodingsResponse
+	if err := protojson.Unmarshal(resp.Body, &listResp); err != nil {
+		return nil, err
+	}
+	return listResp.Encodings, nil
+}
+
+// Validate checks the configuration of a collection before creating it: the
+// chunk sizes and field definitions, and that the embedding model and token
+// encoding are available to the team. It returns an error wrapping
+// ErrInvalidConfiguration for an invalid configuration.
+func (c *Client) Validate(ctx context.Context, opts CreateCollectionOptions) error {
+	if _, err := opts.ChunkConfiguration.proto(); err != nil {
+		return err
+	}
+
+	keys := make(map[string]bool, len(opts.FieldDefinitions))
+	for _, f := range opts.FieldDefinitions {
+		if f.Key == "" || keys[f.Key] {
+			return fmt.Errorf("%w: field definition key %q is empty or duplicated", ErrInvalidConfiguration, f.Key)
+		}
+		keys[f.Key] = true
+	}
+
+	if opts.IndexConfiguration != nil && opts.IndexConfiguration.ModelName != "" {
+		models, err := c.ListAvailableEmbeddingModels(ctx, opts.TeamID)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(models, opts.IndexConfiguration.ModelName) {
+			return fmt.Errorf("%w: embedding model %q is not available", ErrInvalidConfiguration, opts.IndexConfiguration.ModelName)
+		}
+	}
+	if opts.ChunkConfiguration != nil && opts.ChunkConfiguration.Tokens != nil && opts.ChunkConfiguration.Tokens.EncodingName != "" {
+		encodings, err := c.ListAvailableTokenEncodings(ctx, opts.TeamID)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(encodings, opts.ChunkConfiguration.Tokens.EncodingName) {
+			return fmt.Errorf("%w: token encoding %q is not available", ErrInvalidConfiguration, opts.ChunkConfiguration.Tokens.EncodingName)
+		}
+	}
+	return nil
+}
+
 func (c *Client) Search(ctx context.Context, query string, collectionIDs []string, opts *SearchOptions) (*documents.SearchResponse, error) {
 	if c.restClient == nil {
 		return nil, ErrClientNotInitialized
@@ -509,6 +711,38 @@ func (c *Client) ListDocuments(ctx context.Context, opts *ListDocumentsOptions)
 	return docs, *listResp.PaginationToken, nil
 }
 
+// AllDocuments returns an iterator over every document in opts.CollectionID,
+// transparently following pagination tokens. Iteration stops at the first error
+// or when the loop breaks.
+func (c *Client) AllDocuments(ctx context.Context, opts *ListDocumentsOptions) iter.Seq2[*Document, error] {
+	var pageOpts ListDocumentsOptions
+	if opts != nil {
+		pageOpts = *opts
+	}
+	return pager.All(ctx, pageOpts.PaginationToken, func(ctx context.Context, token string) ([]*Document, string, error) {
+		pageOpts.PaginationToken = token
+		return c.ListDocuments(ctx, &pageOpts)
+	})
+}
+
+// ReferencedFiles returns the IDs of the files that are documents of any
+// collection, such as for files.JanitorOptions.Referenced.
+func (c *Client) ReferencedFiles(ctx context.Context) (map[string]bool, error) {
+	referenced := map[string]bool{}
+	for collection, err := range c.AllCollections(ctx, nil) {
+		if err != nil {
+			return nil, err
+		}
+		for doc, err := range c.AllDocuments(ctx, &ListDocumentsOptions{CollectionID: collection.ID}) {
+			if err != nil {
+				return nil, err
+			}
+			referenced[doc.FileID] = true
+		}
+	}
+	return referenced, nil
+}
+
 // UpdateDocument updates a document's fields.
 func (c *Client) UpdateDocument(ctx context.Context, collectionID, fileID, teamID string, fields map[string]string) (*Document, error) {
 	return c.UpdateDocumentWithOptions(ctx, UpdateDocumentOptions{
@@ -3,6 +3,8 @@ package collections
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -10,6 +12,7 @@ import (
 
 	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/rest"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -145,6 +148,144 @@ func TestUpdateCollection(t *testing.T) {
 	}
 }
 
+func TestCreateCollectionConfiguration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req xaiv1.CreateCollectionRequest
+		if err := protojson.Unmarshal(body, &req); err != nil {
+			t.Fatalf("invalid request: %v", err)
+		}
+		if req.GetMetricSpace() != xaiv1.HNSWMetric_HNSW_METRIC_COSINE || req.IndexConfiguration.GetModelName() != "embed-1" {
+			t.Errorf("request = %v", &req)
+		}
+		resp, _ := protojson.Marshal(&xaiv1.CollectionMetadata{
+			CollectionId:       "col-123",
+			CollectionName:     req.CollectionName,
+			IndexConfiguration: req.IndexConfiguration,
+			ChunkConfiguration: req.ChunkConfiguration,
+			FieldDefinitions:   req.FieldDefinitions,
+		})
+		w.Write(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(rest.NewClient(rest.Config{BaseURL: server.URL, APIKey: "test"}))
+	opts := CreateCollectionOptions{
+		Name:               "Docs",
+		IndexConfiguration: &IndexConfiguration{ModelName: "embed-1"},
+		ChunkConfiguration: &ChunkConfiguration{
+			Tokens:          &TokensConfiguration{MaxChunkSizeTokens: 512, ChunkOverlapTokens: 64, EncodingName: "cl100k_base"},
+			StripWhitespace: true,
+		},
+		MetricSpace:      xaiv1.HNSWMetric_HNSW_METRIC_COSINE,
+		FieldDefinitions: []FieldDefinition{{Key: "author", Required: true, InjectIntoChunk: true, Description: "Author"}},
+	}
+	col, err := client.CreateCollection(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("CreateCollection() error = %v", err)
+	}
+	if col.IndexConfiguration == nil || *col.IndexConfiguration != *opts.IndexConfiguration {
+		t.Errorf("IndexConfiguration = %+v", col.IndexConfiguration)
+	}
+	if chunk := col.ChunkConfiguration; chunk == nil || chunk.Chars != nil || chunk.Tokens == nil ||
+		*chunk.Tokens != *opts.ChunkConfiguration.Tokens || !chunk.StripWhitespace {
+		t.Errorf("ChunkConfiguration = %+v", col.ChunkConfiguration)
+	}
+	if len(col.FieldDefinitions) != 1 || col.FieldDefinitions[0] != opts.FieldDefinitions[0] {
+		t.Errorf("FieldDefinitions = %+v", col.FieldDefinitions)
+	}
+}
+
+func TestCreateCollectionInvalidChunks(t *testing.T) {
+	client := NewClient(rest.NewClient(rest.Config{BaseURL: "http://127.0.0.1:0"}))
+	for _, chunk := range []*ChunkConfiguration{
+		{Chars: &CharsConfiguration{MaxChunkSizeChars: 100}, Tokens: &TokensConfiguration{MaxChunkSizeTokens: 100}},
+		{Chars: &CharsConfiguration{MaxChunkSizeChars: 100, ChunkOverlapChars: 100}},
+		{Tokens: &TokensConfiguration{}},
+	} {
+		_, err := client.CreateCollection(context.Background(), CreateCollectionOptions{Name: "Docs", ChunkConfiguration: chunk})
+		if !errors.Is(err, ErrInvalidConfiguration) {
+			t.Errorf("CreateCollection(%+v) error = %v, want ErrInvalidConfiguration", chunk, err)
+		}
+	}
+}
+
+func TestUpdateCollectionWithOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req xaiv1.UpdateCollectionRequest
+		if err := protojson.Unmarshal(body, &req); err != nil {
+			t.Fatalf("invalid request: %v", err)
+		}
+		updates := req.FieldDefinitionUpdates
+		if len(updates) != 2 ||
+			updates[0].Operation != xaiv1.FieldDefinitionOperation_FIELD_DEFINITION_ADD || updates[0].FieldDefinition.Key != "year" ||
+			updates[1].Operation != xaiv1.FieldDefinitionOperation_FIELD_DEFINITION_DELETE || updates[1].FieldDefinition.Key != "draft" {
+			t.Errorf("field updates = %v", updates)
+		}
+		if req.ChunkConfiguration.GetCharsConfiguration().GetMaxChunkSizeChars() != 2000 {
+			t.Errorf("chunk configuration = %v", req.ChunkConfiguration)
+		}
+		resp, _ := protojson.Marshal(&xaiv1.CollectionMetadata{CollectionId: req.CollectionId})
+		w.Write(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(rest.NewClient(rest.Config{BaseURL: server.URL, APIKey: "test"}))
+	_, err := client.UpdateCollectionWithOptions(context.Background(), UpdateCollectionOptions{
+		CollectionID:       "col-123",
+		ChunkConfiguration: &ChunkConfiguration{Chars: &CharsConfiguration{MaxChunkSizeChars: 2000, ChunkOverlapChars: 200}},
+		AddFields:          []FieldDefinition{{Key: "year", Unique: true}},
+		DeleteFields:       []string{"draft"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateCollectionWithOptions() error = %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/collections/embedding-models":
+			json.NewEncoder(w).Encode(&xaiv1.ListAvailableEmbeddingModelsResponse{EmbeddingModels: []string{"embed-1"}})
+		case "/collections/token-encodings":
+			json.NewEncoder(w).Encode(&xaiv1.ListAvailableTokenEncodingsResponse{Encodings: []string{"cl100k_base"}})
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(rest.NewClient(rest.Config{BaseURL: server.URL, APIKey: "test"}))
+	models, err := client.ListAvailableEmbeddingModels(context.Background(), "team-1")
+	if err != nil || len(models) != 1 || models[0] != "embed-1" {
+		t.Errorf("ListAvailableEmbeddingModels() = %v, %v", models, err)
+	}
+
+	valid := CreateCollectionOptions{
+		IndexConfiguration: &IndexConfiguration{ModelName: "embed-1"},
+		ChunkConfiguration: &ChunkConfiguration{Tokens: &TokensConfiguration{MaxChunkSizeTokens: 512, EncodingName: "cl100k_base"}},
+		FieldDefinitions:   []FieldDefinition{{Key: "author"}},
+	}
+	if err := client.Validate(context.Background(), valid); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	for name, modify := range map[string]func(*CreateCollectionOptions){
+		"model":    func(o *CreateCollectionOptions) { o.IndexConfiguration = &IndexConfiguration{ModelName: "embed-2"} },
+		"encoding": func(o *CreateCollectionOptions) { o.ChunkConfiguration.Tokens.EncodingName = "o200k_base" },
+		"fields":   func(o *CreateCollectionOptions) { o.FieldDefinitions = []FieldDefinition{{Key: "a"}, {Key: "a"}} },
+	} {
+		opts := valid
+		tokens := *valid.ChunkConfiguration.Tokens
+		opts.ChunkConfiguration = &ChunkConfiguration{Tokens: &tokens}
+		modify(&opts)
+		if err := client.Validate(context.Background(), opts); !errors.Is(err, ErrInvalidConfiguration) {
+			t.Errorf("%s: Validate() error = %v, want ErrInvalidConfiguration", name, err)
+		}
+	}
+}
+
 func TestDeleteCollection(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodDelete {
@@ -249,6 +390,72 @@ func TestListDocuments(t *testing.T) {
 	}
 }
 
+func TestAllDocumentsFollowsPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/collections/col-1/documents/list" {
+			t.Fatalf("path = %q", r.URL.Path)
+		}
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := &xaiv1.ListDocumentsResponse{}
+		if req["paginationToken"] == "page-2" {
+			resp.Documents = []*xaiv1.DocumentMetadata{{FileMetadata: &xaiv1.FileMetadata{FileId: "file-2"}}}
+		} else {
+			token := "page-2"
+			resp.Documents = []*xaiv1.DocumentMetadata{{FileMetadata: &xaiv1.FileMetadata{FileId: "file-1"}}}
+			resp.PaginationToken = &token
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(rest.NewClient(rest.Config{BaseURL: server.URL, APIKey: "test"}))
+	var ids []string
+	for doc, err := range client.AllDocuments(context.Background(), &ListDocumentsOptions{CollectionID: "col-1"}) {
+		if err != nil {
+			t.Fatalf("AllDocuments() error = %v", err)
+		}
+		ids = append(ids, doc.FileID)
+	}
+	if len(ids) != 2 || ids[0] != "file-1" || ids[1] != "file-2" {
+		t.Errorf("ids = %v", ids)
+	}
+}
+
+func TestReferencedFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/collections/list":
+			json.NewEncoder(w).Encode(&xaiv1.ListCollectionsResponse{Collections: []*xaiv1.CollectionMetadata{
+				{CollectionId: "col-1"},
+				{CollectionId: "col-2"},
+			}})
+		case "/collections/col-1/documents/list":
+			json.NewEncoder(w).Encode(&xaiv1.ListDocumentsResponse{Documents: []*xaiv1.DocumentMetadata{
+				{FileMetadata: &xaiv1.FileMetadata{FileId: "file-1"}},
+				{FileMetadata: &xaiv1.FileMetadata{FileId: "file-2"}},
+			}})
+		case "/collections/col-2/documents/list":
+			json.NewEncoder(w).Encode(&xaiv1.ListDocumentsResponse{Documents: []*xaiv1.DocumentMetadata{
+				{FileMetadata: &xaiv1.FileMetadata{FileId: "file-2"}},
+				{FileMetadata: &xaiv1.FileMetadata{FileId: "file-3"}},
+			}})
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(rest.NewClient(rest.Config{BaseURL: server.URL, APIKey: "test"}))
+	referenced, err := client.ReferencedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("ReferencedFiles() error = %v", err)
+	}
+	if len(referenced) != 3 || !referenced["file-1"] || !referenced["file-2"] || !referenced["file-3"] {
+		t.Errorf("referenced = %v", referenced)
+	}
+}
+
 func TestUpdateDocument(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := &xaiv1.DocumentMetadata{
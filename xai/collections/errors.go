@@ -7,3 +7,7 @@ var ErrNotImplemented = errors.New("collections API method not yet implemented")
 
 // ErrClientNotInitialized is returned when the REST client is not initialized.
 var ErrClientNotInitialized = errors.New("collections client not initialized: REST client is nil")
+
+// ErrInvalidConfiguration is returned when a collection configuration is
+// rejected before being sent.
+var ErrInvalidConfiguration = errors.New("invalid collection configuration")
@@ -1,6 +1,8 @@
 package collections
 
 import (
+	"fmt"
+
 	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
 )
 
@@ -22,9 +24,115 @@ func fromProtoCollection(pc *xaiv1.CollectionMetadata) *Collection {
 		c.CreatedAt = pc.CreatedAt.AsTime()
 	}
 
+	if pc.IndexConfiguration != nil {
+		c.IndexConfiguration = &IndexConfiguration{ModelName: pc.IndexConfiguration.ModelName}
+	}
+	c.ChunkConfiguration = fromProtoChunkConfiguration(pc.ChunkConfiguration)
+	for _, f := range pc.FieldDefinitions {
+		c.FieldDefinitions = append(c.FieldDefinitions, FieldDefinition{
+			Key:             f.Key,
+			Required:        f.Required,
+			Unique:          f.Unique,
+			InjectIntoChunk: f.InjectIntoChunk,
+			Description:     f.GetDescription(),
+		})
+	}
+
 	return c
 }
 
+// fromProtoChunkConfiguration converts a proto ChunkConfiguration to a
+// ChunkConfiguration.
+func fromProtoChunkConfiguration(pc *xaiv1.ChunkConfiguration) *ChunkConfiguration {
+	if pc == nil {
+		return nil
+	}
+
+	c := &ChunkConfiguration{
+		StripWhitespace:      pc.StripWhitespace,
+		InjectNameIntoChunks: pc.InjectNameIntoChunks,
+	}
+	if chars := pc.GetCharsConfiguration(); chars != nil {
+		c.Chars = &CharsConfiguration{
+			MaxChunkSizeChars: chars.MaxChunkSizeChars,
+			ChunkOverlapChars: chars.ChunkOverlapChars,
+		}
+	}
+	if tokens := pc.GetTokensConfiguration(); tokens != nil {
+		c.Tokens = &TokensConfiguration{
+			MaxChunkSizeTokens: tokens.MaxChunkSizeTokens,
+			ChunkOverlapTokens: tokens.ChunkOverlapTokens,
+			EncodingName:       tokens.EncodingName,
+		}
+	}
+	return c
+}
+
+// proto converts the index configuration to its proto form.
+func (c *IndexConfiguration) proto() *xaiv1.IndexConfiguration {
+	if c == nil {
+		return nil
+	}
+	return &xaiv1.IndexConfiguration{ModelName: c.ModelName}
+}
+
+// proto validates the chunk configuration and converts it to its proto form.
+func (c *ChunkConfiguration) proto() (*xaiv1.ChunkConfiguration, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	pc := &xaiv1.ChunkConfiguration{
+		StripWhitespace:      c.StripWhitespace,
+		InjectNameIntoChunks: c.InjectNameIntoChunks,
+	}
+	switch {
+	case c.Chars != nil && c.Tokens != nil:
+		return nil, fmt.Errorf("%w: chunks cannot be measured in both chars and tokens", ErrInvalidConfiguration)
+	case c.Chars != nil:
+		if err := checkChunkSize(c.Chars.MaxChunkSizeChars, c.Chars.ChunkOverlapChars); err != nil {
+			return nil, err
+		}
+		pc.Config = &xaiv1.ChunkConfiguration_CharsConfiguration{CharsConfiguration: &xaiv1.CharsConfiguration{
+			MaxChunkSizeChars: c.Chars.MaxChunkSizeChars,
+			ChunkOverlapChars: c.Chars.ChunkOverlapChars,
+		}}
+	case c.Tokens != nil:
+		if err := checkChunkSize(c.Tokens.MaxChunkSizeTokens, c.Tokens.ChunkOverlapTokens); err != nil {
+			return nil, err
+		}
+		pc.Config = &xaiv1.ChunkConfiguration_TokensConfiguration{TokensConfiguration: &xaiv1.TokensConfiguration{
+			MaxChunkSizeTokens: c.Tokens.MaxChunkSizeTokens,
+			ChunkOverlapTokens: c.Tokens.ChunkOverlapTokens,
+			EncodingName:       c.Tokens.EncodingName,
+		}}
+	}
+	return pc, nil
+}
+
+// checkChunkSize checks that chunks have a size larger than their overlap.
+func checkChunkSize(size, overlap int32) error {
+	if size <= 0 || overlap < 0 || overlap >= size {
+		return fmt.Errorf("%w: chunk size %d with overlap %d", ErrInvalidConfiguration, size, overlap)
+	}
+	return nil
+}
+
+// fieldDefinitionsProto converts field definitions to their proto form.
+func fieldDefinitionsProto(fields []FieldDefinition) []*xaiv1.FieldDefinition {
+	var pfs []*xaiv1.FieldDefinition
+	for _, f := range fields {
+		pfs = append(pfs, &xaiv1.FieldDefinition{
+			Key:             f.Key,
+			Required:        f.Required,
+			Unique:          f.Unique,
+			InjectIntoChunk: f.InjectIntoChunk,
+			Description:     stringPtr(f.Description),
+		})
+	}
+	return pfs
+}
+
 // fromProtoDocument converts a proto DocumentMetadata to a Document.
 func fromProtoDocument(pd *xaiv1.DocumentMetadata) *Document {
 	if pd == nil {
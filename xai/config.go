@@ -4,17 +4,26 @@ package xai
 import (
 	"crypto/tls"
 	"fmt"
+	"log/slog"
 	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/ZaguanLabs/xai-sdk-go/xai/cassette"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/auth"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/constants"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/grpcutil"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/logging"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/metadata"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/transport"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/ratelimit"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/retry"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/telemetry"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
@@ -65,10 +74,13 @@ type Config struct {
 	// environments with self-signed certificates. Never use in production.
 	SkipVerify bool `json:"skip_verify"`
 
-	// MaxRetries is the maximum number of retries (default: 3).
+	// MaxRetries is the maximum number of retries (default: 3). Retries apply to
+	// every gRPC and REST call; see the retry package for what is retried and
+	// for per-call overrides.
 	MaxRetries int `json:"max_retries"`
 
-	// RetryBackoff is the retry backoff duration (default: 1s).
+	// RetryBackoff is the backoff before the first retry (default: 1s). Later
+	// retries back off exponentially with jitter.
 	RetryBackoff time.Duration `json:"retry_backoff"`
 
 	// MaxBackoff is the maximum backoff duration (default: 60s).
@@ -80,14 +92,69 @@ type Config struct {
 	// UserAgent is the user agent string (default: xai-sdk-go/version).
 	UserAgent string `json:"user_agent"`
 
-	// EnableTelemetry controls whether to enable telemetry (default: true).
+	// EnableTelemetry controls whether calls are reported to Observer
+	// (default: true).
 	EnableTelemetry bool `json:"enable_telemetry"`
 
 	// CustomTLSConfig allows providing a custom TLS configuration.
 	CustomTLSConfig *tls.Config `json:"-"`
 
-	// Logger is an optional logger (if nil, no logging is performed).
-	// Logger logger.Logger `json:"-"`
+	// ProxyURL is the proxy of the gRPC and REST connections: an http://,
+	// https://, socks5:// or socks5h:// URL. If empty, HTTPS_PROXY is used.
+	// Hosts listed in NO_PROXY are always reached directly.
+	ProxyURL string `json:"proxy_url"`
+
+	// CABundlePaths are PEM files of certificate authorities trusted in
+	// addition to the system roots, e.g. a corporate CA.
+	CABundlePaths []string `json:"ca_bundle_paths"`
+
+	// ClientCertPath and ClientKeyPath are the PEM certificate and key
+	// presented for mutual TLS.
+	ClientCertPath string `json:"client_cert_path"`
+	ClientKeyPath  string `json:"client_key_path"`
+
+	// HTTPClient sends the REST calls instead of a client built from the
+	// TLS and proxy settings (default: none).
+	HTTPClient *http.Client `json:"-"`
+
+	// DialOptions are added to the gRPC dial options (default: none).
+	DialOptions []grpc.DialOption `json:"-"`
+
+	// UnaryInterceptors and StreamInterceptors run after the SDK's own
+	// interceptors, once per attempt of every gRPC call (default: none).
+	UnaryInterceptors  []grpc.UnaryClientInterceptor  `json:"-"`
+	StreamInterceptors []grpc.StreamClientInterceptor `json:"-"`
+
+	// Credentials provides the API key of each call, overriding APIKey
+	// (default: none). See the credentials package for rotating keys and key pools.
+	Credentials CredentialProvider `json:"-"`
+
+	// Observer is notified of every gRPC and REST call for tracing and
+	// metrics (default: none). See the telemetry package and the xaiotel
+	// module for OpenTelemetry.
+	Observer telemetry.Observer `json:"-"`
+
+	// RateLimiter paces gRPC and REST calls by requests and tokens per minute
+	// (default: none). A limiter can be shared by several clients.
+	RateLimiter *ratelimit.Limiter `json:"-"`
+
+	// Cassette records the gRPC and REST calls to a file or replays them
+	// from it, for offline tests (default: none). See the cassette package.
+	Cassette *cassette.Recorder `json:"-"`
+
+	// Logger receives the client's log records: connection lifecycle at
+	// debug level, every call with its method, latency, status and request
+	// ID, failed attempts and stream terminations (default: none, nothing is
+	// logged). API keys, authorization headers and proxy credentials are
+	// redacted.
+	Logger *slog.Logger `json:"-"`
+
+	// LogBodies adds the request and response bodies, which contain prompts
+	// and completions, to the records of Logger (default: false).
+	LogBodies bool `json:"log_bodies"`
+
+	// sources records where loaded values came from; see Explain.
+	sources map[string]valueSource
 }
 
 // DefaultConfig returns a Config with default values.
@@ -128,11 +195,13 @@ func NewConfigWithAPIKey(apiKey string) *Config {
 
 // LoadFromEnvironment loads configuration from environment variables.
 func (c *Config) LoadFromEnvironment() {
+	before := c.snapshot()
 	c.loadHostConfig()
 	c.loadTimeoutConfig()
 	c.loadSecurityConfig()
 	c.loadRetryConfig()
 	c.loadOtherConfig()
+	c.track(before, SourceEnvironment, func(s setting) string { return s.env })
 }
 
 func (c *Config) loadHostConfig() {
@@ -207,6 +276,23 @@ func (c *Config) loadSecurityConfig() {
 			c.SkipVerify = skipVerify
 		}
 	}
+
+	if proxyURL := os.Getenv("XAI_PROXY_URL"); proxyURL != "" {
+		c.ProxyURL = proxyURL
+	}
+
+	// Several CA bundles are separated like PATH entries
+	if caBundle := os.Getenv("XAI_CA_BUNDLE"); caBundle != "" {
+		c.CABundlePaths = filepath.SplitList(caBundle)
+	}
+
+	if clientCert := os.Getenv("XAI_CLIENT_CERT"); clientCert != "" {
+		c.ClientCertPath = clientCert
+	}
+
+	if clientKey := os.Getenv("XAI_CLIENT_KEY"); clientKey != "" {
+		c.ClientKeyPath = clientKey
+	}
 }
 
 func (c *Config) loadRetryConfig() {
@@ -245,12 +331,18 @@ func (c *Config) loadOtherConfig() {
 			c.EnableTelemetry = enableTelemetry
 		}
 	}
+
+	if logBodiesStr := os.Getenv("XAI_LOG_BODIES"); logBodiesStr != "" {
+		if logBodies, err := strconv.ParseBool(logBodiesStr); err == nil {
+			c.LogBodies = logBodies
+		}
+	}
 }
 
 // Validate validates the configuration.
 func (c *Config) Validate() error {
-	if c.APIKey == "" {
-		return errors.NewConfigError("API key is required. Set XAI_API_KEY environment variable or call WithAPIKey().")
+	if c.APIKey == "" && c.Credentials == nil {
+		return errors.NewConfigError("API key is required. Set XAI_API_KEY environment variable or call WithAPIKey() or WithCredentials().")
 	}
 
 	if err := c.validateHost(); err != nil {
@@ -265,6 +357,10 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if err := c.validateTransport(); err != nil {
+		return err
+	}
+
 	// Validate environment
 	if c.Environment == "" {
 		c.Environment = "production"
@@ -342,6 +438,20 @@ func (c *Config) validateRetries() error {
 	return nil
 }
 
+func (c *Config) validateTransport() error {
+	// Validate proxy and TLS settings
+	if c.ProxyURL != "" {
+		if _, err := transport.ParseProxyURL(c.ProxyURL); err != nil {
+			return errors.NewConfigError(err.Error())
+		}
+	}
+
+	if (c.ClientCertPath == "") != (c.ClientKeyPath == "") {
+		return errors.NewConfigError("client_cert_path and client_key_path must be set together")
+	}
+	return nil
+}
+
 // GRPCAddress returns the gRPC address for this configuration.
 func (c *Config) GRPCAddress() string {
 	return net.JoinHostPort(c.Host, c.GRPCPort)
@@ -361,6 +471,69 @@ func (c *Config) ToSDKMetadata() *metadata.SDKMetadata {
 	}
 }
 
+// RetryPolicy returns the retry policy built from MaxRetries, RetryBackoff and MaxBackoff.
+func (c *Config) RetryPolicy() retry.Policy {
+	return retry.Policy{
+		MaxRetries:     c.MaxRetries,
+		InitialBackoff: c.RetryBackoff,
+		MaxBackoff:     c.MaxBackoff,
+	}
+}
+
+// observer returns the observer of the calls: Observer, unless telemetry is
+// disabled, followed by the logger, or nil if there is neither.
+func (c *Config) observer() telemetry.Observer {
+	var observers []telemetry.Observer
+	if c.EnableTelemetry {
+		observers = append(observers, c.Observer)
+	}
+	if c.Logger != nil {
+		// After Observer, so that records carry the span of the call.
+		observers = append(observers, logging.NewObserver(c.logger(), c.LogBodies))
+	}
+	return telemetry.Combine(observers...)
+}
+
+// logger returns Logger with the configured API keys redacted, or a logger
+// discarding all records.
+func (c *Config) logger() *slog.Logger {
+	return logging.New(c.Logger, c.APIKey, c.ManagementAPIKey)
+}
+
+// transportOptions returns the TLS and proxy settings of the connections.
+func (c *Config) transportOptions() transport.Options {
+	return transport.Options{
+		ProxyURL:   c.ProxyURL,
+		CAFiles:    c.CABundlePaths,
+		CertFile:   c.ClientCertPath,
+		KeyFile:    c.ClientKeyPath,
+		SkipVerify: c.SkipVerify,
+		Base:       c.CustomTLSConfig,
+	}
+}
+
+// grpcProxy returns the proxy of the gRPC connection, or nil if it is direct.
+func (c *Config) grpcProxy() (*url.URL, error) {
+	proxy, err := c.transportOptions().Proxy()
+	if err != nil {
+		return nil, err
+	}
+	scheme := "https"
+	if c.Insecure {
+		scheme = "http"
+	}
+	return transport.ProxyFor(proxy, scheme, c.GRPCAddress())
+}
+
+// grpcTarget returns the target of the gRPC connection. Behind a proxy, the
+// host name is resolved by the proxy rather than locally.
+func (c *Config) grpcTarget() string {
+	if proxyURL, err := c.grpcProxy(); err == nil && proxyURL != nil {
+		return "passthrough:///" + c.GRPCAddress()
+	}
+	return c.GRPCAddress()
+}
+
 // CreateGRPCDialOptions creates gRPC dial options based on the configuration.
 func (c *Config) CreateGRPCDialOptions() ([]grpc.DialOption, error) {
 	var opts []grpc.DialOption
@@ -369,18 +542,55 @@ func (c *Config) CreateGRPCDialOptions() ([]grpc.DialOption, error) {
 	var unaryInterceptors []grpc.UnaryClientInterceptor
 	var streamInterceptors []grpc.StreamClientInterceptor
 
-	// Add authentication interceptor
-	if c.APIKey != "" {
-		authInterceptor := auth.NewAPIKeyAuthInterceptor(c.APIKey, false)
-		unaryInterceptors = append(unaryInterceptors, authInterceptor.UnaryInterceptor())
-		streamInterceptors = append(streamInterceptors, authInterceptor.StreamInterceptor())
+	// Add telemetry interceptor first so that it sees each call as returned to the caller
+	observer := c.observer()
+	if observer != nil {
+		unaryInterceptors = append(unaryInterceptors, telemetry.UnaryInterceptor(observer))
+		streamInterceptors = append(streamInterceptors, telemetry.StreamInterceptor(observer))
 	}
 
+	// Add error interceptor so that it sees the final error of each call
+	unaryInterceptors = append(unaryInterceptors, grpcutil.ErrorUnaryInterceptor())
+	streamInterceptors = append(streamInterceptors, grpcutil.ErrorStreamInterceptor())
+
 	// Add timeout interceptor
 	timeoutInterceptor := grpcutil.NewTimeoutInterceptor(c.Timeout, c.StreamTimeout)
 	unaryInterceptors = append(unaryInterceptors, timeoutInterceptor.UnaryInterceptor())
 	streamInterceptors = append(streamInterceptors, timeoutInterceptor.StreamInterceptor())
 
+	// Add retry interceptor inside the timeout so that retries share the call deadline
+	unaryInterceptors = append(unaryInterceptors, grpcutil.RetryUnaryInterceptor(c.RetryPolicy()))
+	streamInterceptors = append(streamInterceptors, grpcutil.RetryStreamInterceptor(c.RetryPolicy()))
+
+	// Count the attempts of observed calls
+	if observer != nil {
+		unaryInterceptors = append(unaryInterceptors, telemetry.AttemptUnaryInterceptor())
+		streamInterceptors = append(streamInterceptors, telemetry.AttemptStreamInterceptor())
+	}
+
+	// Add authentication interceptor inside the retries so that every attempt asks for a key
+	if provider := c.CredentialsProvider(); provider != nil {
+		authInterceptor := auth.NewProviderAuthInterceptor(provider, false)
+		unaryInterceptors = append(unaryInterceptors, authInterceptor.UnaryInterceptor())
+		streamInterceptors = append(streamInterceptors, authInterceptor.StreamInterceptor())
+	}
+
+	// Add rate limit interceptor inside the retries so that every attempt is paced
+	if c.RateLimiter != nil {
+		unaryInterceptors = append(unaryInterceptors, c.RateLimiter.UnaryInterceptor())
+		streamInterceptors = append(streamInterceptors, c.RateLimiter.StreamInterceptor())
+	}
+
+	// Add caller-supplied interceptors last
+	unaryInterceptors = append(unaryInterceptors, c.UnaryInterceptors...)
+	streamInterceptors = append(streamInterceptors, c.StreamInterceptors...)
+
+	// Record or replay every attempt innermost, in place of the network
+	if c.Cassette != nil {
+		unaryInterceptors = append(unaryInterceptors, c.Cassette.UnaryInterceptor())
+		streamInterceptors = append(streamInterceptors, c.Cassette.StreamInterceptor())
+	}
+
 	// Note: Content-Type header is automatically handled by gRPC
 	// Adding it manually can cause "malformed header" errors
 
@@ -396,22 +606,38 @@ func (c *Config) CreateGRPCDialOptions() ([]grpc.DialOption, error) {
 	if c.Insecure {
 		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	} else {
-		tlsConfig := c.CustomTLSConfig
-		if tlsConfig == nil {
-			tlsConfig = &tls.Config{
-				MinVersion:         tls.VersionTLS12,
-				InsecureSkipVerify: c.SkipVerify, //nolint:gosec
-			}
+		tlsConfig, err := c.transportOptions().TLSConfig()
+		if err != nil {
+			return nil, errors.NewConfigError(err.Error())
 		}
 		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
 	}
 
+	// Configure the proxy; gRPC's own proxy detection is replaced by ours so
+	// that SOCKS proxies and XAI_PROXY_URL are supported
+	proxyURL, err := c.grpcProxy()
+	if err != nil {
+		return nil, errors.NewConfigError(err.Error())
+	}
+	if proxyURL != nil {
+		dialer, err := transport.Dialer(proxyURL, c.ConnectTimeout)
+		if err != nil {
+			return nil, errors.NewConfigError(err.Error())
+		}
+		opts = append(opts, grpc.WithContextDialer(dialer))
+	} else {
+		opts = append(opts, grpc.WithNoProxy())
+	}
+
 	// Configure keep-alive
 	opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
 		Time:    c.KeepAliveTimeout,
 		Timeout: c.KeepAliveTimeout,
 	}))
 
+	// Add caller-supplied dial options last so that they take precedence
+	opts = append(opts, c.DialOptions...)
+
 	return opts, nil
 }
 
@@ -462,6 +688,48 @@ func (c *Config) WithTLSConfig(tlsConfig *tls.Config) *Config {
 	return c
 }
 
+// WithProxy sets the proxy URL of the gRPC and REST connections.
+func (c *Config) WithProxy(proxyURL string) *Config {
+	c.ProxyURL = proxyURL
+	return c
+}
+
+// WithCABundle adds PEM files of trusted certificate authorities.
+func (c *Config) WithCABundle(paths ...string) *Config {
+	c.CABundlePaths = append(c.CABundlePaths, paths...)
+	return c
+}
+
+// WithClientCertificate sets the PEM certificate and key for mutual TLS.
+func (c *Config) WithClientCertificate(certPath, keyPath string) *Config {
+	c.ClientCertPath = certPath
+	c.ClientKeyPath = keyPath
+	return c
+}
+
+// WithHTTPClient sets the HTTP client sending the REST calls.
+func (c *Config) WithHTTPClient(client *http.Client) *Config {
+	c.HTTPClient = client
+	return c
+}
+
+// WithDialOptions adds gRPC dial options.
+func (c *Config) WithDialOptions(opts ...grpc.DialOption) *Config {
+	c.DialOptions = append(c.DialOptions, opts...)
+	return c
+}
+
+// WithInterceptors adds gRPC interceptors; either may be nil.
+func (c *Config) WithInterceptors(unary grpc.UnaryClientInterceptor, stream grpc.StreamClientInterceptor) *Config {
+	if unary != nil {
+		c.UnaryInterceptors = append(c.UnaryInterceptors, unary)
+	}
+	if stream != nil {
+		c.StreamInterceptors = append(c.StreamInterceptors, stream)
+	}
+	return c
+}
+
 // WithEnvironment sets the environment.
 func (c *Config) WithEnvironment(env string) *Config {
 	c.Environment = env
@@ -498,19 +766,41 @@ func (c *Config) WithEnableTelemetry(enable bool) *Config {
 	return c
 }
 
+// WithCredentials sets the provider of the API key of each call.
+func (c *Config) WithCredentials(provider CredentialProvider) *Config {
+	c.Credentials = provider
+	return c
+}
+
+// WithObserver sets the observer notified of every call.
+func (c *Config) WithObserver(observer telemetry.Observer) *Config {
+	c.Observer = observer
+	return c
+}
+
+// WithCassette sets the recorder recording or replaying the client's calls.
+func (c *Config) WithCassette(recorder *cassette.Recorder) *Config {
+	c.Cassette = recorder
+	return c
+}
+
+// WithLogger sets the logger of the client; bodies are logged if logBodies is set.
+func (c *Config) WithLogger(logger *slog.Logger, logBodies bool) *Config {
+	c.Logger = logger
+	c.LogBodies = logBodies
+	return c
+}
+
+// WithRateLimiter sets the rate limiter pacing the client's calls.
+func (c *Config) WithRateLimiter(limiter *ratelimit.Limiter) *Config {
+	c.RateLimiter = limiter
+	return c
+}
+
 // String returns a string representation of the config (without sensitive data).
 func (c *Config) String() string {
-	apiKeyMasked := ""
-	if c.APIKey != "" {
-		if len(c.APIKey) > 8 {
-			apiKeyMasked = strings.Repeat("*", len(c.APIKey)-8) + c.APIKey[len(c.APIKey)-8:]
-		} else {
-			apiKeyMasked = strings.Repeat("*", len(c.APIKey))
-		}
-	}
-
 	return fmt.Sprintf("Config{APIKey:%s, Host:%s, Port:%s, Insecure:%t, Environment:%s, MaxRetries:%d, EnableTelemetry:%t}",
-		apiKeyMasked, c.Host, c.GRPCPort, c.Insecure, c.Environment, c.MaxRetries, c.EnableTelemetry)
+		maskAPIKey(c.APIKey), c.Host, c.GRPCPort, c.Insecure, c.Environment, c.MaxRetries, c.EnableTelemetry)
 }
 
 // parseDuration parses a duration string that may have a suffix.
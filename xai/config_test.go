@@ -1,13 +1,26 @@
 package xai
 
 import (
+	"context"
 	"crypto/tls"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/credentials"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/constants"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/ratelimit"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/telemetry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -145,6 +158,15 @@ func TestConfigValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("CredentialsWithoutAPIKey", func(t *testing.T) {
+		config := DefaultConfig()
+		config.APIKey = ""
+		config = config.WithCredentials(credentials.Env("XAI_API_KEY"))
+		if err := config.Validate(); err != nil {
+			t.Errorf("A credentials provider should replace the API key: %v", err)
+		}
+	})
+
 	t.Run("InvalidTimeouts", func(t *testing.T) {
 		config := NewConfigWithAPIKey("test-key")
 		config.Timeout = 0
@@ -209,6 +231,211 @@ func TestConfigHTTPAddress(t *testing.T) {
 	}
 }
 
+func TestConfigLoadTransportFromEnvironment(t *testing.T) {
+	t.Setenv("XAI_PROXY_URL", "socks5://proxy.internal:1080")
+	t.Setenv("XAI_CA_BUNDLE", "/etc/ssl/corp.pem"+string(os.PathListSeparator)+"/etc/ssl/extra.pem")
+	t.Setenv("XAI_CLIENT_CERT", "/etc/xai/client.pem")
+	t.Setenv("XAI_CLIENT_KEY", "/etc/xai/client-key.pem")
+
+	config := DefaultConfig()
+	config.LoadFromEnvironment()
+
+	if config.ProxyURL != "socks5://proxy.internal:1080" {
+		t.Errorf("Expected proxy URL from XAI_PROXY_URL, got %s", config.ProxyURL)
+	}
+	if len(config.CABundlePaths) != 2 || config.CABundlePaths[1] != "/etc/ssl/extra.pem" {
+		t.Errorf("Expected two CA bundles, got %v", config.CABundlePaths)
+	}
+	if config.ClientCertPath != "/etc/xai/client.pem" || config.ClientKeyPath != "/etc/xai/client-key.pem" {
+		t.Errorf("Expected client certificate from environment, got %s and %s", config.ClientCertPath, config.ClientKeyPath)
+	}
+}
+
+func TestConfigValidateTransport(t *testing.T) {
+	config := NewConfigWithAPIKey("test-key").WithProxy("ftp://proxy.internal")
+	if err := config.Validate(); err == nil {
+		t.Error("Unsupported proxy scheme should return error")
+	}
+
+	config = NewConfigWithAPIKey("test-key").WithClientCertificate("/etc/xai/client.pem", "")
+	if err := config.Validate(); err == nil {
+		t.Error("Client certificate without key should return error")
+	}
+}
+
+// clearConfigEnvironment unsets the environment variables read by LoadConfig.
+func clearConfigEnvironment(t *testing.T) {
+	t.Helper()
+	t.Setenv("XAI_CONFIG_FILE", "")
+	t.Setenv("XAI_PROFILE", "")
+	for _, s := range settings {
+		if s.env != "" {
+			t.Setenv(s.env, "")
+		}
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	clearConfigEnvironment(t)
+	path := writeConfigFile(t, `{
+		"default_profile": "research",
+		"profiles": {
+			"research": {"api_key": "research-key", "timeout": "45s", "max_retries": 5},
+			"staging": {"api_key_file": "staging.key", "host": "api.staging.example.com", "insecure": true, "stream_timeout": 600}
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "staging.key"), []byte("staging-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XAI_CONFIG_FILE", path)
+
+	t.Run("DefaultProfile", func(t *testing.T) {
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.APIKey != "research-key" || config.Timeout != 45*time.Second || config.MaxRetries != 5 {
+			t.Errorf("Expected the research profile, got %+v", config)
+		}
+		if config.Host != constants.DefaultAPIV1Host {
+			t.Errorf("Expected default host, got %s", config.Host)
+		}
+	})
+
+	t.Run("SelectedProfile", func(t *testing.T) {
+		t.Setenv("XAI_PROFILE", "staging")
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Host != "api.staging.example.com" || !config.Insecure || config.StreamTimeout != 600*time.Second {
+			t.Errorf("Expected the staging profile, got %+v", config)
+		}
+		key, err := config.CredentialsProvider().APIKey(context.Background())
+		if err != nil || key != "staging-key" {
+			t.Errorf("Expected key from api_key_file, got %q, %v", key, err)
+		}
+
+		// WithProfile takes precedence over XAI_PROFILE
+		config, err = LoadConfig(WithProfile("research"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.APIKey != "research-key" {
+			t.Errorf("Expected the research profile, got %+v", config)
+		}
+	})
+
+	t.Run("EnvironmentOverridesFile", func(t *testing.T) {
+		t.Setenv("XAI_PROFILE", "staging")
+		t.Setenv("XAI_API_KEY", "env-key")
+		t.Setenv("XAI_HOST", "env.example.com")
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Host != "env.example.com" {
+			t.Errorf("Expected host from XAI_HOST, got %s", config.Host)
+		}
+		key, err := config.CredentialsProvider().APIKey(context.Background())
+		if err != nil || key != "env-key" {
+			t.Errorf("Expected key from XAI_API_KEY, got %q, %v", key, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for name, content := range map[string]string{
+			"UnknownField":   `{"profiles": {"default": {"hostname": "api.example.com"}}}`,
+			"SeveralKeys":    `{"profiles": {"default": {"api_key": "key", "api_key_command": ["pass", "xai"]}}}`,
+			"BadDuration":    `{"profiles": {"default": {"timeout": "soon"}}}`,
+			"MissingDefault": `{"default_profile": "missing", "profiles": {}}`,
+		} {
+			if _, err := LoadConfig(WithConfigFile(writeConfigFile(t, content))); err == nil {
+				t.Errorf("%s: expected error", name)
+			} else if e, ok := err.(*errors.Error); !ok || e.Type() != errors.ErrorTypeConfig {
+				t.Errorf("%s: expected config error, got %v", name, err)
+			}
+		}
+		if _, err := LoadConfig(WithProfile("missing")); err == nil {
+			t.Error("Unknown profile should return error")
+		}
+		if _, err := LoadConfig(WithConfigFile(filepath.Join(t.TempDir(), "missing.json"))); err == nil {
+			t.Error("Missing config file should return error")
+		}
+	})
+
+	t.Run("NoConfigFile", func(t *testing.T) {
+		t.Setenv("XAI_CONFIG_FILE", "")
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		t.Setenv("HOME", t.TempDir())
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Timeout != constants.DefaultTimeout {
+			t.Errorf("Expected default timeout, got %v", config.Timeout)
+		}
+		if _, err := LoadConfig(WithProfile("research")); err == nil {
+			t.Error("Profile without config file should return error")
+		}
+	})
+}
+
+func TestLoadConfigManagementKeyCommand(t *testing.T) {
+	clearConfigEnvironment(t)
+	path := writeConfigFile(t, `{"profiles": {"default": {"api_key": "key", "management_api_key_command": ["echo", "management-key"]}}}`)
+
+	config, err := LoadConfig(WithConfigFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ManagementAPIKey != "management-key" {
+		t.Errorf("Expected key printed by the command, got %q", config.ManagementAPIKey)
+	}
+}
+
+func TestConfigExplain(t *testing.T) {
+	clearConfigEnvironment(t)
+	path := writeConfigFile(t, `{"profiles": {"work": {"api_key": "work-api-key-123456", "timeout": "45s", "max_retries": 5}}}`)
+	t.Setenv("XAI_MAX_RETRIES", "7")
+
+	config, err := LoadConfig(WithConfigFile(path), WithProfile("work"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.WithEnvironment("staging")
+
+	explained := map[string]Setting{}
+	for _, s := range config.Explain() {
+		explained[s.Name] = s
+	}
+	for name, want := range map[string]Setting{
+		"APIKey":      {Value: "***********y-123456", Source: SourceFile, Origin: path + ", profile work"},
+		"Timeout":     {Value: "45s", Source: SourceFile, Origin: path + ", profile work"},
+		"MaxRetries":  {Value: "7", Source: SourceEnvironment, Origin: "XAI_MAX_RETRIES"},
+		"Environment": {Value: "staging", Source: SourceExplicit},
+		"Host":        {Value: constants.DefaultAPIV1Host, Source: SourceDefault},
+	} {
+		want.Name = name
+		if got := explained[name]; got != want {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+
+	if s := config.Explain().String(); !strings.Contains(s, "XAI_MAX_RETRIES") || strings.Contains(s, "work-api-key") {
+		t.Errorf("Unexpected explanation:\n%s", s)
+	}
+}
+
 func TestConfigCreateGRPCDialOptions(t *testing.T) {
 	t.Run("InsecureConnection", func(t *testing.T) {
 		config := &Config{
@@ -241,6 +468,73 @@ func TestConfigCreateGRPCDialOptions(t *testing.T) {
 		}
 	})
 
+	t.Run("Proxy", func(t *testing.T) {
+		config := NewConfigWithAPIKey("test-key").WithProxy("http://proxy.internal:3128")
+		base, err := DefaultConfig().CreateGRPCDialOptions()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		opts, err := config.CreateGRPCDialOptions()
+		if err != nil {
+			t.Errorf("Should not return error for proxy: %v", err)
+		}
+		if len(opts) != len(base) {
+			t.Errorf("Expected the proxy dialer to replace gRPC's proxy detection, got %d options instead of %d", len(opts), len(base))
+		}
+		if target := config.grpcTarget(); target != "passthrough:///"+config.GRPCAddress() {
+			t.Errorf("Expected the proxy to resolve the host, got target %s", target)
+		}
+	})
+
+	t.Run("ExtraOptions", func(t *testing.T) {
+		base, err := DefaultConfig().CreateGRPCDialOptions()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config := DefaultConfig().WithDialOptions(grpc.WithUserAgent("test"))
+		opts, err := config.CreateGRPCDialOptions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(opts) != len(base)+1 {
+			t.Errorf("Expected %d dial options, got %d", len(base)+1, len(opts))
+		}
+	})
+
+	t.Run("MissingClientCertificate", func(t *testing.T) {
+		config := DefaultConfig().WithClientCertificate("/nonexistent/client.pem", "/nonexistent/client-key.pem")
+		if _, err := config.CreateGRPCDialOptions(); err == nil {
+			t.Error("Should return error for a missing client certificate")
+		}
+	})
+
+	t.Run("Observer", func(t *testing.T) {
+		base, err := DefaultConfig().CreateGRPCDialOptions()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config := DefaultConfig().WithObserver(nopObserver{})
+		opts, err := config.CreateGRPCDialOptions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(opts) != len(base) {
+			t.Errorf("Expected the observer to add interceptors only, got %d options instead of %d", len(opts), len(base))
+		}
+		if config.observer() == nil {
+			t.Error("Expected the observer to be used")
+		}
+		if config.WithEnableTelemetry(false).observer() != nil {
+			t.Error("Expected no observer with telemetry disabled")
+		}
+		if config.WithLogger(slog.New(slog.DiscardHandler), false).observer() == nil {
+			t.Error("Expected calls to be logged with telemetry disabled")
+		}
+	})
+
 	t.Run("CustomTLS", func(t *testing.T) {
 		customTLS := &tls.Config{
 			MinVersion: tls.VersionTLS13,
@@ -260,6 +554,97 @@ func TestConfigCreateGRPCDialOptions(t *testing.T) {
 	})
 }
 
+type nopObserver struct{}
+
+func (nopObserver) StartCall(ctx context.Context, _ telemetry.Call) (context.Context, telemetry.CallObserver) {
+	return ctx, nil
+}
+
+func TestConfigStreamRetryWithRateLimiter(t *testing.T) {
+	newConfig := func() *Config {
+		return DefaultConfig().WithAPIKey("test-key").WithInsecure(true).WithRetryBackoff(time.Millisecond)
+	}
+	chat, opens := newFlakyChatClient(t, newConfig(), 1)
+
+	stream, err := chat.GetCompletionChunk(context.Background(), &xaiv1.GetCompletionsRequest{Model: "grok-4"})
+	if err != nil {
+		t.Fatalf("GetCompletionChunk() error = %v", err)
+	}
+	chunk, err := stream.Recv()
+	if err != nil || chunk.GetId() != "chunk-1" {
+		t.Fatalf("Recv() = %v, %v", chunk, err)
+	}
+	if *opens != 2 {
+		t.Errorf("opened %d streams, want 2", *opens)
+	}
+
+	// Once the retries are used up, the error of the last attempt is returned.
+	chat, opens = newFlakyChatClient(t, newConfig().WithMaxRetries(1), 3)
+	_, err = chat.GetCompletionChunk(context.Background(), &xaiv1.GetCompletionsRequest{Model: "grok-4"})
+	if status.Code(err) != codes.Unavailable || *opens != 2 {
+		t.Errorf("GetCompletionChunk() error = %v after %d streams, want Unavailable after 2", err, *opens)
+	}
+}
+
+// newFlakyChatClient returns a chat client with the interceptors of config
+// and a rate limiter whose first failures streams fail to open with
+// Unavailable. It reports the number of streams opened.
+func newFlakyChatClient(t *testing.T, config *Config, failures int) (xaiv1.ChatClient, *int) {
+	t.Helper()
+	opens := 0
+	// Stands in for the network, inside the rate limiter.
+	network := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ grpc.Streamer, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+		opens++
+		if opens <= failures {
+			return nil, status.Error(codes.Unavailable, "connection not ready")
+		}
+		return &fakeChunkStream{ctx: ctx}, nil
+	}
+	limiter := ratelimit.New(ratelimit.Config{Default: ratelimit.Limits{RequestsPerMinute: 100}})
+	config = config.WithRateLimiter(limiter).WithInterceptors(nil, network)
+
+	opts, err := config.CreateGRPCDialOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := grpc.NewClient("passthrough:///xai.test", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return xaiv1.NewChatClient(conn), &opens
+}
+
+// fakeChunkStream is a server stream returning a single chunk.
+type fakeChunkStream struct {
+	ctx  context.Context
+	sent bool
+}
+
+func (s *fakeChunkStream) Header() (metadata.MD, error) { return nil, nil }
+func (s *fakeChunkStream) Trailer() metadata.MD         { return nil }
+func (s *fakeChunkStream) CloseSend() error             { return nil }
+func (s *fakeChunkStream) Context() context.Context     { return s.ctx }
+func (s *fakeChunkStream) SendMsg(interface{}) error    { return nil }
+
+func (s *fakeChunkStream) RecvMsg(m interface{}) error {
+	if s.sent {
+		return io.EOF
+	}
+	s.sent = true
+	m.(*xaiv1.GetChatCompletionChunk).Id = "chunk-1"
+	return nil
+}
+
+func TestConfigRetryPolicy(t *testing.T) {
+	config := DefaultConfig().WithMaxRetries(5).WithRetryBackoff(2 * time.Second).WithMaxBackoff(time.Minute)
+
+	policy := config.RetryPolicy()
+	if policy.MaxRetries != 5 || policy.InitialBackoff != 2*time.Second || policy.MaxBackoff != time.Minute {
+		t.Errorf("RetryPolicy() = %+v", policy)
+	}
+}
+
 func TestConfigWithMethods(t *testing.T) {
 	config := DefaultConfig()
 
@@ -311,6 +696,13 @@ func TestConfigWithMethods(t *testing.T) {
 	if config.EnableTelemetry {
 		t.Error("Expected telemetry to be false")
 	}
+
+	// Test WithRateLimiter
+	limiter := ratelimit.New(ratelimit.Config{Default: ratelimit.Limits{RequestsPerMinute: 60}})
+	config = config.WithRateLimiter(limiter)
+	if config.RateLimiter != limiter {
+		t.Error("Expected rate limiter to be set")
+	}
 }
 
 func TestConfigString(t *testing.T) {
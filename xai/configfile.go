@@ -0,0 +1,383 @@
+package xai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai/credentials"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/constants"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+)
+
+// DefaultProfile is the profile used when none is selected.
+const DefaultProfile = "default"
+
+// configFile is the layout of a configuration file.
+type configFile struct {
+	// DefaultProfile is the profile used when XAI_PROFILE is not set.
+	DefaultProfile string `json:"default_profile"`
+
+	Profiles map[string]profile `json:"profiles"`
+}
+
+// profile is a named set of settings of a configuration file, keyed like the
+// JSON fields of Config. Unset fields keep their defaults.
+type profile struct {
+	// At most one of APIKey, APIKeyFile and APIKeyCommand is set, and
+	// likewise for the management API key. APIKeyCommand is an argv whose
+	// command prints the key.
+	APIKey                  string   `json:"api_key"`
+	APIKeyFile              string   `json:"api_key_file"`
+	APIKeyCommand           []string `json:"api_key_command"`
+	ManagementAPIKey        string   `json:"management_api_key"`
+	ManagementAPIKeyFile    string   `json:"management_api_key_file"`
+	ManagementAPIKeyCommand []string `json:"management_api_key_command"`
+
+	Host              string `json:"host"`
+	GRPCPort          string `json:"grpc_port"`
+	HTTPHost          string `json:"http_host"`
+	ManagementAPIHost string `json:"management_api_host"`
+	HTTPPort          string `json:"http_port"`
+
+	Timeout          *duration `json:"timeout"`
+	ConnectTimeout   *duration `json:"connect_timeout"`
+	KeepAliveTimeout *duration `json:"keep_alive_timeout"`
+	StreamTimeout    *duration `json:"stream_timeout"`
+
+	Insecure       *bool    `json:"insecure"`
+	SkipVerify     *bool    `json:"skip_verify"`
+	ProxyURL       string   `json:"proxy_url"`
+	CABundlePaths  []string `json:"ca_bundle_paths"`
+	ClientCertPath string   `json:"client_cert_path"`
+	ClientKeyPath  string   `json:"client_key_path"`
+
+	MaxRetries   *int      `json:"max_retries"`
+	RetryBackoff *duration `json:"retry_backoff"`
+	MaxBackoff   *duration `json:"max_backoff"`
+
+	Environment     string `json:"environment"`
+	UserAgent       string `json:"user_agent"`
+	EnableTelemetry *bool  `json:"enable_telemetry"`
+	LogBodies       *bool  `json:"log_bodies"`
+}
+
+// duration is a duration in a configuration file: a string such as "30s",
+// or a number of seconds.
+type duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var seconds float64
+		if err := json.Unmarshal(data, &seconds); err != nil {
+			return fmt.Errorf("invalid duration: %s", data)
+		}
+		*d = duration(seconds * float64(time.Second))
+		return nil
+	}
+	parsed, err := parseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// LoadOption customizes LoadConfig.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	path    string
+	profile string
+}
+
+// WithConfigFile reads the configuration file at path instead of
+// XAI_CONFIG_FILE or DefaultConfigFile. The file must exist.
+func WithConfigFile(path string) LoadOption {
+	return func(o *loadOptions) {
+		o.path = path
+	}
+}
+
+// WithProfile selects the profile of the configuration file instead of
+// XAI_PROFILE or the file's default_profile.
+func WithProfile(name string) LoadOption {
+	return func(o *loadOptions) {
+		o.profile = name
+	}
+}
+
+// DefaultConfigFile returns the default configuration file, xai/config.json
+// in the user configuration directory: $XDG_CONFIG_HOME or ~/.config on
+// Linux, ~/Library/Application Support on macOS and %AppData% on Windows.
+func DefaultConfigFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "xai", "config.json"), nil
+}
+
+// LoadConfig returns a configuration layering, from lowest to highest
+// precedence, the defaults, a profile of the configuration file and the
+// environment variables; With* calls on the result override all three.
+//
+// The file is the one given with WithConfigFile, otherwise XAI_CONFIG_FILE,
+// otherwise DefaultConfigFile, which may be missing. The profile is the one
+// given with WithProfile, otherwise XAI_PROFILE, otherwise the file's
+// default_profile, otherwise DefaultProfile. A file looks like:
+//
+//	{
+//	  "default_profile": "research",
+//	  "profiles": {
+//	    "research": {"api_key_command": ["op", "read", "op://research/xai/key"]},
+//	    "staging": {"api_key_file": "~/.secrets/xai-staging", "host": "api.staging.example.com", "timeout": "60s"}
+//	  }
+//	}
+//
+// Profile keys are the JSON fields of Config, with durations such as "30s".
+// Instead of a plaintext api_key, a profile can reference the key with
+// api_key_file, read by a credentials.File provider that picks up rotated
+// keys, or api_key_command, run by a credentials.Command provider. The
+// management_api_key_file and management_api_key_command references are
+// resolved once, by LoadConfig. Relative paths are relative to the file.
+// Config.Explain reports which layer each value came from.
+func LoadConfig(opts ...LoadOption) (*Config, error) {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	path, required := o.path, true
+	if path == "" {
+		path = os.Getenv("XAI_CONFIG_FILE")
+	}
+	if path == "" {
+		required = false
+		path, _ = DefaultConfigFile()
+	}
+	name := o.profile
+	if name == "" {
+		name = os.Getenv("XAI_PROFILE")
+	}
+
+	config := DefaultConfig()
+	if err := config.loadFile(path, name, required); err != nil {
+		return nil, err
+	}
+	config.LoadFromEnvironment()
+
+	// An API key from the environment overrides a key reference of the file
+	if config.sourceOf("APIKey") == SourceEnvironment && config.sourceOf("Credentials") == SourceFile {
+		config.Credentials = nil
+		config.forgetSource("Credentials")
+	}
+	return config, nil
+}
+
+// loadFile applies a profile of the configuration file at path. A missing
+// file is ignored unless required or a profile is selected.
+func (c *Config) loadFile(path, name string, required bool) error {
+	var data []byte
+	var err error
+	if path != "" {
+		data, err = os.ReadFile(path) //nolint:gosec // the path is chosen by the user
+	}
+	if path == "" || (err != nil && os.IsNotExist(err) && !required) {
+		if name != "" {
+			return errors.NewConfigError(fmt.Sprintf("profile %q selected but no config file found", name))
+		}
+		return nil
+	}
+	if err != nil {
+		return errors.NewConfigError(fmt.Sprintf("failed to read config file: %v", err))
+	}
+
+	var file configFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return errors.NewConfigError(fmt.Sprintf("invalid config file %s: %v", path, err))
+	}
+
+	if name == "" {
+		name = file.DefaultProfile
+	}
+	p, ok := file.Profiles[name]
+	if name == "" {
+		name = DefaultProfile
+		p, ok = file.Profiles[name]
+		if !ok {
+			return nil
+		}
+	}
+	if !ok {
+		return errors.NewConfigError(fmt.Sprintf("profile %q not found in config file %s", name, path))
+	}
+
+	before := c.snapshot()
+	if err := c.applyProfile(p, filepath.Dir(path)); err != nil {
+		return errors.NewConfigError(fmt.Sprintf("profile %q of config file %s: %v", name, path, err))
+	}
+	c.track(before, SourceFile, func(setting) string {
+		return fmt.Sprintf("%s, profile %s", path, name)
+	})
+	return nil
+}
+
+// applyProfile applies the settings of p; relative paths are resolved
+// against dir.
+func (c *Config) applyProfile(p profile, dir string) error {
+	resolve := pathResolver(dir)
+	if err := c.applyProfileKeys(p, resolve); err != nil {
+		return err
+	}
+	c.applyProfileStrings(p, resolve)
+	c.applyProfileDurations(p)
+	c.applyProfileFlags(p)
+	if p.MaxRetries != nil {
+		if *p.MaxRetries < 0 {
+			return fmt.Errorf("max_retries cannot be negative")
+		}
+		c.MaxRetries = *p.MaxRetries
+	}
+	return nil
+}
+
+// pathResolver returns a function expanding ~/ and resolving relative paths
+// against dir.
+func pathResolver(dir string) func(string) string {
+	return func(path string) string {
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			if home, err := os.UserHomeDir(); err == nil {
+				return filepath.Join(home, rest)
+			}
+		}
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+}
+
+// applyProfileKeys applies the API keys of p, given in plaintext or
+// referenced by file or command.
+func (c *Config) applyProfileKeys(p profile, resolve func(string) string) error {
+	provider, err := keyReference("api_key", p.APIKey, resolve(p.APIKeyFile), p.APIKeyCommand)
+	if err != nil {
+		return err
+	}
+	if provider != nil {
+		c.Credentials = provider
+	} else if p.APIKey != "" {
+		c.APIKey = p.APIKey
+	}
+
+	provider, err = keyReference("management_api_key", p.ManagementAPIKey, resolve(p.ManagementAPIKeyFile), p.ManagementAPIKeyCommand)
+	if err != nil {
+		return err
+	}
+	if provider == nil {
+		if p.ManagementAPIKey != "" {
+			c.ManagementAPIKey = p.ManagementAPIKey
+		}
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultTimeout)
+	defer cancel()
+	key, err := provider.APIKey(ctx)
+	if err != nil {
+		return fmt.Errorf("management API key: %w", err)
+	}
+	c.ManagementAPIKey = key
+	return nil
+}
+
+// applyProfileStrings applies the string and path settings of p.
+func (c *Config) applyProfileStrings(p profile, resolve func(string) string) {
+	setString := func(dst *string, value string) {
+		if value != "" {
+			*dst = value
+		}
+	}
+	setString(&c.Host, p.Host)
+	setString(&c.GRPCPort, p.GRPCPort)
+	setString(&c.HTTPHost, p.HTTPHost)
+	setString(&c.ManagementAPIHost, p.ManagementAPIHost)
+	setString(&c.HTTPPort, p.HTTPPort)
+	setString(&c.ProxyURL, p.ProxyURL)
+	setString(&c.ClientCertPath, resolve(p.ClientCertPath))
+	setString(&c.ClientKeyPath, resolve(p.ClientKeyPath))
+	setString(&c.Environment, p.Environment)
+	setString(&c.UserAgent, p.UserAgent)
+	if p.CABundlePaths != nil {
+		c.CABundlePaths = make([]string, len(p.CABundlePaths))
+		for i, path := range p.CABundlePaths {
+			c.CABundlePaths[i] = resolve(path)
+		}
+	}
+}
+
+// applyProfileDurations applies the timeouts and backoffs of p.
+func (c *Config) applyProfileDurations(p profile) {
+	for _, d := range []struct {
+		dst   *time.Duration
+		value *duration
+	}{
+		{&c.Timeout, p.Timeout},
+		{&c.ConnectTimeout, p.ConnectTimeout},
+		{&c.KeepAliveTimeout, p.KeepAliveTimeout},
+		{&c.StreamTimeout, p.StreamTimeout},
+		{&c.RetryBackoff, p.RetryBackoff},
+		{&c.MaxBackoff, p.MaxBackoff},
+	} {
+		if d.value != nil {
+			*d.dst = time.Duration(*d.value)
+		}
+	}
+}
+
+// applyProfileFlags applies the boolean settings of p.
+func (c *Config) applyProfileFlags(p profile) {
+	for _, b := range []struct {
+		dst   *bool
+		value *bool
+	}{
+		{&c.Insecure, p.Insecure},
+		{&c.SkipVerify, p.SkipVerify},
+		{&c.EnableTelemetry, p.EnableTelemetry},
+		{&c.LogBodies, p.LogBodies},
+	} {
+		if b.value != nil {
+			*b.dst = *b.value
+		}
+	}
+}
+
+// keyReference returns the provider of a key referenced by file or command,
+// or nil for a plaintext key or none.
+func keyReference(field, key, file string, command []string) (CredentialProvider, error) {
+	set := 0
+	for _, ok := range []bool{key != "", file != "", len(command) > 0} {
+		if ok {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("only one of %s, %s_file and %s_command can be set", field, field, field)
+	}
+	switch {
+	case file != "":
+		return credentials.File(file, 0), nil
+	case len(command) > 0:
+		return credentials.Command(command[0], command[1:]...), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,29 @@
+package xai
+
+import "github.com/ZaguanLabs/xai-sdk-go/xai/credentials"
+
+// CredentialProvider provides the API key of each call. See the credentials
+// package for the available providers.
+type CredentialProvider = credentials.Provider
+
+// CredentialsProvider returns the provider of the API key of each call:
+// Credentials if set, otherwise a static provider for APIKey, or nil if
+// neither is set.
+func (c *Config) CredentialsProvider() CredentialProvider {
+	if c.Credentials != nil {
+		return c.Credentials
+	}
+	if c.APIKey != "" {
+		return credentials.Static(c.APIKey)
+	}
+	return nil
+}
+
+// managementCredentialsProvider returns the provider of the management API
+// key: ManagementAPIKey if set, otherwise the provider of the API key.
+func (c *Config) managementCredentialsProvider() CredentialProvider {
+	if c.ManagementAPIKey != "" {
+		return credentials.Static(c.ManagementAPIKey)
+	}
+	return c.CredentialsProvider()
+}
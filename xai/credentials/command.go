@@ -0,0 +1,76 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCommandTTL is how long a CommandProvider reuses the key printed by its command.
+const DefaultCommandTTL = 5 * time.Minute
+
+// CommandProvider runs a command that prints the key on its standard output,
+// such as a secret manager CLI, and reuses the key for a TTL.
+type CommandProvider struct {
+	name string
+	args []string
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	key     string
+	fetched time.Time
+}
+
+// Command returns a provider running name with args. The key is reused for
+// DefaultCommandTTL; use WithTTL to change it.
+func Command(name string, args ...string) *CommandProvider {
+	return &CommandProvider{name: name, args: args, ttl: DefaultCommandTTL}
+}
+
+// WithTTL sets how long the key printed by the command is reused.
+func (p *CommandProvider) WithTTL(ttl time.Duration) *CommandProvider {
+	p.ttl = ttl
+	return p
+}
+
+// APIKey returns the key printed by the command, running it if the last key
+// expired.
+func (p *CommandProvider) APIKey(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.key != "" && time.Since(p.fetched) < p.ttl {
+		return p.key, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.name, p.args...) //nolint:gosec // the command is chosen by the application
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("API key command %s failed: %w: %s", p.name, err, strings.TrimSpace(stderr.String()))
+	}
+	key := strings.TrimSpace(stdout.String())
+	if key == "" {
+		return "", fmt.Errorf("%w: command %s printed nothing", ErrNoAPIKey, p.name)
+	}
+	p.key, p.fetched = key, time.Now()
+	return key, nil
+}
+
+// Report discards the key when it was rejected, so that the command runs
+// again on the next call.
+func (p *CommandProvider) Report(key string, err error) bool {
+	if isAuthError(err) {
+		p.mu.Lock()
+		if p.key == key {
+			p.key = ""
+		}
+		p.mu.Unlock()
+	}
+	return false
+}
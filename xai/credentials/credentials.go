@@ -0,0 +1,84 @@
+// Package credentials provides the API keys used by the xAI SDK.
+//
+// A Provider is consulted before every gRPC and REST call, so keys can be
+// rotated without restarting: Static returns a fixed key, Env reads an
+// environment variable, File follows a file rewritten by a secret manager,
+// Command runs a helper program and Func calls back into the application.
+// Pool spreads calls across several keys and fails over when a key is
+// rejected or rate limited.
+//
+//	pool := credentials.NewPool([]string{keyA, keyB}, credentials.WithStrategy(credentials.RoundRobin))
+//	client, err := xai.NewClient(xai.NewConfig().WithCredentials(pool))
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrNoAPIKey is returned when a provider has no API key to offer.
+var ErrNoAPIKey = errors.New("no API key available")
+
+// Provider provides the API key of each call.
+type Provider interface {
+	// APIKey returns the API key to authenticate the next call with.
+	APIKey(ctx context.Context) (string, error)
+}
+
+// Reporter is implemented by providers that react to failed calls, such as
+// Pool retiring a rejected key.
+type Reporter interface {
+	// Report is called with the key and error of a failed call. It returns true
+	// if the call may be retried at once with another key.
+	Report(key string, err error) bool
+}
+
+// Report forwards the outcome of a call made with key to p if it is a
+// Reporter, and returns whether the call may be retried with another key.
+func Report(p Provider, key string, err error) bool {
+	if reporter, ok := p.(Reporter); ok && err != nil {
+		return reporter.Report(key, err)
+	}
+	return false
+}
+
+// Func adapts a function to a Provider.
+type Func func(ctx context.Context) (string, error)
+
+// APIKey calls f.
+func (f Func) APIKey(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+type staticProvider string
+
+// Static returns a provider that always returns key.
+func Static(key string) Provider {
+	return staticProvider(key)
+}
+
+func (p staticProvider) APIKey(context.Context) (string, error) {
+	if p == "" {
+		return "", ErrNoAPIKey
+	}
+	return string(p), nil
+}
+
+type envProvider string
+
+// Env returns a provider that reads the key from the environment variable
+// name on every call.
+func Env(name string) Provider {
+	return envProvider(name)
+}
+
+func (p envProvider) APIKey(context.Context) (string, error) {
+	key := strings.TrimSpace(os.Getenv(string(p)))
+	if key == "" {
+		return "", fmt.Errorf("%w: %s is not set", ErrNoAPIKey, string(p))
+	}
+	return key, nil
+}
@@ -0,0 +1,144 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	xaierrors "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStaticEnvAndFunc(t *testing.T) {
+	ctx := context.Background()
+	if key, err := Static("key-1").APIKey(ctx); err != nil || key != "key-1" {
+		t.Errorf("Static: %q, %v", key, err)
+	}
+	if _, err := Static("").APIKey(ctx); !errors.Is(err, ErrNoAPIKey) {
+		t.Errorf("empty Static: %v, want ErrNoAPIKey", err)
+	}
+
+	t.Setenv("XAI_TEST_KEY", " key-2\n")
+	if key, err := Env("XAI_TEST_KEY").APIKey(ctx); err != nil || key != "key-2" {
+		t.Errorf("Env: %q, %v", key, err)
+	}
+	if _, err := Env("XAI_TEST_KEY_UNSET").APIKey(ctx); !errors.Is(err, ErrNoAPIKey) {
+		t.Errorf("unset Env: %v, want ErrNoAPIKey", err)
+	}
+
+	calls := 0
+	f := Func(func(context.Context) (string, error) {
+		calls++
+		return "key-3", nil
+	})
+	if key, _ := f.APIKey(ctx); key != "key-3" || calls != 1 {
+		t.Errorf("Func: %q after %d calls", key, calls)
+	}
+}
+
+func TestFileFollowsRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(path, []byte("old-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p := File(path, time.Nanosecond)
+	if key, err := p.APIKey(context.Background()); err != nil || key != "old-key" {
+		t.Fatalf("APIKey() = %q, %v", key, err)
+	}
+
+	if err := os.WriteFile(path, []byte("new-key-2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if key, err := p.APIKey(context.Background()); err != nil || key != "new-key-2" {
+		t.Errorf("after rotation: APIKey() = %q, %v", key, err)
+	}
+
+	// The last key is kept while the file is missing.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if key, err := p.APIKey(context.Background()); err != nil || key != "new-key-2" {
+		t.Errorf("missing file: APIKey() = %q, %v", key, err)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo is not available")
+	}
+	p := Command("echo", "cmd-key")
+	if key, err := p.APIKey(context.Background()); err != nil || key != "cmd-key" {
+		t.Fatalf("APIKey() = %q, %v", key, err)
+	}
+	p.Report("cmd-key", status.Error(codes.Unauthenticated, "revoked"))
+	if p.key != "" {
+		t.Error("a rejected key should be discarded")
+	}
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	p := NewPool([]string{"a", "b", "c"}, WithStrategy(RoundRobin))
+	var got []string
+	for range 4 {
+		key, _ := p.APIKey(context.Background())
+		got = append(got, key)
+	}
+	if want := []string{"a", "b", "c", "a"}; !equal(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	p := NewPool([]string{"primary", "secondary"})
+	now := time.Unix(0, 0)
+	p.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if key, _ := p.APIKey(ctx); key != "primary" {
+		t.Fatalf("APIKey() = %q, want primary", key)
+	}
+
+	rateLimited := xaierrors.FromHTTP(429, map[string][]string{"Retry-After": {"10"}}, nil, nil)
+	if !p.Report("primary", rateLimited) {
+		t.Error("Report() should offer the secondary key")
+	}
+	if key, _ := p.APIKey(ctx); key != "secondary" {
+		t.Errorf("APIKey() = %q, want secondary while primary is rate limited", key)
+	}
+
+	now = now.Add(11 * time.Second)
+	if key, _ := p.APIKey(ctx); key != "primary" {
+		t.Errorf("APIKey() = %q, want primary after its cooldown", key)
+	}
+
+	// Errors other than authentication and rate limits are ignored.
+	if p.Report("primary", status.Error(codes.Internal, "oops")) {
+		t.Error("Report() should ignore internal errors")
+	}
+
+	// With every key set aside, the one available soonest is used.
+	p.Report("secondary", status.Error(codes.Unauthenticated, "revoked"))
+	if p.Report("primary", status.Error(codes.ResourceExhausted, "slow down")) {
+		t.Error("Report() should not offer another key when none is healthy")
+	}
+	if key, _ := p.APIKey(ctx); key != "primary" {
+		t.Errorf("APIKey() = %q, want primary", key)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultFileCheckInterval is how often a FileProvider checks its file for changes.
+const DefaultFileCheckInterval = 5 * time.Second
+
+// FileProvider reads the key from a file and reloads it when the file changes,
+// for keys mounted from a secret store. Leading and trailing whitespace is
+// ignored. If the file becomes unreadable, the last key read is kept.
+type FileProvider struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	key     string
+	modTime time.Time
+	size    int64
+	checked time.Time
+}
+
+// File returns a provider reading the key from path, checked for changes at
+// most every interval (default: DefaultFileCheckInterval).
+func File(path string, interval time.Duration) *FileProvider {
+	if interval <= 0 {
+		interval = DefaultFileCheckInterval
+	}
+	return &FileProvider{path: path, interval: interval}
+}
+
+// APIKey returns the key in the file.
+func (p *FileProvider) APIKey(context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.key != "" && now.Sub(p.checked) < p.interval {
+		return p.key, nil
+	}
+	p.checked = now
+
+	info, err := os.Stat(p.path)
+	if err == nil && p.key != "" && info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return p.key, nil
+	}
+	if err == nil {
+		err = p.load(info)
+	}
+	if p.key == "" {
+		if err == nil {
+			err = fmt.Errorf("%w: %s is empty", ErrNoAPIKey, p.path)
+		}
+		return "", err
+	}
+	return p.key, nil
+}
+
+// Report forces the file to be checked on the next call when the key was
+// rejected, so that a rotated key is picked up at once.
+func (p *FileProvider) Report(_ string, err error) bool {
+	if isAuthError(err) {
+		p.mu.Lock()
+		p.checked = time.Time{}
+		p.modTime = time.Time{}
+		p.mu.Unlock()
+	}
+	return false
+}
+
+func (p *FileProvider) load(info os.FileInfo) error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read API key file: %w", err)
+	}
+	if key := strings.TrimSpace(string(data)); key != "" {
+		p.key = key
+		p.modTime, p.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
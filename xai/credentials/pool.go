@@ -0,0 +1,157 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	xaierrors "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Strategy selects the key of each call in a Pool.
+type Strategy int
+
+const (
+	// Failover uses the first healthy key in order, moving to the next one
+	// while it is set aside.
+	Failover Strategy = iota
+
+	// RoundRobin rotates through the healthy keys on every call.
+	RoundRobin
+)
+
+const (
+	// DefaultRateLimitCooldown is how long a rate limited key is set aside when
+	// the server did not say when to retry.
+	DefaultRateLimitCooldown = time.Minute
+
+	// DefaultAuthCooldown is how long a rejected key is set aside.
+	DefaultAuthCooldown = time.Hour
+)
+
+// PoolOption configures a Pool.
+type PoolOption func(*Pool)
+
+// WithStrategy sets how the pool selects keys (default: Failover).
+func WithStrategy(strategy Strategy) PoolOption {
+	return func(p *Pool) {
+		p.strategy = strategy
+	}
+}
+
+// WithCooldowns sets how long keys are set aside after a rate limit error
+// without retry delay and after an authentication error.
+func WithCooldowns(rateLimit, auth time.Duration) PoolOption {
+	return func(p *Pool) {
+		p.rateLimitCooldown = rateLimit
+		p.authCooldown = auth
+	}
+}
+
+// Pool spreads calls across several API keys. Keys that are rejected
+// (Unauthenticated, HTTP 401) or rate limited (ResourceExhausted, HTTP 429)
+// are set aside for a cooldown, and the call may be retried with another key.
+// When every key is set aside, the one available soonest is used.
+type Pool struct {
+	strategy          Strategy
+	rateLimitCooldown time.Duration
+	authCooldown      time.Duration
+	now               func() time.Time
+
+	mu        sync.Mutex
+	keys      []string
+	available []time.Time
+	next      int
+}
+
+// NewPool creates a pool of keys.
+func NewPool(keys []string, opts ...PoolOption) *Pool {
+	p := &Pool{
+		strategy:          Failover,
+		rateLimitCooldown: DefaultRateLimitCooldown,
+		authCooldown:      DefaultAuthCooldown,
+		now:               time.Now,
+		keys:              append([]string(nil), keys...),
+		available:         make([]time.Time, len(keys)),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// APIKey returns the key of the next call.
+func (p *Pool) APIKey(context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.keys) == 0 {
+		return "", ErrNoAPIKey
+	}
+	now := p.now()
+	start := 0
+	if p.strategy == RoundRobin {
+		start = p.next
+	}
+	soonest := -1
+	for i := range p.keys {
+		idx := (start + i) % len(p.keys)
+		if !p.available[idx].After(now) {
+			if p.strategy == RoundRobin {
+				p.next = idx + 1
+			}
+			return p.keys[idx], nil
+		}
+		if soonest < 0 || p.available[idx].Before(p.available[soonest]) {
+			soonest = idx
+		}
+	}
+	return p.keys[soonest], nil
+}
+
+// Report sets key aside if err is an authentication or rate limit error, and
+// returns true if another key is available.
+func (p *Pool) Report(key string, err error) bool {
+	var cooldown time.Duration
+	switch {
+	case isAuthError(err):
+		cooldown = p.authCooldown
+	case isRateLimitError(err):
+		cooldown = p.rateLimitCooldown
+		var apiErr *xaierrors.APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			cooldown = apiErr.RetryAfter
+		}
+	default:
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	others := false
+	for i, k := range p.keys {
+		if k == key {
+			p.available[i] = now.Add(cooldown)
+		} else if !p.available[i].After(now) {
+			others = true
+		}
+	}
+	return others
+}
+
+// Len returns the number of keys in the pool.
+func (p *Pool) Len() int {
+	return len(p.keys)
+}
+
+func isAuthError(err error) bool {
+	return status.Code(err) == codes.Unauthenticated
+}
+
+func isRateLimitError(err error) bool {
+	return status.Code(err) == codes.ResourceExhausted
+}
@@ -0,0 +1,41 @@
+package xai
+
+import "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+
+// APIError is an error reported by the xAI API. Every package of the SDK
+// returns it, possibly wrapped, for failed gRPC and REST calls:
+//
+//	var apiErr *xai.APIError
+//	if errors.As(err, &apiErr) {
+//		log.Printf("request %s failed with %s", apiErr.RequestID, apiErr.Code)
+//	}
+//	if errors.Is(err, xai.ErrRateLimited) {
+//		time.Sleep(apiErr.RetryAfter)
+//	}
+//
+// status.FromError and status.Code also work on errors wrapping an APIError.
+type APIError = errors.APIError
+
+// Sentinel errors matched by APIError with errors.Is.
+var (
+	// ErrRateLimited indicates the request was rejected by rate limits or quotas.
+	ErrRateLimited = errors.ErrRateLimit
+
+	// ErrAuthentication indicates a missing, invalid or revoked API key.
+	ErrAuthentication = errors.ErrUnauthorized
+
+	// ErrPermissionDenied indicates the API key may not perform the request.
+	ErrPermissionDenied = errors.ErrPermissionDenied
+
+	// ErrNotFound indicates the model or resource does not exist.
+	ErrNotFound = errors.ErrNotFound
+
+	// ErrInvalidRequest indicates the request was rejected as invalid.
+	ErrInvalidRequest = errors.ErrInvalidRequest
+
+	// ErrContextTooLong indicates the prompt does not fit the model's context window.
+	ErrContextTooLong = errors.ErrContextTooLong
+
+	// ErrServiceUnavailable indicates the service is temporarily unavailable.
+	ErrServiceUnavailable = errors.ErrServiceUnavailable
+)
@@ -0,0 +1,180 @@
+package xai
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/logging"
+)
+
+// Source is the layer an effective configuration value came from.
+type Source string
+
+// Configuration sources, from lowest to highest precedence.
+const (
+	SourceDefault     Source = "default"
+	SourceFile        Source = "file"
+	SourceEnvironment Source = "environment"
+	SourceExplicit    Source = "explicit"
+)
+
+// Setting is an effective configuration value and where it came from.
+type Setting struct {
+	// Name is the Config field, such as "Timeout".
+	Name string
+
+	// Value is the formatted value, with API keys masked and proxy
+	// credentials redacted.
+	Value string
+
+	Source Source
+
+	// Origin details the source: the environment variable, or the
+	// configuration file and profile.
+	Origin string
+}
+
+// Explanation lists the effective configuration values.
+type Explanation []Setting
+
+// String formats the explanation as a table.
+func (e Explanation) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, s := range e {
+		value := s.Value
+		if value == "" {
+			value = "-"
+		}
+		source := string(s.Source)
+		if s.Origin != "" {
+			source += " (" + s.Origin + ")"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, value, source)
+	}
+	_ = w.Flush()
+	return b.String()
+}
+
+// Explain reports which source each effective value came from: the default,
+// the configuration file, an environment variable or an explicit change such
+// as a With* call. Sources are recorded by LoadConfig and
+// LoadFromEnvironment; a value changed since is explicit.
+func (c *Config) Explain() Explanation {
+	defaults := DefaultConfig().snapshot()
+	current := c.snapshot()
+	explanation := make(Explanation, 0, len(settings))
+	for _, s := range settings {
+		setting := Setting{Name: s.name, Value: current[s.name], Source: SourceExplicit}
+		if origin, ok := c.sources[s.name]; ok && origin.value == current[s.name] {
+			setting.Source, setting.Origin = origin.source, origin.origin
+		} else if !ok && current[s.name] == defaults[s.name] {
+			setting.Source = SourceDefault
+		}
+		switch {
+		case s.secret:
+			setting.Value = maskAPIKey(setting.Value)
+		case s.name == "ProxyURL":
+			setting.Value = logging.Redact(setting.Value)
+		}
+		explanation = append(explanation, setting)
+	}
+	return explanation
+}
+
+// valueSource records the source of a value and the value it set.
+type valueSource struct {
+	source Source
+	origin string
+	value  string
+}
+
+// setting is an explained Config field.
+type setting struct {
+	name   string
+	env    string
+	secret bool
+	value  func(*Config) string
+}
+
+// settings are the explained Config fields.
+var settings = []setting{
+	{"APIKey", "XAI_API_KEY", true, func(c *Config) string { return c.APIKey }},
+	{"Credentials", "", false, func(c *Config) string {
+		if c.Credentials == nil {
+			return ""
+		}
+		return fmt.Sprintf("%T", c.Credentials)
+	}},
+	{"ManagementAPIKey", "XAI_MANAGEMENT_API_KEY", true, func(c *Config) string { return c.ManagementAPIKey }},
+	{"Host", "XAI_HOST", false, func(c *Config) string { return c.Host }},
+	{"GRPCPort", "XAI_GRPC_PORT", false, func(c *Config) string { return c.GRPCPort }},
+	{"HTTPHost", "XAI_HTTP_HOST", false, func(c *Config) string { return c.HTTPHost }},
+	{"ManagementAPIHost", "XAI_MANAGEMENT_API_HOST", false, func(c *Config) string { return c.ManagementAPIHost }},
+	{"HTTPPort", "XAI_HTTP_PORT", false, func(c *Config) string { return c.HTTPPort }},
+	{"Timeout", "XAI_TIMEOUT", false, func(c *Config) string { return c.Timeout.String() }},
+	{"ConnectTimeout", "XAI_CONNECT_TIMEOUT", false, func(c *Config) string { return c.ConnectTimeout.String() }},
+	{"KeepAliveTimeout", "XAI_KEEPALIVE_TIMEOUT", false, func(c *Config) string { return c.KeepAliveTimeout.String() }},
+	{"StreamTimeout", "XAI_STREAM_TIMEOUT", false, func(c *Config) string { return c.StreamTimeout.String() }},
+	{"Insecure", "XAI_INSECURE", false, func(c *Config) string { return strconv.FormatBool(c.Insecure) }},
+	{"SkipVerify", "XAI_SKIP_VERIFY", false, func(c *Config) string { return strconv.FormatBool(c.SkipVerify) }},
+	{"ProxyURL", "XAI_PROXY_URL", false, func(c *Config) string { return c.ProxyURL }},
+	{"CABundlePaths", "XAI_CA_BUNDLE", false, func(c *Config) string { return strings.Join(c.CABundlePaths, ", ") }},
+	{"ClientCertPath", "XAI_CLIENT_CERT", false, func(c *Config) string { return c.ClientCertPath }},
+	{"ClientKeyPath", "XAI_CLIENT_KEY", false, func(c *Config) string { return c.ClientKeyPath }},
+	{"MaxRetries", "XAI_MAX_RETRIES", false, func(c *Config) string { return strconv.Itoa(c.MaxRetries) }},
+	{"RetryBackoff", "XAI_RETRY_BACKOFF", false, func(c *Config) string { return c.RetryBackoff.String() }},
+	{"MaxBackoff", "XAI_MAX_BACKOFF", false, func(c *Config) string { return c.MaxBackoff.String() }},
+	{"Environment", "XAI_ENVIRONMENT", false, func(c *Config) string { return c.Environment }},
+	{"UserAgent", "XAI_USER_AGENT", false, func(c *Config) string { return c.UserAgent }},
+	{"EnableTelemetry", "XAI_ENABLE_TELEMETRY", false, func(c *Config) string { return strconv.FormatBool(c.EnableTelemetry) }},
+	{"LogBodies", "XAI_LOG_BODIES", false, func(c *Config) string { return strconv.FormatBool(c.LogBodies) }},
+}
+
+// snapshot returns the values of the explained fields.
+func (c *Config) snapshot() map[string]string {
+	values := make(map[string]string, len(settings))
+	for _, s := range settings {
+		values[s.name] = s.value(c)
+	}
+	return values
+}
+
+// track records source as the source of the values changed since before.
+func (c *Config) track(before map[string]string, source Source, origin func(setting) string) {
+	// Configs copied by value share the map, so it is replaced, not updated
+	sources := maps.Clone(c.sources)
+	if sources == nil {
+		sources = map[string]valueSource{}
+	}
+	for _, s := range settings {
+		if value := s.value(c); value != before[s.name] {
+			sources[s.name] = valueSource{source: source, origin: origin(s), value: value}
+		}
+	}
+	c.sources = sources
+}
+
+// sourceOf returns the recorded source of a field, or "" if none.
+func (c *Config) sourceOf(name string) Source {
+	return c.sources[name].source
+}
+
+// forgetSource removes the recorded source of a field.
+func (c *Config) forgetSource(name string) {
+	sources := maps.Clone(c.sources)
+	delete(sources, name)
+	c.sources = sources
+}
+
+// maskAPIKey masks all but the last 8 characters of an API key, or all of a
+// shorter one.
+func maskAPIKey(key string) string {
+	if len(key) > 8 {
+		return strings.Repeat("*", len(key)-8) + key[len(key)-8:]
+	}
+	return strings.Repeat("*", len(key))
+}
@@ -60,6 +60,7 @@ func TestContent(t *testing.T) {
 		if err != nil {
 			t.Fatalf("protojson.Marshal() error = %v", err)
 		}
+		w.Header().Set("Content-Type", "application/json")
 		w.Write(data)
 	}))
 	defer server.Close()
@@ -101,6 +102,52 @@ func TestListWithFilter(t *testing.T) {
 	}
 }
 
+func TestAllFollowsPagination(t *testing.T) {
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req xaiv1.ListFilesRequest
+		if err := protojson.Unmarshal(body, &req); err != nil {
+			t.Fatalf("protojson.Unmarshal() error = %v", err)
+		}
+		tokens = append(tokens, req.PaginationToken)
+		resp := &xaiv1.ListFilesResponse{Data: []*xaiv1.File{{Id: "file-1"}, {Id: "file-2"}}, PaginationToken: "page-2"}
+		if req.PaginationToken == "page-2" {
+			resp = &xaiv1.ListFilesResponse{Data: []*xaiv1.File{{Id: "file-3"}}}
+		}
+		data, _ := protojson.Marshal(resp)
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	client := NewClient(rest.NewClient(rest.Config{BaseURL: server.URL, APIKey: "test"}))
+	opts := &ListOptions{Limit: 2}
+	var ids []string
+	for file, err := range client.All(context.Background(), opts) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		ids = append(ids, file.ID)
+	}
+	if len(ids) != 3 || ids[2] != "file-3" {
+		t.Errorf("ids = %v", ids)
+	}
+	if len(tokens) != 2 || tokens[1] != "page-2" {
+		t.Errorf("tokens = %v", tokens)
+	}
+	if opts.PaginationToken != "" {
+		t.Error("All() must not modify the caller's options")
+	}
+
+	tokens = nil
+	for range client.All(context.Background(), opts) {
+		break
+	}
+	if len(tokens) != 1 {
+		t.Errorf("early break fetched %d pages, want 1", len(tokens))
+	}
+}
+
 func TestPublicURLMethods(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch {
@@ -0,0 +1,367 @@
+package files
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/rest"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/retry"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// DefaultMaxResumes is how many times an interrupted download is resumed.
+const DefaultMaxResumes = 3
+
+// DownloadOptions configures DownloadTo.
+type DownloadOptions struct {
+	// SHA256 is the expected hex SHA-256 of the content, such as File.SHA256
+	// of an upload. If empty, the digest sent by the server, if any, is used.
+	SHA256 string
+	// MaxResumes is how many times a download interrupted by a network error
+	// is resumed (default: DefaultMaxResumes). Use a negative value to never
+	// resume.
+	MaxResumes int
+	// Progress, if set, is called after each chunk is written.
+	Progress func(DownloadProgress)
+}
+
+// DownloadProgress reports the progress of a download.
+type DownloadProgress struct {
+	// BytesReceived is the number of bytes written so far.
+	BytesReceived int64
+	// TotalBytes is the size of the content, or 0 if unknown.
+	TotalBytes int64
+}
+
+// DownloadResult describes a completed download.
+type DownloadResult struct {
+	// Size is the number of bytes written.
+	Size int64
+	// SHA256 is the hex SHA-256 of the content written.
+	SHA256 string
+	// Resumes is the number of times the download was resumed.
+	Resumes int
+}
+
+// resumePolicy paces the resumption of interrupted downloads.
+var resumePolicy = retry.Policy{InitialBackoff: 500 * time.Millisecond, MaxBackoff: 10 * time.Second}
+
+// DownloadTo streams the content of a file to w without holding it in
+// memory. The content is read from the REST API, whose download is resumed
+// from the last byte written with an HTTP Range request after a network
+// error, or from the gRPC GetFileContent stream if the client has no REST
+// client. The size and SHA-256 of the content are verified when known,
+// returning ErrIncompleteDownload or ErrChecksumMismatch; w may then hold
+// partial or corrupt content. opts may be nil.
+func (c *Client) DownloadTo(ctx context.Context, fileID string, w io.Writer, opts *DownloadOptions) (*DownloadResult, error) {
+	if c.restClient == nil && c.grpcClient == nil {
+		return nil, ErrClientNotInitialized
+	}
+	var o DownloadOptions
+	if opts != nil {
+		o = *opts
+	}
+	policy := resumePolicy
+	policy.MaxRetries = o.MaxResumes
+	if o.MaxResumes == 0 {
+		policy.MaxRetries = DefaultMaxResumes
+	}
+
+	d := &download{w: w, hash: sha256.New(), progress: o.Progress, expected: strings.ToLower(o.SHA256)}
+	attempts, err := c.downloadResuming(ctx, fileID, d, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DownloadResult{Size: d.written, SHA256: hex.EncodeToString(d.hash.Sum(nil)), Resumes: attempts - 1}
+	if d.total > 0 && d.written != d.total {
+		return result, fmt.Errorf("%w: received %d of %d bytes", ErrIncompleteDownload, d.written, d.total)
+	}
+	if d.expected != "" && d.expected != result.SHA256 {
+		return result, fmt.Errorf("%w: got %s, want %s", ErrChecksumMismatch, result.SHA256, d.expected)
+	}
+	return result, nil
+}
+
+// Download returns a reader streaming the content of a file; see DownloadTo.
+// Closing the reader aborts the download. If the content cannot be verified,
+// the last Read returns the error.
+func (c *Client) Download(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	if c.restClient == nil && c.grpcClient == nil {
+		return nil, ErrClientNotInitialized
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	w := &startWriter{w: pw, started: make(chan struct{})}
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.DownloadTo(ctx, fileID, w, nil)
+		done <- err
+		w.start()
+		_ = pw.CloseWithError(err)
+	}()
+
+	// Errors before the first byte, such as an unknown file, are returned
+	// here rather than by Read
+	<-w.started
+	select {
+	case err := <-done:
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+	default:
+	}
+	return &downloadReader{PipeReader: pr, cancel: cancel}, nil
+}
+
+// download is the state of a download across resumes.
+type download struct {
+	w        io.Writer
+	hash     hash.Hash
+	progress func(DownloadProgress)
+	expected string
+
+	written int64
+	total   int64
+}
+
+// writeError is an error of the destination writer, which is not resumed.
+type writeError struct{ err error }
+
+func (e *writeError) Error() string { return e.err.Error() }
+func (e *writeError) Unwrap() error { return e.err }
+
+// Write writes p to the destination, hashing it and reporting progress.
+func (d *download) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	d.hash.Write(p[:n])
+	d.written += int64(n)
+	if n > 0 && d.progress != nil {
+		d.progress(DownloadProgress{BytesReceived: d.written, TotalBytes: d.total})
+	}
+	if err != nil {
+		return n, &writeError{err: err}
+	}
+	return n, nil
+}
+
+// classify reports whether an interrupted download is resumed: after
+// network errors and transient gRPC errors, but not after REST API errors,
+// which the REST client already retried, or errors of the destination.
+func (d *download) classify(err error) (bool, time.Duration) {
+	var we *writeError
+	var httpErr *rest.HTTPError
+	if errors.As(err, &we) || errors.As(err, &httpErr) || errors.Is(err, ErrIncompleteDownload) {
+		return false, 0
+	}
+	if _, ok := status.FromError(err); ok {
+		return retry.ClassifyGRPC(err, nil, true)
+	}
+	return retry.ClassifyNetwork(err, true), 0
+}
+
+// downloadResuming downloads the content, resuming it after interruptions
+// according to policy, and returns the number of attempts.
+func (c *Client) downloadResuming(ctx context.Context, fileID string, d *download, policy retry.Policy) (int, error) {
+	attempts := 0
+	err := retry.Do(ctx, policy, d.classify, func() error {
+		attempts++
+		if c.restClient != nil {
+			return c.downloadREST(ctx, fileID, d)
+		}
+		return c.downloadGRPC(ctx, fileID, d)
+	})
+	return attempts, err
+}
+
+// downloadREST downloads the rest of the content, from d.written on.
+func (c *Client) downloadREST(ctx context.Context, fileID string, d *download) error {
+	req := rest.Request{Method: http.MethodGet, Path: fmt.Sprintf("/files/%s/content", fileID)}
+	if d.written > 0 {
+		req.Headers = map[string]string{"Range": fmt.Sprintf("bytes=%d-", d.written)}
+	}
+	resp, err := c.restClient.Stream(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	skip, err := d.resumeAt(resp)
+	if err != nil {
+		return err
+	}
+
+	// The content may also come as a JSON FileContentChunk, which cannot be
+	// requested by range
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/json" {
+		return d.writeContentChunk(resp.Body, skip)
+	}
+
+	// The server ignored the range: skip what was already written
+	if skip > 0 {
+		if _, err := io.CopyN(io.Discard, resp.Body, skip); err != nil {
+			return err
+		}
+	}
+	_, err = io.Copy(d, resp.Body)
+	return err
+}
+
+// resumeAt checks the response to a resumed request and returns the number of
+// bytes to skip, which is d.written if the server ignored the range. It also
+// records the total size and the expected SHA-256 announced by the server.
+func (d *download) resumeAt(resp *http.Response) (int64, error) {
+	skip := d.written
+	if resp.StatusCode == http.StatusPartialContent {
+		start, total, ok := contentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != d.written {
+			return 0, fmt.Errorf("%w: unexpected Content-Range %q", ErrIncompleteDownload, resp.Header.Get("Content-Range"))
+		}
+		skip = 0
+		if total > 0 {
+			d.total = total
+		}
+	} else if resp.ContentLength > 0 {
+		d.total = resp.ContentLength
+	}
+	if d.expected == "" && d.written == 0 {
+		d.expected = digestSHA256(resp.Header)
+	}
+	return skip, nil
+}
+
+// writeContentChunk writes the content of a JSON FileContentChunk, skipping
+// its first skip bytes.
+func (d *download) writeContentChunk(body io.Reader, skip int64) error {
+	data, err := io.ReadAll(io.LimitReader(body, rest.MaxResponseSize))
+	if err != nil {
+		return err
+	}
+	var chunk xaiv1.FileContentChunk
+	if err := protojson.Unmarshal(data, &chunk); err != nil {
+		return err
+	}
+	d.total = int64(len(chunk.Data))
+	if skip > d.total {
+		return fmt.Errorf("%w: content shrank to %d bytes", ErrIncompleteDownload, d.total)
+	}
+	_, err = d.Write(chunk.Data[skip:])
+	return err
+}
+
+// downloadGRPC downloads the content from the GetFileContent stream. The
+// stream cannot start at an offset, so a resumed stream skips what was
+// already written.
+func (c *Client) downloadGRPC(ctx context.Context, fileID string, d *download) error {
+	stream, err := c.grpcClient.GetFileContent(ctx, &xaiv1.GetFileContentRequest{FileId: fileID})
+	if err != nil {
+		return err
+	}
+	skip := d.written
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		data := chunk.GetData()
+		n := min(skip, int64(len(data)))
+		skip -= n
+		if _, err := d.Write(data[n:]); err != nil {
+			return err
+		}
+	}
+}
+
+// contentRange parses a "bytes start-end/total" Content-Range; total is 0
+// if unknown.
+func contentRange(value string) (start, total int64, ok bool) {
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	span, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	first, _, ok := strings.Cut(span, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, total, true
+}
+
+// digestSHA256 returns the hex SHA-256 of a Repr-Digest (RFC 9530) or Digest
+// (RFC 3230) header, or "" if there is none.
+func digestSHA256(header http.Header) string {
+	for _, field := range []string{"Repr-Digest", "Digest"} {
+		for _, value := range strings.Split(header.Get(field), ",") {
+			algorithm, digest, ok := strings.Cut(strings.TrimSpace(value), "=")
+			if !ok || !strings.EqualFold(algorithm, "sha-256") {
+				continue
+			}
+			sum, err := base64.StdEncoding.DecodeString(strings.Trim(digest, ":"))
+			if err == nil && len(sum) == sha256.Size {
+				return hex.EncodeToString(sum)
+			}
+		}
+	}
+	return ""
+}
+
+// startWriter signals the first write, or the end of a write-less download.
+type startWriter struct {
+	w       io.Writer
+	started chan struct{}
+	once    sync.Once
+}
+
+func (w *startWriter) Write(p []byte) (int, error) {
+	w.start()
+	return w.w.Write(p)
+}
+
+func (w *startWriter) start() {
+	w.once.Do(func() { close(w.started) })
+}
+
+// downloadReader is the reader returned by Download.
+type downloadReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+// Close aborts the download.
+func (r *downloadReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
@@ -0,0 +1,209 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/rest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var downloadContent = strings.Repeat("0123456789", 100)
+
+func init() {
+	resumePolicy.InitialBackoff = time.Millisecond
+}
+
+// contentServer serves downloadContent, cutting the connection after half of
+// the content for the first cut requests; honorRange controls whether Range
+// requests get partial content.
+func contentServer(t *testing.T, cut int32, honorRange bool) (*Client, *[]string) {
+	t.Helper()
+	var calls atomic.Int32
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/files/file-1/content" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"file not found"}`))
+			return
+		}
+		ranges = append(ranges, r.Header.Get("Range"))
+		content := downloadContent
+		var start int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start); err == nil && honorRange {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)-start))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write([]byte(content[start:]))
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		if calls.Add(1) <= cut {
+			_, _ = w.Write([]byte(content[:len(content)/2]))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+	return NewClient(rest.NewClient(rest.Config{BaseURL: server.URL, APIKey: "test"})), &ranges
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadToResumesWithRange(t *testing.T) {
+	client, ranges := contentServer(t, 1, true)
+
+	var out bytes.Buffer
+	var last DownloadProgress
+	result, err := client.DownloadTo(context.Background(), "file-1", &out, &DownloadOptions{
+		SHA256:   sha256Hex(downloadContent),
+		Progress: func(p DownloadProgress) { last = p },
+	})
+	if err != nil {
+		t.Fatalf("DownloadTo() error = %v", err)
+	}
+	if out.String() != downloadContent {
+		t.Errorf("downloaded %d bytes, content differs", out.Len())
+	}
+	if result.Resumes != 1 || result.Size != 1000 || result.SHA256 != sha256Hex(downloadContent) {
+		t.Errorf("result = %+v", result)
+	}
+	if len(*ranges) != 2 || (*ranges)[1] != "bytes=500-" {
+		t.Errorf("ranges = %q, want a resume from byte 500", *ranges)
+	}
+	if last != (DownloadProgress{BytesReceived: 1000, TotalBytes: 1000}) {
+		t.Errorf("progress = %+v", last)
+	}
+}
+
+func TestDownloadToRangeIgnored(t *testing.T) {
+	client, _ := contentServer(t, 1, false)
+
+	var out bytes.Buffer
+	result, err := client.DownloadTo(context.Background(), "file-1", &out, nil)
+	if err != nil {
+		t.Fatalf("DownloadTo() error = %v", err)
+	}
+	if out.String() != downloadContent || result.Resumes != 1 {
+		t.Errorf("downloaded %d bytes after %d resumes", out.Len(), result.Resumes)
+	}
+}
+
+func TestDownloadToNoResume(t *testing.T) {
+	client, _ := contentServer(t, 1, true)
+
+	_, err := client.DownloadTo(context.Background(), "file-1", io.Discard, &DownloadOptions{MaxResumes: -1})
+	if err == nil {
+		t.Fatal("DownloadTo() should fail without resuming")
+	}
+}
+
+func TestDownloadToChecksum(t *testing.T) {
+	client, _ := contentServer(t, 0, true)
+
+	_, err := client.DownloadTo(context.Background(), "file-1", io.Discard, &DownloadOptions{SHA256: sha256Hex("other content")})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("error = %v, want ErrChecksumMismatch", err)
+	}
+
+	// The digest of the server is verified when no SHA-256 is given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		sum := sha256.Sum256([]byte("other content"))
+		w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+		_, _ = w.Write([]byte(downloadContent))
+	}))
+	defer server.Close()
+	client = NewClient(rest.NewClient(rest.Config{BaseURL: server.URL}))
+	if _, err := client.DownloadTo(context.Background(), "file-1", io.Discard, nil); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("error = %v, want ErrChecksumMismatch from Repr-Digest", err)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	client, _ := contentServer(t, 1, true)
+
+	reader, err := client.Download(context.Background(), "file-1")
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil || string(content) != downloadContent {
+		t.Errorf("read %d bytes, %v", len(content), err)
+	}
+	if err := reader.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	// Errors before the content are returned by Download
+	if _, err := client.Download(context.Background(), "missing"); err == nil {
+		t.Error("Download() of a missing file should fail")
+	}
+}
+
+// contentStream is a GetFileContent stream sending chunks, then failing with
+// err if set.
+type contentStream struct {
+	grpc.ClientStream
+	chunks []string
+	err    error
+}
+
+func (s *contentStream) Recv() (*xaiv1.FileContentChunk, error) {
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return &xaiv1.FileContentChunk{Data: []byte(chunk)}, nil
+}
+
+// contentClient answers GetFileContent with streams.
+type contentClient struct {
+	xaiv1.FilesClient
+	streams []*contentStream
+}
+
+func (c *contentClient) GetFileContent(context.Context, *xaiv1.GetFileContentRequest, ...grpc.CallOption) (grpc.ServerStreamingClient[xaiv1.FileContentChunk], error) {
+	stream := c.streams[0]
+	c.streams = c.streams[1:]
+	return stream, nil
+}
+
+func TestDownloadToGRPC(t *testing.T) {
+	grpcClient := &contentClient{streams: []*contentStream{
+		{chunks: []string{"Hello, "}, err: status.Error(codes.Unavailable, "connection reset")},
+		{chunks: []string{"Hel", "lo, world"}},
+	}}
+
+	var out bytes.Buffer
+	result, err := NewClientWithGRPC(nil, grpcClient).DownloadTo(context.Background(), "file-1", &out, nil)
+	if err != nil {
+		t.Fatalf("DownloadTo() error = %v", err)
+	}
+	if out.String() != "Hello, world" || result.Resumes != 1 {
+		t.Errorf("downloaded %q after %d resumes", out.String(), result.Resumes)
+	}
+}
@@ -10,3 +10,15 @@ var ErrClientNotInitialized = errors.New("files client not initialized: REST cli
 
 // ErrFileTooLarge is returned when a file exceeds the maximum allowed size.
 var ErrFileTooLarge = errors.New("file size exceeds maximum allowed size")
+
+// ErrIncompleteDownload is returned when a download ends before the full
+// content was received.
+var ErrIncompleteDownload = errors.New("download ended before the full content was received")
+
+// ErrChecksumMismatch is returned when downloaded content does not match its
+// expected SHA-256.
+var ErrChecksumMismatch = errors.New("downloaded content does not match the expected SHA-256")
+
+// ErrNoCriteria is returned by a Janitor without selection criteria, which
+// would select every file.
+var ErrNoCriteria = errors.New("janitor has no selection criteria")
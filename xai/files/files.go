@@ -2,15 +2,18 @@
 package files
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"iter"
 	"sync"
 	"time"
 
 	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/constants"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/pager"
 	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/rest"
 	"google.golang.org/protobuf/encoding/protojson"
 )
@@ -18,6 +21,7 @@ import (
 // Client provides access to the xAI Files API.
 type Client struct {
 	restClient *rest.Client
+	grpcClient xaiv1.FilesClient
 }
 
 // NewClient creates a new Files API client.
@@ -27,6 +31,15 @@ func NewClient(restClient *rest.Client) *Client {
 	}
 }
 
+// NewClientWithGRPC creates a Files API client that streams uploads over the
+// gRPC Files service.
+func NewClientWithGRPC(restClient *rest.Client, grpcClient xaiv1.FilesClient) *Client {
+	return &Client{
+		restClient: restClient,
+		grpcClient: grpcClient,
+	}
+}
+
 // File represents a file with metadata.
 type File struct {
 	ID                 string
@@ -37,6 +50,10 @@ type File struct {
 	TeamID             string
 	PublicURL          string
 	PublicURLExpiresAt time.Time
+
+	// SHA256 is the hex SHA-256 of the content, computed while uploading;
+	// it is only set on the File returned by Upload.
+	SHA256 string
 }
 
 // ListOptions contains options for listing files.
@@ -60,6 +77,28 @@ type UploadOptions struct {
 	Purpose string
 	// MaxSize is the maximum file size in bytes. If 0, defaults to DefaultMaxFileSize (100MB).
 	MaxSize int64
+	// Size is the size of the content reported to Progress, if known. It
+	// defaults to the remaining length of *os.File, *bytes.Reader,
+	// *bytes.Buffer and *strings.Reader readers.
+	Size int64
+	// ChunkSize is the size of the chunks of a streamed upload (default:
+	// DefaultChunkSize).
+	ChunkSize int
+	// Progress, if set, is called after each chunk is sent.
+	Progress func(UploadProgress)
+}
+
+// initProto returns the UploadFileInit describing the upload.
+func (o *UploadOptions) initProto() *xaiv1.UploadFileInit {
+	return &xaiv1.UploadFileInit{Name: o.Name, Purpose: o.Purpose}
+}
+
+// UploadProgress reports the progress of an upload.
+type UploadProgress struct {
+	// BytesSent is the number of bytes sent so far.
+	BytesSent int64
+	// TotalBytes is the size of the content, or 0 if unknown.
+	TotalBytes int64
 }
 
 // PublicURLOptions configures public URL creation for stored generated assets.
@@ -134,8 +173,14 @@ func fromProto(pf *xaiv1.File) *File {
 	return f
 }
 
-// Upload uploads a file.
+// Upload uploads a file. With a gRPC connection, the content is streamed in
+// chunks of opts.ChunkSize without being held in memory; otherwise it is sent
+// in a single REST request. The SHA-256 of the content is computed while
+// uploading and set on the returned File. Canceling ctx aborts the upload.
 func (c *Client) Upload(ctx context.Context, reader io.Reader, opts UploadOptions) (*File, error) {
+	if c.grpcClient != nil {
+		return c.uploadStream(ctx, reader, opts)
+	}
 	if c.restClient == nil {
 		return nil, ErrClientNotInitialized
 	}
@@ -149,8 +194,9 @@ func (c *Client) Upload(ctx context.Context, reader io.Reader, opts UploadOption
 	// Limit file size to prevent memory exhaustion
 	limitedReader := io.LimitReader(reader, maxSize+1)
 
-	// Read file content
-	content, err := io.ReadAll(limitedReader)
+	// Read file content, hashing it on the way
+	hash := sha256.New()
+	content, err := io.ReadAll(io.TeeReader(limitedReader, hash))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file content: %w", err)
 	}
@@ -162,10 +208,7 @@ func (c *Client) Upload(ctx context.Context, reader io.Reader, opts UploadOption
 
 	// Create upload request
 	req := &xaiv1.UploadFileChunk{
-		Init: &xaiv1.UploadFileInit{
-			Name:    opts.Name,
-			Purpose: opts.Purpose,
-		},
+		Init: opts.initProto(),
 		Data: content,
 	}
 
@@ -178,36 +221,21 @@ func (c *Client) Upload(ctx context.Context, reader io.Reader, opts UploadOption
 	if err != nil {
 		return nil, err
 	}
+	if opts.Progress != nil {
+		opts.Progress(UploadProgress{BytesSent: int64(len(content)), TotalBytes: int64(len(content))})
+	}
 
 	var file xaiv1.File
 	if err := protojson.Unmarshal(resp.Body, &file); err != nil {
 		return nil, err
 	}
 
-	return fromProto(&file), nil
-}
-
-// Download downloads a file's content.
-func (c *Client) Download(ctx context.Context, fileID string) (io.ReadCloser, error) {
-	if c.restClient == nil {
-		return nil, ErrClientNotInitialized
-	}
-
-	resp, err := c.restClient.Get(ctx, fmt.Sprintf("/files/%s/content", fileID))
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse the response to get file chunks
-	var chunks xaiv1.FileContentChunk
-	if err := protojson.Unmarshal(resp.Body, &chunks); err != nil {
-		return nil, err
-	}
-
-	// Return the data as a ReadCloser
-	return io.NopCloser(bytes.NewReader(chunks.Data)), nil
+	f := fromProto(&file)
+	f.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return f, nil
 }
 
+// Content returns the content of a file, read into memory.
 func (c *Client) Content(ctx context.Context, fileID string) ([]byte, error) {
 	reader, err := c.Download(ctx, fileID)
 	if err != nil {
@@ -262,6 +290,24 @@ func (c *Client) List(ctx context.Context, opts *ListOptions) (*ListResult, erro
 	}, nil
 }
 
+// All returns an iterator over every file matching opts, transparently following
+// pagination tokens. opts.Limit sets the page size and opts.PaginationToken the
+// starting page. Iteration stops at the first error or when the loop breaks.
+func (c *Client) All(ctx context.Context, opts *ListOptions) iter.Seq2[*File, error] {
+	var pageOpts ListOptions
+	if opts != nil {
+		pageOpts = *opts
+	}
+	return pager.All(ctx, pageOpts.PaginationToken, func(ctx context.Context, token string) ([]*File, string, error) {
+		pageOpts.PaginationToken = token
+		result, err := c.List(ctx, &pageOpts)
+		if err != nil {
+			return nil, "", err
+		}
+		return result.Files, result.PaginationToken, nil
+	})
+}
+
 // CreatePublicURL creates a publicly shareable URL for a stored file.
 func (c *Client) CreatePublicURL(ctx context.Context, fileID string, expiresAfter time.Duration) (*xaiv1.CreatePublicUrlResponse, error) {
 	if c.restClient == nil {
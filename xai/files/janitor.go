@@ -0,0 +1,215 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultJanitorConcurrency is the default maximum number of concurrent
+// requests of a Janitor.
+const DefaultJanitorConcurrency = 10
+
+// JanitorOptions selects the files cleaned up by a Janitor. A file is
+// selected only if it matches every criterion set; at least one must be set.
+type JanitorOptions struct {
+	// OlderThan selects the files created longer ago than this.
+	OlderThan time.Duration
+	// Purpose selects the files uploaded with this purpose. Files do not
+	// report their purpose, so it is matched by the server as the listing
+	// filter "purpose:<Purpose>", combined with Filter.
+	Purpose string
+	// Filter is a server-side filter of the listing, as ListOptions.Filter.
+	Filter string
+	// Names lists glob patterns of file names, as SyncOptions.Include.
+	Names []string
+	// Referenced, if set, returns the IDs of the files in use, such as
+	// collections.Client.ReferencedFiles; the files it returns are never
+	// selected.
+	Referenced func(ctx context.Context) (map[string]bool, error)
+	// Match, if set, selects the files for which it returns true.
+	Match func(*File) bool
+
+	// DryRun reports the selected files without changing them.
+	DryRun bool
+	// Concurrency is the maximum number of concurrent requests (default:
+	// DefaultJanitorConcurrency).
+	Concurrency int
+}
+
+// Janitor cleans up stale files in bulk: it lists every file, selects those
+// matching its options and deletes them or revokes their public URLs.
+type Janitor struct {
+	client *Client
+	opts   JanitorOptions
+}
+
+// JanitorFailure is a selected file that could not be cleaned up.
+type JanitorFailure struct {
+	File *File
+	Err  error
+}
+
+// JanitorReport summarizes a cleanup.
+type JanitorReport struct {
+	// DryRun reports whether the selected files were left unchanged.
+	DryRun bool
+	// Scanned is the number of files listed.
+	Scanned int
+	// Selected are the files matching the criteria, in listing order.
+	Selected []*File
+	// SelectedBytes is the total size of the selected files.
+	SelectedBytes int64
+	// Cleaned are the selected files deleted, or whose public URL was
+	// revoked; it is empty in a dry run.
+	Cleaned []*File
+	// Failed are the selected files that could not be cleaned up.
+	Failed []JanitorFailure
+}
+
+// String returns a one-line summary of the report.
+func (r *JanitorReport) String() string {
+	if r.DryRun {
+		return fmt.Sprintf("dry run: %d of %d files selected (%d bytes)", len(r.Selected), r.Scanned, r.SelectedBytes)
+	}
+	return fmt.Sprintf("%d of %d files selected (%d bytes): %d cleaned, %d failed",
+		len(r.Selected), r.Scanned, r.SelectedBytes, len(r.Cleaned), len(r.Failed))
+}
+
+// Janitor returns a Janitor cleaning up the files selected by opts.
+func (c *Client) Janitor(opts JanitorOptions) *Janitor {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultJanitorConcurrency
+	}
+	return &Janitor{client: c, opts: opts}
+}
+
+// Delete deletes the selected files. It returns an error if the files
+// cannot be listed; failures of individual files are listed in the report.
+func (j *Janitor) Delete(ctx context.Context) (*JanitorReport, error) {
+	return j.run(ctx, func(*File) bool { return true }, func(ctx context.Context, f *File) error {
+		return j.client.Delete(ctx, f.ID)
+	})
+}
+
+// RevokePublicURLs revokes the public URLs of the selected files that have
+// one, leaving the files themselves in place.
+func (j *Janitor) RevokePublicURLs(ctx context.Context) (*JanitorReport, error) {
+	return j.run(ctx, func(f *File) bool { return f.PublicURL != "" }, func(ctx context.Context, f *File) error {
+		_, err := j.client.RevokePublicURL(ctx, f.ID)
+		return err
+	})
+}
+
+// run applies clean to the selected files accepted by eligible.
+func (j *Janitor) run(ctx context.Context, eligible func(*File) bool, clean func(context.Context, *File) error) (*JanitorReport, error) {
+	report, err := j.selectFiles(ctx, eligible)
+	if err != nil || report.DryRun {
+		return report, err
+	}
+
+	errs := make([]error, len(report.Selected))
+	sem := make(chan struct{}, j.opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, f := range report.Selected {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if errs[i] = ctx.Err(); errs[i] == nil {
+				errs[i] = clean(ctx, f)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, f := range report.Selected {
+		if errs[i] != nil {
+			report.Failed = append(report.Failed, JanitorFailure{File: f, Err: errs[i]})
+		} else {
+			report.Cleaned = append(report.Cleaned, f)
+		}
+	}
+	return report, nil
+}
+
+// selectFiles lists every file and selects those matching the options.
+func (j *Janitor) selectFiles(ctx context.Context, eligible func(*File) bool) (*JanitorReport, error) {
+	m, err := j.newMatcher(ctx, eligible)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &JanitorReport{DryRun: j.opts.DryRun}
+	for f, err := range j.client.All(ctx, &ListOptions{Filter: m.listFilter()}) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+		report.Scanned++
+		if m.matches(f) {
+			report.Selected = append(report.Selected, f)
+			report.SelectedBytes += f.Size
+		}
+	}
+	return report, nil
+}
+
+// fileMatcher tests the listed files against the options of a Janitor.
+type fileMatcher struct {
+	opts       JanitorOptions
+	cutoff     time.Time
+	referenced map[string]bool
+	eligible   func(*File) bool
+}
+
+// newMatcher checks that the options set a criterion and loads the
+// referenced files.
+func (j *Janitor) newMatcher(ctx context.Context, eligible func(*File) bool) (*fileMatcher, error) {
+	o := j.opts
+	if o.OlderThan <= 0 && o.Purpose == "" && o.Filter == "" && len(o.Names) == 0 && o.Referenced == nil && o.Match == nil {
+		return nil, ErrNoCriteria
+	}
+	m := &fileMatcher{opts: o, cutoff: time.Now().Add(-o.OlderThan), eligible: eligible}
+	if o.Referenced != nil {
+		referenced, err := o.Referenced(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list referenced files: %w", err)
+		}
+		m.referenced = referenced
+	}
+	return m, nil
+}
+
+// listFilter returns the server-side filter of the listing, selecting the
+// files of the Purpose and Filter criteria.
+func (m *fileMatcher) listFilter() string {
+	switch {
+	case m.opts.Purpose == "":
+		return m.opts.Filter
+	case m.opts.Filter == "":
+		return "purpose:" + m.opts.Purpose
+	default:
+		return "purpose:" + m.opts.Purpose + " AND (" + m.opts.Filter + ")"
+	}
+}
+
+// matches reports whether f matches every criterion. The Purpose and Filter
+// criteria are applied by the listing, see listFilter.
+func (m *fileMatcher) matches(f *File) bool {
+	o := m.opts
+	if o.OlderThan > 0 && (f.CreatedAt.IsZero() || !f.CreatedAt.Before(m.cutoff)) {
+		return false
+	}
+	if len(o.Names) > 0 && !matchAny(o.Names, f.Filename) {
+		return false
+	}
+	if m.referenced[f.ID] {
+		return false
+	}
+	if o.Match != nil && !o.Match(f) {
+		return false
+	}
+	return m.eligible(f)
+}
@@ -0,0 +1,112 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// seedFiles stores files created age ago on the fake server.
+func seedFiles(fs *filesServer, files map[string]time.Duration) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for name, age := range files {
+		id := "file-" + strings.TrimSuffix(name, ".txt")
+		fs.files[id] = &xaiv1.File{Id: id, Filename: name, Size: 10, CreatedAt: timestamppb.New(time.Now().Add(-age))}
+	}
+}
+
+func TestJanitorDelete(t *testing.T) {
+	server, client := newFilesServer(t)
+	seedFiles(server, map[string]time.Duration{
+		"old.txt":        48 * time.Hour,
+		"old-in-use.txt": 48 * time.Hour,
+		"new.txt":        time.Minute,
+		"old.log":        48 * time.Hour,
+	})
+	opts := JanitorOptions{
+		OlderThan: 24 * time.Hour,
+		Names:     []string{"*.txt"},
+		Referenced: func(context.Context) (map[string]bool, error) {
+			return map[string]bool{"file-old-in-use": true}, nil
+		},
+		DryRun: true,
+	}
+
+	report, err := client.Janitor(opts).Delete(context.Background())
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if report.Scanned != 4 || len(report.Selected) != 1 || report.Selected[0].ID != "file-old" || len(report.Cleaned) != 0 {
+		t.Errorf("dry run report = %+v", report)
+	}
+	if len(server.names()) != 4 {
+		t.Error("dry run deleted files")
+	}
+
+	opts.DryRun = false
+	report, err = client.Janitor(opts).Delete(context.Background())
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if len(report.Cleaned) != 1 || len(report.Failed) != 0 || report.SelectedBytes != 10 {
+		t.Errorf("report = %+v", report)
+	}
+	if names := server.names(); len(names) != 3 || names["old.txt"] {
+		t.Errorf("remote files = %v", names)
+	}
+	if got := report.String(); got != "1 of 4 files selected (10 bytes): 1 cleaned, 0 failed" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestJanitorNoCriteria(t *testing.T) {
+	_, client := newFilesServer(t)
+	if _, err := client.Janitor(JanitorOptions{DryRun: true}).Delete(context.Background()); !errors.Is(err, ErrNoCriteria) {
+		t.Errorf("error = %v, want ErrNoCriteria", err)
+	}
+}
+
+func TestJanitorPurpose(t *testing.T) {
+	server, client := newFilesServer(t)
+	seedFiles(server, map[string]time.Duration{"batch.jsonl": time.Hour})
+
+	report, err := client.Janitor(JanitorOptions{Purpose: "batch", DryRun: true}).Delete(context.Background())
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if server.filter != "purpose:batch" || len(report.Selected) != 1 {
+		t.Errorf("filter = %q, report = %+v", server.filter, report)
+	}
+
+	opts := JanitorOptions{Purpose: "batch", Filter: "size:>100", DryRun: true}
+	if _, err := client.Janitor(opts).Delete(context.Background()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if want := "purpose:batch AND (size:>100)"; server.filter != want {
+		t.Errorf("filter = %q, want %q", server.filter, want)
+	}
+}
+
+func TestJanitorRevokePublicURLs(t *testing.T) {
+	server, client := newFilesServer(t)
+	seedFiles(server, map[string]time.Duration{"shared.txt": time.Hour, "private.txt": time.Hour})
+	url := "https://files.example/shared.txt"
+	server.files["file-shared"].PublicUrl = &url
+
+	report, err := client.Janitor(JanitorOptions{Names: []string{"*.txt"}}).RevokePublicURLs(context.Background())
+	if err != nil {
+		t.Fatalf("RevokePublicURLs() error = %v", err)
+	}
+	if len(report.Cleaned) != 1 || report.Cleaned[0].ID != "file-shared" {
+		t.Errorf("report = %+v", report)
+	}
+	if server.files["file-shared"].PublicUrl != nil || len(server.names()) != 2 {
+		t.Error("public URL not revoked, or files deleted")
+	}
+}
@@ -0,0 +1,497 @@
+package files
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultManifestName is the name of the manifest written by Sync in the
+// synchronized directory.
+const DefaultManifestName = ".xai-sync.json"
+
+// manifestVersion is the version of the manifest format.
+const manifestVersion = 1
+
+// SyncOptions configures Sync.
+type SyncOptions struct {
+	// Include lists glob patterns of the files synchronized (default: all).
+	// Patterns without a slash match the file name; others match the path
+	// relative to the directory, with slashes, where ** matches any number of
+	// directories, e.g. "docs/**/*.md".
+	Include []string
+	// Exclude lists glob patterns of files and directories left out.
+	Exclude []string
+
+	// Purpose is the purpose of the uploaded files.
+	Purpose string
+	// MaxSize is the maximum size of an uploaded file (default:
+	// DefaultMaxFileSize); larger files are reported as failed.
+	MaxSize int64
+	// Concurrency is the maximum number of concurrent uploads (default: 50).
+	Concurrency int
+
+	// Delete deletes the remote files of local files that were removed or
+	// excluded, and the previous versions of updated files. Only files
+	// recorded in the manifest are ever deleted.
+	Delete bool
+
+	// Manifest is the path of the manifest recording the uploaded files
+	// (default: DefaultManifestName in the directory).
+	Manifest string
+}
+
+// SyncedFile is a local file and its remote copy.
+type SyncedFile struct {
+	// Path is the path relative to the directory, with slashes; it is the
+	// name of the remote file.
+	Path   string
+	FileID string
+	SHA256 string
+	Size   int64
+}
+
+// SyncFailure is a file that could not be synchronized.
+type SyncFailure struct {
+	Path string
+	Err  error
+}
+
+// SyncReport lists the changes made by Sync.
+type SyncReport struct {
+	// Uploaded are the new local files uploaded.
+	Uploaded []SyncedFile
+	// Updated are the changed local files uploaded again.
+	Updated []SyncedFile
+	// Unchanged are the local files whose content was already uploaded.
+	Unchanged []SyncedFile
+	// Deleted are the remote files deleted, with Delete set.
+	Deleted []SyncedFile
+	// Failed are the files that failed to upload or delete.
+	Failed []SyncFailure
+}
+
+// manifest records the files uploaded by Sync.
+type manifest struct {
+	Version int                      `json:"version"`
+	Files   map[string]manifestEntry `json:"files"`
+}
+
+type manifestEntry struct {
+	FileID  string    `json:"file_id"`
+	SHA256  string    `json:"sha256"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	// Stale lists the remote files of previous versions not yet deleted.
+	Stale []string `json:"stale,omitempty"`
+}
+
+// localFile is a file found in the synchronized directory.
+type localFile struct {
+	rel     string
+	path    string
+	size    int64
+	modTime time.Time
+	sha256  string
+}
+
+// Sync mirrors the files of dir into the Files API. Files are hashed and
+// compared with the manifest, which records the SHA-256 and remote file of
+// each file uploaded, and with the remote files listed: files whose content
+// was already uploaded and is still present are skipped, others are uploaded
+// concurrently through BatchUpload. The manifest is saved after each upload,
+// so an interrupted Sync resumes where it stopped.
+//
+// Sync returns an error if the directory cannot be read or the manifest
+// saved; failures of individual files are listed in the report.
+func (c *Client) Sync(ctx context.Context, dir string, opts *SyncOptions) (*SyncReport, error) {
+	var o SyncOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Manifest == "" {
+		o.Manifest = filepath.Join(dir, DefaultManifestName)
+	}
+
+	m, err := loadManifest(o.Manifest)
+	if err != nil {
+		return nil, err
+	}
+	local, err := scanDir(dir, o, m)
+	if err != nil {
+		return nil, err
+	}
+	remote := map[string]bool{}
+	for f, err := range c.All(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list remote files: %w", err)
+		}
+		remote[f.ID] = true
+	}
+
+	report := &SyncReport{}
+	s := &syncer{client: c, opts: o, manifest: m, report: report}
+	var pending []localFile
+	for _, f := range local {
+		entry, ok := m.Files[f.rel]
+		if ok && entry.SHA256 == f.sha256 && remote[entry.FileID] {
+			// The modification time may have changed without the content
+			entry.ModTime = f.modTime
+			m.Files[f.rel] = entry
+			report.Unchanged = append(report.Unchanged, SyncedFile{Path: f.rel, FileID: entry.FileID, SHA256: f.sha256, Size: f.size})
+			continue
+		}
+		pending = append(pending, f)
+	}
+
+	if err := s.upload(ctx, pending, remote); err != nil {
+		return report, err
+	}
+	if o.Delete {
+		s.deleteRemoved(ctx, local, remote)
+	}
+	if err := s.save(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// syncer is the state of a Sync.
+type syncer struct {
+	client *Client
+	opts   SyncOptions
+
+	mu       sync.Mutex
+	manifest *manifest
+	report   *SyncReport
+}
+
+// upload uploads the pending files, recording each one in the manifest.
+func (s *syncer) upload(ctx context.Context, pending []localFile, remote map[string]bool) error {
+	if len(pending) == 0 {
+		return nil
+	}
+	readers := make([]io.Reader, len(pending))
+	uploadOpts := make([]UploadOptions, len(pending))
+	for i, f := range pending {
+		readers[i] = &lazyFile{path: f.path}
+		uploadOpts[i] = UploadOptions{Name: f.rel, Purpose: s.opts.Purpose, MaxSize: s.opts.MaxSize, Size: f.size}
+	}
+
+	var saveErr error
+	_, err := s.client.BatchUpload(ctx, readers, uploadOpts, s.opts.Concurrency, func(i int, reader io.Reader, result interface{}) {
+		_ = reader.(*lazyFile).Close()
+		res := result.(*BatchUploadResult)
+		f := pending[i]
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if res.Error != nil {
+			s.report.Failed = append(s.report.Failed, SyncFailure{Path: f.rel, Err: res.Error})
+			return
+		}
+
+		previous, existed := s.manifest.Files[f.rel]
+		entry := manifestEntry{FileID: res.File.ID, SHA256: res.File.SHA256, Size: f.size, ModTime: f.modTime}
+		if entry.SHA256 == "" {
+			entry.SHA256 = f.sha256
+		}
+		synced := SyncedFile{Path: f.rel, FileID: entry.FileID, SHA256: entry.SHA256, Size: f.size}
+		if existed {
+			entry.Stale = previous.Stale
+			if remote[previous.FileID] {
+				entry.Stale = append(entry.Stale, previous.FileID)
+			}
+		}
+		if existed && previous.SHA256 != entry.SHA256 {
+			s.report.Updated = append(s.report.Updated, synced)
+		} else {
+			s.report.Uploaded = append(s.report.Uploaded, synced)
+		}
+		s.manifest.Files[f.rel] = entry
+		if err := s.saveLocked(); err != nil && saveErr == nil {
+			saveErr = err
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return saveErr
+}
+
+// deleteRemoved deletes the remote files of removed local files and of
+// previous versions of updated files.
+func (s *syncer) deleteRemoved(ctx context.Context, local []localFile, remote map[string]bool) {
+	present := make(map[string]bool, len(local))
+	for _, f := range local {
+		present[f.rel] = true
+	}
+
+	for _, rel := range slices.Sorted(maps.Keys(s.manifest.Files)) {
+		entry := s.manifest.Files[rel]
+		var kept []string
+		for _, id := range entry.Stale {
+			if err := s.deleteRemote(ctx, rel, manifestEntry{FileID: id}, remote); err != nil {
+				kept = append(kept, id)
+			}
+		}
+		entry.Stale = kept
+
+		if !present[rel] && entry.FileID != "" {
+			if err := s.deleteRemote(ctx, rel, entry, remote); err == nil {
+				entry.FileID = ""
+			}
+		}
+		if entry.FileID == "" && len(entry.Stale) == 0 {
+			delete(s.manifest.Files, rel)
+		} else {
+			s.manifest.Files[rel] = entry
+		}
+	}
+}
+
+// deleteRemote deletes the remote file of an entry, unless it is already
+// gone, and reports it.
+func (s *syncer) deleteRemote(ctx context.Context, rel string, entry manifestEntry, remote map[string]bool) error {
+	if !remote[entry.FileID] {
+		return nil
+	}
+	if err := s.client.Delete(ctx, entry.FileID); err != nil {
+		s.report.Failed = append(s.report.Failed, SyncFailure{Path: rel, Err: err})
+		return err
+	}
+	s.report.Deleted = append(s.report.Deleted, SyncedFile{Path: rel, FileID: entry.FileID, SHA256: entry.SHA256, Size: entry.Size})
+	return nil
+}
+
+func (s *syncer) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveLocked()
+}
+
+// saveLocked writes the manifest atomically; s.mu must be held.
+func (s *syncer) saveLocked() error {
+	data, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.opts.Manifest), filepath.Base(s.opts.Manifest)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save sync manifest: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save sync manifest: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save sync manifest: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.opts.Manifest); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save sync manifest: %w", err)
+	}
+	return nil
+}
+
+// loadManifest reads the manifest at path, or returns an empty one if it
+// does not exist.
+func loadManifest(path string) (*manifest, error) {
+	m := &manifest{Version: manifestVersion, Files: map[string]manifestEntry{}}
+	data, err := os.ReadFile(path) //nolint:gosec // the path is chosen by the caller
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid sync manifest %s: %w", path, err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported sync manifest version %d", m.Version)
+	}
+	if m.Files == nil {
+		m.Files = map[string]manifestEntry{}
+	}
+	return m, nil
+}
+
+// scanDir lists and hashes the files of dir selected by the options. The
+// hash recorded in the manifest is reused for files whose size and
+// modification time did not change.
+func scanDir(dir string, o SyncOptions, m *manifest) ([]localFile, error) {
+	manifestPath, err := filepath.Abs(o.Manifest)
+	if err != nil {
+		return nil, err
+	}
+	s := &dirScanner{dir: dir, opts: o, manifest: m, manifestPath: manifestPath}
+	if err := filepath.WalkDir(dir, s.visit); err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	return s.files, nil
+}
+
+// dirScanner collects the files of a synchronized directory.
+type dirScanner struct {
+	dir          string
+	opts         SyncOptions
+	manifest     *manifest
+	manifestPath string
+	files        []localFile
+}
+
+// visit is the filepath.WalkDirFunc of the scan.
+func (s *dirScanner) visit(p string, d fs.DirEntry, err error) error {
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(s.dir, p)
+	if err != nil {
+		return err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		return nil
+	}
+	ok, err := s.selected(p, rel, d)
+	if !ok {
+		return err
+	}
+
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+	f := localFile{rel: rel, path: p, size: info.Size(), modTime: info.ModTime()}
+	if f.sha256, err = s.hash(f); err != nil {
+		return err
+	}
+	s.files = append(s.files, f)
+	return nil
+}
+
+// selected reports whether the entry p is a file to synchronize: a regular
+// file matching the include patterns and none of the exclude patterns, other
+// than the manifest. Excluded directories are skipped with filepath.SkipDir.
+func (s *dirScanner) selected(p, rel string, d fs.DirEntry) (bool, error) {
+	if matchAny(s.opts.Exclude, rel) {
+		if d.IsDir() {
+			return false, filepath.SkipDir
+		}
+		return false, nil
+	}
+	if !d.Type().IsRegular() {
+		return false, nil
+	}
+	if abs, err := filepath.Abs(p); err == nil && abs == s.manifestPath {
+		return false, nil
+	}
+	return len(s.opts.Include) == 0 || matchAny(s.opts.Include, rel), nil
+}
+
+// hash returns the SHA-256 of f, taken from the manifest if the size and
+// modification time of the file did not change.
+func (s *dirScanner) hash(f localFile) (string, error) {
+	if entry, ok := s.manifest.Files[f.rel]; ok && entry.Size == f.size && entry.ModTime.Equal(f.modTime) {
+		return entry.SHA256, nil
+	}
+	return hashFile(f.path)
+}
+
+// hashFile returns the hex SHA-256 of a file.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path) //nolint:gosec // the path is found in the synchronized directory
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// matchAny reports whether rel matches one of the patterns; see
+// SyncOptions.Include.
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+			continue
+		}
+		if matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against pattern segments, where **
+// matches any number of segments.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// lazyFile opens a file on its first read, so that BatchUpload does not hold
+// every file open at once.
+type lazyFile struct {
+	path string
+	file *os.File
+}
+
+func (f *lazyFile) Read(p []byte) (int, error) {
+	if f.file == nil {
+		file, err := os.Open(f.path) //nolint:gosec // the path is found in the synchronized directory
+		if err != nil {
+			return 0, err
+		}
+		f.file = file
+	}
+	return f.file.Read(p)
+}
+
+// Close closes the file if it was opened.
+func (f *lazyFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
@@ -0,0 +1,215 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/rest"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// filesServer is a fake Files REST API storing uploads in memory.
+type filesServer struct {
+	mu      sync.Mutex
+	files   map[string]*xaiv1.File
+	uploads int
+	// fail rejects the uploads of these file names.
+	fail map[string]bool
+	// filter is the filter of the last listing.
+	filter string
+}
+
+func newFilesServer(t *testing.T) (*filesServer, *Client) {
+	t.Helper()
+	fs := &filesServer{files: map[string]*xaiv1.File{}, fail: map[string]bool{}}
+	server := httptest.NewServer(fs)
+	t.Cleanup(server.Close)
+	return fs, NewClient(rest.NewClient(rest.Config{BaseURL: server.URL, APIKey: "test"}))
+}
+
+func (fs *filesServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/files":
+		var chunk xaiv1.UploadFileChunk
+		body, _ := io.ReadAll(r.Body)
+		if err := protojson.Unmarshal(body, &chunk); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if fs.fail[chunk.Init.Name] {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"rejected"}`))
+			return
+		}
+		fs.uploads++
+		f := &xaiv1.File{Id: fmt.Sprintf("file-%d", fs.uploads), Filename: chunk.Init.Name, Size: int64(len(chunk.Data))}
+		fs.files[f.Id] = f
+		data, _ := protojson.Marshal(f)
+		_, _ = w.Write(data)
+	case r.Method == http.MethodPost && r.URL.Path == "/files/list":
+		var req xaiv1.ListFilesRequest
+		body, _ := io.ReadAll(r.Body)
+		_ = protojson.Unmarshal(body, &req)
+		fs.filter = req.GetFilter()
+		resp := &xaiv1.ListFilesResponse{}
+		for _, f := range fs.files {
+			resp.Data = append(resp.Data, f)
+		}
+		data, _ := protojson.Marshal(resp)
+		_, _ = w.Write(data)
+	case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/public-url"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/files/"), "/public-url")
+		fs.files[id].PublicUrl = nil
+		data, _ := protojson.Marshal(&xaiv1.RevokePublicUrlResponse{FileId: id, Revoked: true})
+		_, _ = w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(fs.files, strings.TrimPrefix(r.URL.Path, "/files/"))
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// names returns the names of the stored files.
+func (fs *filesServer) names() map[string]bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	names := map[string]bool{}
+	for _, f := range fs.files {
+		names[f.Filename] = true
+	}
+	return names
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	server, client := newFilesServer(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.txt":          "alpha",
+		"docs/b.md":      "bravo",
+		"docs/debug.log": "noise",
+		"tmp/c.txt":      "charlie",
+	})
+	opts := &SyncOptions{Exclude: []string{"*.log", "tmp"}, Delete: true}
+
+	report, err := client.Sync(context.Background(), dir, opts)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(report.Uploaded) != 2 || len(report.Failed) != 0 {
+		t.Fatalf("first sync: %+v", report)
+	}
+	if names := server.names(); !names["a.txt"] || !names["docs/b.md"] || len(names) != 2 {
+		t.Errorf("remote files = %v", names)
+	}
+	if _, err := os.Stat(filepath.Join(dir, DefaultManifestName)); err != nil {
+		t.Errorf("manifest not written: %v", err)
+	}
+
+	// Nothing changed
+	report, err = client.Sync(context.Background(), dir, opts)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(report.Unchanged) != 2 || len(report.Uploaded)+len(report.Updated) != 0 || server.uploads != 2 {
+		t.Errorf("second sync: %+v after %d uploads", report, server.uploads)
+	}
+
+	// One file changed, one removed
+	writeFiles(t, dir, map[string]string{"a.txt": "alpha, again"})
+	if err := os.Remove(filepath.Join(dir, "docs", "b.md")); err != nil {
+		t.Fatal(err)
+	}
+	report, err = client.Sync(context.Background(), dir, opts)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(report.Updated) != 1 || report.Updated[0].Path != "a.txt" || len(report.Deleted) != 2 {
+		t.Errorf("third sync: %+v", report)
+	}
+	if names := server.names(); !names["a.txt"] || len(names) != 1 {
+		t.Errorf("remote files = %v", names)
+	}
+}
+
+func TestSyncResumes(t *testing.T) {
+	server, client := newFilesServer(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "alpha", "b.txt": "bravo"})
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+
+	server.fail["b.txt"] = true
+	report, err := client.Sync(context.Background(), dir, &SyncOptions{Manifest: manifest})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(report.Uploaded) != 1 || len(report.Failed) != 1 || report.Failed[0].Path != "b.txt" {
+		t.Fatalf("interrupted sync: %+v", report)
+	}
+
+	// Only the failed file is uploaded again
+	server.fail["b.txt"] = false
+	report, err = client.Sync(context.Background(), dir, &SyncOptions{Manifest: manifest})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(report.Uploaded) != 1 || report.Uploaded[0].Path != "b.txt" || len(report.Unchanged) != 1 {
+		t.Errorf("resumed sync: %+v", report)
+	}
+
+	// A remote file deleted elsewhere is uploaded again
+	server.mu.Lock()
+	delete(server.files, report.Uploaded[0].FileID)
+	server.mu.Unlock()
+	report, err = client.Sync(context.Background(), dir, &SyncOptions{Manifest: manifest})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(report.Uploaded) != 1 || report.Uploaded[0].Path != "b.txt" {
+		t.Errorf("restoring sync: %+v", report)
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"*.md", "docs/guide/intro.md", true},
+		{"*.md", "docs/intro.txt", false},
+		{"docs/*.md", "docs/intro.md", true},
+		{"docs/*.md", "docs/guide/intro.md", false},
+		{"docs/**/*.md", "docs/intro.md", true},
+		{"docs/**/*.md", "docs/guide/v1/intro.md", true},
+		{"docs/**", "docs/guide/intro.md", true},
+		{"docs/**", "src/intro.md", false},
+	} {
+		if got := matchAny([]string{tt.pattern}, tt.path); got != tt.want {
+			t.Errorf("matchAny(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/internal/constants"
+)
+
+// DefaultChunkSize is the size of the chunks of a streamed upload (1MB).
+const DefaultChunkSize = 1024 * 1024
+
+// uploadStream uploads the content of reader over the gRPC UploadFile client
+// stream, one chunk at a time. The first chunk carries the file name and
+// purpose.
+func (c *Client) uploadStream(ctx context.Context, reader io.Reader, opts UploadOptions) (*File, error) {
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = constants.DefaultMaxFileSize
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	total := opts.Size
+	if total <= 0 {
+		total = remainingSize(reader)
+	}
+	if total > maxSize {
+		return nil, fmt.Errorf("%w: file size %d exceeds limit %d", ErrFileTooLarge, total, maxSize)
+	}
+
+	// Returning early cancels the stream, so the server discards the upload
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.grpcClient.UploadFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &uploader{stream: stream, hash: sha256.New(), progress: opts.Progress, maxSize: maxSize, total: total}
+	if err := u.sendAll(ctx, reader, chunkSize, opts.initProto()); err != nil {
+		return nil, err
+	}
+
+	pf, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, err
+	}
+	file := fromProto(pf)
+	file.SHA256 = hex.EncodeToString(u.hash.Sum(nil))
+	return file, nil
+}
+
+// uploader sends the chunks of an upload, hashing the content and reporting
+// progress on the way.
+type uploader struct {
+	stream   xaiv1.Files_UploadFileClient
+	hash     hash.Hash
+	progress func(UploadProgress)
+	maxSize  int64
+	total    int64
+	sent     int64
+}
+
+// sendAll reads reader in chunks of chunkSize and sends them, the first one
+// carrying init.
+func (u *uploader) sendAll(ctx context.Context, reader io.Reader, chunkSize int, init *xaiv1.UploadFileInit) error {
+	chunk := &xaiv1.UploadFileChunk{Init: init}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// A fresh buffer per chunk, as a sent message must not be modified
+		data := make([]byte, chunkSize)
+		n, readErr := io.ReadFull(reader, data)
+		if n > 0 {
+			chunk.Data = data[:n]
+			if err := u.send(chunk); err != nil {
+				return err
+			}
+			chunk = &xaiv1.UploadFileChunk{}
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read file content: %w", readErr)
+		}
+	}
+
+	// An empty file is a single chunk without data
+	if chunk.Init != nil {
+		return u.send(chunk)
+	}
+	return nil
+}
+
+// send sends a chunk, enforcing the size limit.
+func (u *uploader) send(chunk *xaiv1.UploadFileChunk) error {
+	n := int64(len(chunk.Data))
+	if u.sent+n > u.maxSize {
+		return fmt.Errorf("%w: file size exceeds limit %d", ErrFileTooLarge, u.maxSize)
+	}
+	u.hash.Write(chunk.Data)
+	if err := u.stream.Send(chunk); err != nil {
+		return sendError(u.stream, err)
+	}
+	u.sent += n
+	if n > 0 && u.progress != nil {
+		u.progress(UploadProgress{BytesSent: u.sent, TotalBytes: u.total})
+	}
+	return nil
+}
+
+// sendError returns the error of a failed send: io.EOF means the server
+// ended the stream, whose status CloseAndRecv returns.
+func sendError(stream xaiv1.Files_UploadFileClient, err error) error {
+	if !errors.Is(err, io.EOF) {
+		return err
+	}
+	if _, err := stream.CloseAndRecv(); err != nil {
+		return err
+	}
+	return fmt.Errorf("upload stream closed by the server")
+}
+
+// remainingSize returns the number of bytes left in reader, or 0 if unknown.
+func remainingSize(reader io.Reader) int64 {
+	switch r := reader.(type) {
+	case *bytes.Reader:
+		return int64(r.Len())
+	case *bytes.Buffer:
+		return int64(r.Len())
+	case *strings.Reader:
+		return int64(r.Len())
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0
+		}
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0
+		}
+		return info.Size() - offset
+	}
+	return 0
+}
@@ -0,0 +1,142 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"google.golang.org/grpc"
+)
+
+// fakeFilesClient answers UploadFile with an uploadStream.
+type fakeFilesClient struct {
+	xaiv1.FilesClient
+	stream *uploadStream
+}
+
+func (c *fakeFilesClient) UploadFile(ctx context.Context, _ ...grpc.CallOption) (grpc.ClientStreamingClient[xaiv1.UploadFileChunk, xaiv1.File], error) {
+	c.stream = &uploadStream{ctx: ctx}
+	return c.stream, nil
+}
+
+// uploadStream records the chunks sent.
+type uploadStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	chunks []*xaiv1.UploadFileChunk
+}
+
+func (s *uploadStream) Send(chunk *xaiv1.UploadFileChunk) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	s.chunks = append(s.chunks, chunk)
+	return nil
+}
+
+func (s *uploadStream) CloseAndRecv() (*xaiv1.File, error) {
+	var size int64
+	for _, chunk := range s.chunks {
+		size += int64(len(chunk.Data))
+	}
+	return &xaiv1.File{Id: "file-1", Filename: s.chunks[0].GetInit().GetName(), Size: size}, nil
+}
+
+func TestUploadStream(t *testing.T) {
+	grpcClient := &fakeFilesClient{}
+	client := NewClientWithGRPC(nil, grpcClient)
+	content := strings.Repeat("0123456789", 25)
+
+	var progress []UploadProgress
+	file, err := client.Upload(context.Background(), strings.NewReader(content), UploadOptions{
+		Name:      "digits.txt",
+		Purpose:   "assistants",
+		ChunkSize: 100,
+		Progress:  func(p UploadProgress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	chunks := grpcClient.stream.chunks
+	if len(chunks) != 3 || len(chunks[0].Data) != 100 || len(chunks[2].Data) != 50 {
+		t.Fatalf("chunks = %d, want 100, 100 and 50 bytes", len(chunks))
+	}
+	if chunks[0].GetInit().GetName() != "digits.txt" || chunks[0].GetInit().GetPurpose() != "assistants" || chunks[1].Init != nil {
+		t.Errorf("only the first chunk should carry the init, got %v and %v", chunks[0].Init, chunks[1].Init)
+	}
+	var sent bytes.Buffer
+	for _, chunk := range chunks {
+		sent.Write(chunk.Data)
+	}
+	if sent.String() != content {
+		t.Error("sent content differs")
+	}
+
+	if len(progress) != 3 || progress[2] != (UploadProgress{BytesSent: 250, TotalBytes: 250}) {
+		t.Errorf("progress = %v", progress)
+	}
+	sum := sha256.Sum256([]byte(content))
+	if file.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("SHA256 = %s", file.SHA256)
+	}
+	if file.ID != "file-1" || file.Size != 250 {
+		t.Errorf("file = %+v", file)
+	}
+}
+
+func TestUploadStreamEmpty(t *testing.T) {
+	grpcClient := &fakeFilesClient{}
+	file, err := NewClientWithGRPC(nil, grpcClient).Upload(context.Background(), strings.NewReader(""), UploadOptions{Name: "empty.txt"})
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if chunks := grpcClient.stream.chunks; len(chunks) != 1 || chunks[0].GetInit().GetName() != "empty.txt" {
+		t.Errorf("chunks = %v, want a single init chunk", chunks)
+	}
+	if file.SHA256 != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("SHA256 = %s", file.SHA256)
+	}
+}
+
+func TestUploadStreamTooLarge(t *testing.T) {
+	client := NewClientWithGRPC(nil, &fakeFilesClient{})
+
+	// The size of a known reader is checked before uploading
+	_, err := client.Upload(context.Background(), strings.NewReader("too large"), UploadOptions{MaxSize: 4})
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("error = %v, want ErrFileTooLarge", err)
+	}
+
+	// Other readers are counted while streaming
+	reader := io.MultiReader(strings.NewReader("too "), strings.NewReader("large"))
+	_, err = client.Upload(context.Background(), reader, UploadOptions{MaxSize: 4, ChunkSize: 2})
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("error = %v, want ErrFileTooLarge", err)
+	}
+}
+
+func TestUploadStreamCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	grpcClient := &fakeFilesClient{}
+	_, err := NewClientWithGRPC(nil, grpcClient).Upload(ctx, strings.NewReader(strings.Repeat("x", 100)), UploadOptions{
+		ChunkSize: 10,
+		Progress: func(p UploadProgress) {
+			if p.BytesSent == 30 {
+				cancel()
+			}
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if len(grpcClient.stream.chunks) != 3 {
+		t.Errorf("sent %d chunks, want 3", len(grpcClient.stream.chunks))
+	}
+}
@@ -4,7 +4,9 @@ package auth
 import (
 	"context"
 	"fmt"
+	"io"
 
+	"github.com/ZaguanLabs/xai-sdk-go/xai/credentials"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -18,16 +20,28 @@ const (
 	XAPIKeyHeader = "x-api-key"
 )
 
+// maxCredentialAttempts bounds the attempts of a unary call failing over
+// between the keys of a provider.
+const maxCredentialAttempts = 4
+
 // APIKeyAuthInterceptor creates a unary interceptor that authenticates requests with an API key.
 type APIKeyAuthInterceptor struct {
-	apiKey     string
+	provider   credentials.Provider
 	useXAPIKey bool
 }
 
 // NewAPIKeyAuthInterceptor creates a new API key authentication interceptor.
 func NewAPIKeyAuthInterceptor(apiKey string, useXAPIKey bool) *APIKeyAuthInterceptor {
+	return NewProviderAuthInterceptor(credentials.Static(apiKey), useXAPIKey)
+}
+
+// NewProviderAuthInterceptor creates an authentication interceptor that asks
+// provider for the API key of every call. Failed calls are reported to the
+// provider, and unary calls are retried at once with another key when the
+// provider offers one.
+func NewProviderAuthInterceptor(provider credentials.Provider, useXAPIKey bool) *APIKeyAuthInterceptor {
 	return &APIKeyAuthInterceptor{
-		apiKey:     apiKey,
+		provider:   provider,
 		useXAPIKey: useXAPIKey,
 	}
 }
@@ -42,14 +56,19 @@ func (a *APIKeyAuthInterceptor) UnaryInterceptor() grpc.UnaryClientInterceptor {
 		invoker grpc.UnaryInvoker,
 		opts ...grpc.CallOption,
 	) error {
-		// Add authentication metadata to context
-		authCtx, err := a.addAuthMetadata(ctx)
-		if err != nil {
-			return status.Error(codes.Unauthenticated, fmt.Sprintf("failed to add auth metadata: %v", err))
-		}
+		for attempt := 1; ; attempt++ {
+			// Add authentication metadata to context
+			authCtx, apiKey, err := a.addAuthMetadata(ctx)
+			if err != nil {
+				return status.Error(codes.Unauthenticated, fmt.Sprintf("failed to add auth metadata: %v", err))
+			}
 
-		// Call the invoker with authenticated context
-		return invoker(authCtx, method, req, reply, cc, opts...)
+			// Call the invoker with authenticated context
+			err = invoker(authCtx, method, req, reply, cc, opts...)
+			if !credentials.Report(a.provider, apiKey, err) || attempt == maxCredentialAttempts {
+				return err
+			}
+		}
 	}
 }
 
@@ -64,31 +83,55 @@ func (a *APIKeyAuthInterceptor) StreamInterceptor() grpc.StreamClientInterceptor
 		opts ...grpc.CallOption,
 	) (grpc.ClientStream, error) {
 		// Add authentication metadata to context
-		authCtx, err := a.addAuthMetadata(ctx)
+		authCtx, apiKey, err := a.addAuthMetadata(ctx)
 		if err != nil {
 			return nil, status.Error(codes.Unauthenticated, fmt.Sprintf("failed to add auth metadata: %v", err))
 		}
 
 		// Call the streamer with authenticated context
-		return streamer(authCtx, desc, cc, method, opts...)
+		stream, err := streamer(authCtx, desc, cc, method, opts...)
+		if err != nil {
+			credentials.Report(a.provider, apiKey, err)
+			return nil, err
+		}
+		return &reportingStream{ClientStream: stream, provider: a.provider, apiKey: apiKey}, nil
 	}
 }
 
-// addAuthMetadata adds authentication metadata to the context.
+// addAuthMetadata adds authentication metadata to the context and returns the API key used.
 // Uses AppendToOutgoingContext to preserve gRPC's internal metadata.
-func (a *APIKeyAuthInterceptor) addAuthMetadata(ctx context.Context) (context.Context, error) {
-	if a.apiKey == "" {
-		return nil, fmt.Errorf("API key is empty")
+func (a *APIKeyAuthInterceptor) addAuthMetadata(ctx context.Context) (context.Context, string, error) {
+	apiKey, err := a.provider.APIKey(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if apiKey == "" {
+		return nil, "", fmt.Errorf("API key is empty")
 	}
 
 	// Add authentication metadata using AppendToOutgoingContext
 	// This preserves any existing metadata including gRPC's content-type
 	if a.useXAPIKey {
 		// Use x-api-key header
-		return metadata.AppendToOutgoingContext(ctx, XAPIKeyHeader, a.apiKey), nil
+		return metadata.AppendToOutgoingContext(ctx, XAPIKeyHeader, apiKey), apiKey, nil
 	}
 	// Use authorization bearer token
-	return metadata.AppendToOutgoingContext(ctx, AuthorizationHeader, fmt.Sprintf("Bearer %s", a.apiKey)), nil
+	return metadata.AppendToOutgoingContext(ctx, AuthorizationHeader, fmt.Sprintf("Bearer %s", apiKey)), apiKey, nil
+}
+
+// reportingStream reports the error ending a stream to the credentials provider.
+type reportingStream struct {
+	grpc.ClientStream
+	provider credentials.Provider
+	apiKey   string
+}
+
+func (s *reportingStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil && err != io.EOF {
+		credentials.Report(s.provider, s.apiKey, err)
+	}
+	return err
 }
 
 // APIKeyAuthUnaryInterceptor creates a simple unary interceptor with the given API key.
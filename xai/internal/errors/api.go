@@ -0,0 +1,293 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai/retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrContextTooLong indicates the prompt does not fit the model's context window.
+var ErrContextTooLong = errors.New("context too long")
+
+// requestIDKeys are the header and metadata keys carrying the request ID.
+var requestIDKeys = []string{"x-request-id", "request-id"}
+
+// contextTooLongMarkers are message fragments the API uses when a prompt
+// exceeds the model's context window.
+var contextTooLongMarkers = []string{
+	"maximum prompt length",
+	"maximum context length",
+	"context length",
+	"context window",
+	"prompt is too long",
+	"too many tokens",
+}
+
+// APIError is an error reported by the xAI API over gRPC or REST.
+//
+// It matches the SDK sentinels with errors.Is (ErrRateLimit, ErrUnauthorized,
+// ErrPermissionDenied, ErrNotFound, ErrInvalidRequest, ErrContextTooLong,
+// ErrServiceUnavailable, ErrInternal, ErrDeadlineExceeded) and implements
+// GRPCStatus so that status.FromError and status.Code keep working.
+type APIError struct {
+	// Code is the gRPC status code. For REST calls it is derived from HTTPStatus.
+	Code codes.Code
+
+	// HTTPStatus is the HTTP status code. For gRPC calls it is derived from Code.
+	HTTPStatus int
+
+	// Message is the error message reported by the server.
+	Message string
+
+	// RequestID identifies the request in the API's logs, if it was reported.
+	RequestID string
+
+	// RetryAfter is the delay the server asked for before retrying, or zero.
+	RetryAfter time.Duration
+
+	// Details holds the gRPC status details, or the decoded JSON body of a
+	// REST error response.
+	Details []any
+
+	status *status.Status
+	cause  error
+}
+
+// Error returns the error message.
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (HTTP %d)", e.Code, e.HTTPStatus)
+	if e.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Message)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " [request_id=%s]", e.RequestID)
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying gRPC status or HTTP error.
+func (e *APIError) Unwrap() error {
+	return e.cause
+}
+
+// As converts the error to an *Error when target is a **Error, so that code
+// matching the *Error returned before APIError with errors.As keeps working.
+func (e *APIError) As(target any) bool {
+	sdkErr, ok := target.(**Error)
+	if !ok {
+		return false
+	}
+	*sdkErr = NewErrorWithCause(e.Type(), e.Code, e.Message, e.cause)
+	return true
+}
+
+// Is reports whether the error matches one of the SDK sentinel errors, or
+// context.Canceled / context.DeadlineExceeded for canceled and timed-out calls.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrRateLimit, ErrTooManyRequests:
+		return e.Code == codes.ResourceExhausted
+	case ErrUnauthorized, ErrInvalidAPIKey:
+		return e.Code == codes.Unauthenticated
+	case ErrPermissionDenied:
+		return e.Code == codes.PermissionDenied
+	case ErrNotFound:
+		return e.Code == codes.NotFound
+	case ErrInvalidRequest:
+		return e.Code == codes.InvalidArgument
+	case ErrContextTooLong:
+		return e.isContextTooLong()
+	case ErrServiceUnavailable:
+		return e.Code == codes.Unavailable
+	case ErrInternal:
+		return e.Code == codes.Internal
+	case ErrDeadlineExceeded, context.DeadlineExceeded:
+		return e.Code == codes.DeadlineExceeded
+	case ErrCanceled, context.Canceled:
+		return e.Code == codes.Canceled
+	}
+	return false
+}
+
+func (e *APIError) isContextTooLong() bool {
+	if e.Code != codes.InvalidArgument && e.Code != codes.OutOfRange && e.HTTPStatus != http.StatusRequestEntityTooLarge {
+		return false
+	}
+	message := strings.ToLower(e.Message)
+	for _, marker := range contextTooLongMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Type returns the SDK error type of the error.
+func (e *APIError) Type() ErrorType {
+	return mapGRPCCodeToErrorType(e.Code)
+}
+
+// GRPCStatus returns the gRPC status of the error, including its details.
+func (e *APIError) GRPCStatus() *status.Status {
+	if e.status != nil {
+		return e.status
+	}
+	return status.New(e.Code, e.Message)
+}
+
+// newGRPCAPIError converts a gRPC status into an APIError. md is the header
+// and trailer metadata of the failed call.
+func newGRPCAPIError(st *status.Status, err error, md ...metadata.MD) *APIError {
+	apiErr := &APIError{
+		Code:       st.Code(),
+		HTTPStatus: HTTPStatusFromCode(st.Code()),
+		Message:    st.Message(),
+		Details:    st.Details(),
+		status:     st,
+		cause:      err,
+	}
+	for _, m := range md {
+		if apiErr.RequestID == "" {
+			apiErr.RequestID = firstValue(m, requestIDKeys)
+		}
+		if after, ok := retry.Pushback(err, m); ok && after > 0 && apiErr.RetryAfter == 0 {
+			apiErr.RetryAfter = after
+		}
+	}
+	if apiErr.RetryAfter == 0 {
+		if after, ok := retry.Pushback(err, nil); ok && after > 0 {
+			apiErr.RetryAfter = after
+		}
+	}
+	return apiErr
+}
+
+func firstValue(md metadata.MD, keys []string) string {
+	for _, key := range keys {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// FromHTTP converts an HTTP error response into an APIError. cause is kept as
+// the unwrapped error. The message is taken from the "error" or "message"
+// field of a JSON body, or from the body itself.
+func FromHTTP(statusCode int, header http.Header, body []byte, cause error) *APIError {
+	apiErr := &APIError{
+		Code:       CodeFromHTTPStatus(statusCode),
+		HTTPStatus: statusCode,
+		cause:      cause,
+	}
+	for _, key := range requestIDKeys {
+		if id := header.Get(key); id != "" {
+			apiErr.RequestID = id
+			break
+		}
+	}
+	if after, ok := retry.ParseRetryAfter(header.Get("Retry-After")); ok {
+		apiErr.RetryAfter = after
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		apiErr.Details = []any{decoded}
+		apiErr.Message = jsonErrorMessage(decoded)
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(statusCode)
+	}
+	return apiErr
+}
+
+// jsonErrorMessage extracts the message of a JSON error body such as
+// {"error": "..."}, {"error": {"message": "..."}} or {"message": "..."}.
+func jsonErrorMessage(body map[string]any) string {
+	switch value := body["error"].(type) {
+	case string:
+		return value
+	case map[string]any:
+		if message, ok := value["message"].(string); ok {
+			return message
+		}
+	}
+	if message, ok := body["message"].(string); ok {
+		return message
+	}
+	return ""
+}
+
+// httpStatusByCode maps gRPC codes to HTTP status codes, following the
+// mapping used by gRPC gateways.
+var httpStatusByCode = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+}
+
+// HTTPStatusFromCode returns the HTTP status code corresponding to a gRPC code.
+func HTTPStatusFromCode(code codes.Code) int {
+	if httpStatus, ok := httpStatusByCode[code]; ok {
+		return httpStatus
+	}
+	return http.StatusInternalServerError
+}
+
+// CodeFromHTTPStatus returns the gRPC code corresponding to an HTTP status code.
+func CodeFromHTTPStatus(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499:
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	}
+	if httpStatus >= 500 {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,110 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestFromGRPCAPIError(t *testing.T) {
+	st, err := status.New(codes.ResourceExhausted, "slow down").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(2 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := metadata.Pairs("x-request-id", "req-123")
+
+	wrapped := fmt.Errorf("chat: %w", FromGRPC(st.Err(), header))
+
+	var apiErr *APIError
+	if !errors.As(wrapped, &apiErr) {
+		t.Fatalf("errors.As(%v) failed", wrapped)
+	}
+	if apiErr.Code != codes.ResourceExhausted || apiErr.HTTPStatus != http.StatusTooManyRequests {
+		t.Errorf("Code = %v, HTTPStatus = %d", apiErr.Code, apiErr.HTTPStatus)
+	}
+	if apiErr.RequestID != "req-123" || apiErr.RetryAfter != 2*time.Second || len(apiErr.Details) != 1 {
+		t.Errorf("RequestID = %q, RetryAfter = %v, Details = %v", apiErr.RequestID, apiErr.RetryAfter, apiErr.Details)
+	}
+	if !errors.Is(wrapped, ErrRateLimit) || errors.Is(wrapped, ErrNotFound) {
+		t.Error("errors.Is should match ErrRateLimit only")
+	}
+	if status.Code(wrapped) != codes.ResourceExhausted {
+		t.Errorf("status.Code() = %v, want ResourceExhausted", status.Code(wrapped))
+	}
+	if got, _ := status.FromError(wrapped); len(got.Details()) != 1 {
+		t.Error("status.FromError should keep the status details")
+	}
+	if FromGRPC(apiErr) != apiErr {
+		t.Error("FromGRPC should return an APIError as is")
+	}
+}
+
+func TestAPIErrorAsError(t *testing.T) {
+	err := fmt.Errorf("call failed: %w", FromGRPC(status.Error(codes.NotFound, "no such model")))
+
+	var sdkErr *Error
+	if !errors.As(err, &sdkErr) {
+		t.Fatalf("errors.As(%v, *Error) = false", err)
+	}
+	if sdkErr.Type() != ErrorTypeAPI || sdkErr.Code() != codes.NotFound || sdkErr.GRPCStatus().Message() != "no such model" {
+		t.Errorf("Error = %v (%s, %s)", sdkErr, sdkErr.Type(), sdkErr.Code())
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != codes.NotFound {
+		t.Errorf("errors.As(%v, *APIError) = false", err)
+	}
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		err    *APIError
+		target error
+		want   bool
+	}{
+		{&APIError{Code: codes.Unauthenticated}, ErrUnauthorized, true},
+		{&APIError{Code: codes.InvalidArgument, Message: "This model's maximum prompt length is 131072"}, ErrContextTooLong, true},
+		{&APIError{Code: codes.InvalidArgument, Message: "This model's maximum prompt length is 131072"}, ErrInvalidRequest, true},
+		{&APIError{Code: codes.InvalidArgument, Message: "temperature out of range"}, ErrContextTooLong, false},
+		{&APIError{Code: codes.DeadlineExceeded}, context.DeadlineExceeded, true},
+		{&APIError{Code: codes.Canceled}, context.Canceled, true},
+		{&APIError{Code: codes.Unavailable}, ErrServiceUnavailable, true},
+		{&APIError{Code: codes.Unavailable}, ErrInternal, false},
+	}
+	for _, tt := range tests {
+		if got := errors.Is(tt.err, tt.target); got != tt.want {
+			t.Errorf("errors.Is(%v, %v) = %v, want %v", tt.err, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestFromHTTP(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Request-Id", "req-456")
+	header.Set("Retry-After", "3")
+	cause := errors.New("HTTP 429")
+
+	apiErr := FromHTTP(http.StatusTooManyRequests, header, []byte(`{"error":{"message":"rate limit reached"}}`), cause)
+	if apiErr.Code != codes.ResourceExhausted || apiErr.Message != "rate limit reached" {
+		t.Errorf("Code = %v, Message = %q", apiErr.Code, apiErr.Message)
+	}
+	if apiErr.RequestID != "req-456" || apiErr.RetryAfter != 3*time.Second || len(apiErr.Details) != 1 {
+		t.Errorf("RequestID = %q, RetryAfter = %v, Details = %v", apiErr.RequestID, apiErr.RetryAfter, apiErr.Details)
+	}
+	if !errors.Is(apiErr, ErrRateLimit) || !errors.Is(apiErr, cause) {
+		t.Error("errors.Is should match ErrRateLimit and the cause")
+	}
+
+	plain := FromHTTP(http.StatusBadGateway, http.Header{}, []byte("upstream failed\n"), nil)
+	if plain.Code != codes.Unavailable || plain.Message != "upstream failed" {
+		t.Errorf("plain body: Code = %v, Message = %q", plain.Code, plain.Message)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -236,21 +237,25 @@ func (e *Error) GRPCStatus() *status.Status {
 	return status.New(e.grpcCode, e.message)
 }
 
-// FromGRPC converts a gRPC status error to an xAI SDK error.
-func FromGRPC(err error) error {
+// FromGRPC converts a gRPC status error to an xAI SDK error. Status errors are
+// returned as *APIError; md is the header and trailer metadata of the failed
+// call, from which the request ID and retry delay are taken.
+func FromGRPC(err error, md ...metadata.MD) error {
 	if err == nil {
 		return nil
 	}
 
-	// If it's already an Error type, return as is
+	// If it's already an SDK error, return as is
 	if sdkError, ok := err.(*Error); ok {
 		return sdkError
 	}
+	if apiErr, ok := err.(*APIError); ok {
+		return apiErr
+	}
 
 	// Convert gRPC status error
 	if st, ok := status.FromError(err); ok {
-		errorType := mapGRPCCodeToErrorType(st.Code())
-		return NewError(errorType, st.Code(), st.Message())
+		return newGRPCAPIError(st, err, md...)
 	}
 
 	// Handle io.EOF as a special case
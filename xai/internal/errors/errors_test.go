@@ -315,7 +315,8 @@ func TestFromGRPCWithWrappedError(t *testing.T) {
 	result := FromGRPC(cause)
 
 	// Should convert to SDK error
-	if _, ok := result.(*Error); !ok {
+	var sdkErr *Error
+	if !errors.As(result, &sdkErr) {
 		t.Error("FromGRPC should convert gRPC error to SDK error")
 	}
 }
@@ -0,0 +1,83 @@
+package grpcutil
+
+import (
+	"context"
+	"io"
+
+	xaierrors "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorUnaryInterceptor returns a unary interceptor that converts gRPC status
+// errors into *errors.APIError, carrying the request ID and retry delay
+// reported in the call's header and trailer.
+func ErrorUnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		var header, trailer metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header), grpc.Trailer(&trailer))...)
+		return convertError(err, header, trailer)
+	}
+}
+
+// ErrorStreamInterceptor returns a stream interceptor that converts gRPC
+// status errors into *errors.APIError, like ErrorUnaryInterceptor.
+func ErrorStreamInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, convertError(err)
+		}
+		return &errorStream{ClientStream: stream}, nil
+	}
+}
+
+// errorStream converts the errors of a client stream into *errors.APIError.
+type errorStream struct {
+	grpc.ClientStream
+}
+
+func (s *errorStream) SendMsg(m interface{}) error {
+	return s.convert(s.ClientStream.SendMsg(m))
+}
+
+func (s *errorStream) RecvMsg(m interface{}) error {
+	return s.convert(s.ClientStream.RecvMsg(m))
+}
+
+func (s *errorStream) convert(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	if _, ok := status.FromError(err); !ok {
+		return err
+	}
+	// The header is available once the stream has failed, so this does not block.
+	header, _ := s.Header()
+	return convertError(err, header, s.Trailer())
+}
+
+func convertError(err error, md ...metadata.MD) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); !ok {
+		return err
+	}
+	return xaierrors.FromGRPC(err, md...)
+}
@@ -0,0 +1,50 @@
+package grpcutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	xaierrors "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorUnaryInterceptor(t *testing.T) {
+	invoker := func(_ context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, opts ...grpc.CallOption) error {
+		for _, opt := range opts {
+			if header, ok := opt.(grpc.HeaderCallOption); ok {
+				*header.HeaderAddr = metadata.Pairs("x-request-id", "req-1")
+			}
+		}
+		return status.Error(codes.PermissionDenied, "no access")
+	}
+
+	err := ErrorUnaryInterceptor()(context.Background(), "/xai_api.Models/ListLanguageModels", nil, nil, nil, invoker)
+	var apiErr *xaierrors.APIError
+	if !errors.As(err, &apiErr) || apiErr.RequestID != "req-1" || !errors.Is(err, xaierrors.ErrPermissionDenied) {
+		t.Fatalf("interceptor() = %#v", err)
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("status.Code() = %v", status.Code(err))
+	}
+}
+
+func TestErrorStreamInterceptor(t *testing.T) {
+	stream := &fakeClientStream{err: status.Error(codes.Unavailable, "down")}
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return stream, nil
+	}
+
+	cs, err := ErrorStreamInterceptor()(context.Background(), &grpc.StreamDesc{ServerStreams: true}, nil, "/xai_api.Chat/GetCompletionChunk", streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cs.RecvMsg(nil)
+	var apiErr *xaierrors.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != codes.Unavailable || apiErr.RetryAfter == 0 {
+		t.Errorf("RecvMsg() = %#v, want an APIError with the pushback delay", err)
+	}
+}
@@ -0,0 +1,218 @@
+package grpcutil
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// nonIdempotentMethods are read-like methods that nevertheless generate a new
+// result on each call, so they are only retried when the server did not
+// process the request.
+var nonIdempotentMethods = map[string]bool{
+	"/xai_api.Chat/GetCompletion":      true,
+	"/xai_api.Chat/GetCompletionChunk": true,
+}
+
+// idempotentPrefixes are the method name prefixes of idempotent methods.
+var idempotentPrefixes = []string{"get", "list", "delete", "retrieve", "batchget", "tokenize", "embed", "search"}
+
+// IsIdempotentMethod reports whether a gRPC method can safely be retried after
+// a failure that may have happened after the server processed it. Getters,
+// listings, deletions and pure computations such as tokenization are idempotent.
+func IsIdempotentMethod(method string) bool {
+	if nonIdempotentMethods[method] {
+		return false
+	}
+	name := strings.ToLower(method[strings.LastIndexByte(method, '/')+1:])
+	for _, prefix := range idempotentPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryUnaryInterceptor returns a unary interceptor that retries failed calls
+// according to policy, which can be overridden per call with retry.WithPolicy,
+// retry.WithMaxRetries and retry.WithIdempotent.
+func RetryUnaryInterceptor(policy retry.Policy) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		p := retry.PolicyFromContext(ctx, policy)
+		if p.MaxRetries <= 0 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		idempotent := retry.IdempotentFromContext(ctx, IsIdempotentMethod(method))
+
+		var trailer metadata.MD
+		classify := func(err error) (bool, time.Duration) {
+			return retry.ClassifyGRPC(err, trailer, idempotent)
+		}
+		return retry.Do(ctx, p, classify, func() error {
+			trailer = nil
+			return invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+		})
+	}
+}
+
+// RetryStreamInterceptor returns a stream interceptor that retries the
+// establishment of server-streaming calls: failures before the first message
+// is received re-open the stream and resend the request, including failures of
+// a send to a stream that inner interceptors open lazily, such as the rate
+// limiter. Once a message has been received, errors are returned as is.
+// Client-streaming calls are not retried.
+func RetryStreamInterceptor(policy retry.Policy) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		p := retry.PolicyFromContext(ctx, policy)
+		if p.MaxRetries <= 0 || desc.ClientStreams {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		s := &retryStream{
+			ctx:        ctx,
+			desc:       desc,
+			cc:         cc,
+			method:     method,
+			streamer:   streamer,
+			opts:       opts,
+			policy:     p,
+			idempotent: retry.IdempotentFromContext(ctx, IsIdempotentMethod(method)),
+		}
+		if err := retry.Do(ctx, p, s.classify, s.open); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+}
+
+// retryStream is a server-streaming call that is re-opened when it fails
+// before its first message.
+type retryStream struct {
+	grpc.ClientStream
+
+	ctx        context.Context
+	desc       *grpc.StreamDesc
+	cc         *grpc.ClientConn
+	method     string
+	streamer   grpc.Streamer
+	opts       []grpc.CallOption
+	policy     retry.Policy
+	idempotent bool
+
+	// attempts counts the streams opened so far.
+	attempts int
+	// sent and closeSent record the request so that it can be replayed.
+	sent      []interface{}
+	closeSent bool
+	// committed is set once the first receive succeeded or failed for good.
+	committed bool
+	// failed is the stream of the last failed attempt, used for its trailer.
+	failed grpc.ClientStream
+}
+
+func (s *retryStream) open() error {
+	s.attempts++
+	s.failed = nil
+	stream, err := s.streamer(s.ctx, s.desc, s.cc, s.method, s.opts...)
+	if err != nil {
+		return err
+	}
+	for _, msg := range s.sent {
+		if err := stream.SendMsg(msg); err != nil {
+			return err
+		}
+	}
+	if s.closeSent {
+		if err := stream.CloseSend(); err != nil {
+			return err
+		}
+	}
+	s.ClientStream = stream
+	return nil
+}
+
+// remainingPolicy returns the policy less the retries already used to open
+// the stream.
+func (s *retryStream) remainingPolicy() retry.Policy {
+	p := s.policy
+	p.MaxRetries -= s.attempts - 1
+	return p
+}
+
+func (s *retryStream) classify(err error) (bool, time.Duration) {
+	var trailer metadata.MD
+	if s.failed != nil {
+		trailer = s.failed.Trailer()
+	}
+	return retry.ClassifyGRPC(err, trailer, s.idempotent)
+}
+
+func (s *retryStream) SendMsg(m interface{}) error {
+	if s.committed {
+		return s.ClientStream.SendMsg(m)
+	}
+	s.sent = append(s.sent, m)
+	err := s.ClientStream.SendMsg(m)
+	if err == nil || err == io.EOF {
+		// A stream that failed returns io.EOF and its status from RecvMsg.
+		return err
+	}
+
+	// The stream could not be established on its first send: re-open it.
+	first := true
+	return retry.Do(s.ctx, s.remainingPolicy(), s.classify, func() error {
+		if first {
+			first = false
+			return err
+		}
+		return s.open()
+	})
+}
+
+func (s *retryStream) CloseSend() error {
+	s.closeSent = true
+	return s.ClientStream.CloseSend()
+}
+
+func (s *retryStream) RecvMsg(m interface{}) error {
+	if s.committed {
+		return s.ClientStream.RecvMsg(m)
+	}
+
+	first := true
+	err := retry.Do(s.ctx, s.remainingPolicy(), s.classify, func() error {
+		if !first {
+			if err := s.open(); err != nil {
+				return err
+			}
+		}
+		first = false
+		if err := s.ClientStream.RecvMsg(m); err != nil {
+			s.failed = s.ClientStream
+			return err
+		}
+		return nil
+	})
+	s.committed = true
+	s.sent = nil
+	return err
+}
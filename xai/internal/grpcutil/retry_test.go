@@ -0,0 +1,187 @@
+package grpcutil
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai/retry"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+var testPolicy = retry.Policy{MaxRetries: 2, InitialBackoff: time.Millisecond, Jitter: -1}
+
+func TestIsIdempotentMethod(t *testing.T) {
+	tests := map[string]bool{
+		"/xai_api.Models/ListLanguageModels": true,
+		"/xai_api.Auth/get_api_key_info":     true,
+		"/xai_api.Tokenize/TokenizeText":     true,
+		"/xai_api.Chat/GetCompletion":        false,
+		"/xai_api.Chat/GetCompletionChunk":   false,
+		"/xai_api.BatchMgmt/CreateBatch":     false,
+	}
+	for method, want := range tests {
+		if got := IsIdempotentMethod(method); got != want {
+			t.Errorf("IsIdempotentMethod(%q) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+func TestRetryUnaryInterceptor(t *testing.T) {
+	interceptor := RetryUnaryInterceptor(testPolicy)
+	busy, _ := status.New(codes.ResourceExhausted, "busy").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Millisecond)})
+	failures := []error{status.Error(codes.Unavailable, "down"), busy.Err()}
+
+	calls := 0
+	invoker := func(_ context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		calls++
+		if calls <= len(failures) {
+			return failures[calls-1]
+		}
+		return nil
+	}
+	if err := interceptor(context.Background(), "/xai_api.Chat/GetCompletion", nil, nil, nil, invoker); err != nil || calls != 3 {
+		t.Errorf("interceptor() = %v after %d calls, want nil after 3", err, calls)
+	}
+
+	// Rate limits are retried for non-idempotent methods only with a pushback.
+	calls = 0
+	limited := func(_ context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		calls++
+		return status.Error(codes.ResourceExhausted, "busy")
+	}
+	if err := interceptor(context.Background(), "/xai_api.Chat/GetCompletion", nil, nil, nil, limited); err == nil || calls != 1 {
+		t.Errorf("rate limited without pushback: %v after %d calls", err, calls)
+	}
+
+	// Internal errors are not retried for non-idempotent methods.
+	calls = 0
+	failing := func(_ context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		calls++
+		return status.Error(codes.Internal, "oops")
+	}
+	if err := interceptor(context.Background(), "/xai_api.Chat/GetCompletion", nil, nil, nil, failing); err == nil || calls != 1 {
+		t.Errorf("non-idempotent: %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	if err := interceptor(context.Background(), "/xai_api.Models/ListLanguageModels", nil, nil, nil, failing); err == nil || calls != 3 {
+		t.Errorf("idempotent: %v after %d calls, want 3", err, calls)
+	}
+
+	// Retries can be disabled per call.
+	calls = 0
+	ctx := retry.WithMaxRetries(context.Background(), 0)
+	if err := interceptor(ctx, "/xai_api.Models/ListLanguageModels", nil, nil, nil, failing); err == nil || calls != 1 {
+		t.Errorf("disabled: %v after %d calls", err, calls)
+	}
+}
+
+// fakeClientStream fails its first receive with err, if set.
+type fakeClientStream struct {
+	grpc.ClientStream
+	err      error
+	sent     []interface{}
+	closed   bool
+	messages int
+}
+
+func (f *fakeClientStream) SendMsg(m interface{}) error {
+	f.sent = append(f.sent, m)
+	return nil
+}
+
+func (f *fakeClientStream) CloseSend() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeClientStream) RecvMsg(interface{}) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.messages == 0 {
+		return io.EOF
+	}
+	f.messages--
+	return nil
+}
+
+func (f *fakeClientStream) Header() (metadata.MD, error) {
+	return nil, nil
+}
+
+func (f *fakeClientStream) Trailer() metadata.MD {
+	return metadata.Pairs(retry.PushbackHeader, "1")
+}
+
+func TestRetryStreamInterceptorReopensBeforeFirstMessage(t *testing.T) {
+	var streams []*fakeClientStream
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream := &fakeClientStream{messages: 2}
+		if len(streams) == 0 {
+			stream.err = status.Error(codes.Unavailable, "down")
+		}
+		streams = append(streams, stream)
+		return stream, nil
+	}
+
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	cs, err := RetryStreamInterceptor(testPolicy)(context.Background(), desc, nil, "/xai_api.Chat/GetCompletionChunk", streamer)
+	if err != nil {
+		t.Fatalf("interceptor() error = %v", err)
+	}
+	if err := cs.SendMsg("request"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	received := 0
+	for {
+		err := cs.RecvMsg(nil)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("RecvMsg() error = %v", err)
+		}
+		received++
+	}
+
+	if len(streams) != 2 || received != 2 {
+		t.Fatalf("streams = %d, received = %d", len(streams), received)
+	}
+	if reopened := streams[1]; len(reopened.sent) != 1 || reopened.sent[0] != "request" || !reopened.closed {
+		t.Errorf("request not replayed on the new stream: %+v", reopened)
+	}
+}
+
+func TestRetryStreamInterceptorDoesNotRetryAfterFirstMessage(t *testing.T) {
+	opened := 0
+	stream := &fakeClientStream{messages: 1}
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		opened++
+		return stream, nil
+	}
+
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	cs, err := RetryStreamInterceptor(testPolicy)(context.Background(), desc, nil, "/xai_api.Chat/GetCompletionChunk", streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.RecvMsg(nil); err != nil {
+		t.Fatal(err)
+	}
+	stream.err = status.Error(codes.Unavailable, "down")
+	if err := cs.RecvMsg(nil); status.Code(err) != codes.Unavailable || opened != 1 {
+		t.Errorf("RecvMsg() = %v with %d streams opened", err, opened)
+	}
+}
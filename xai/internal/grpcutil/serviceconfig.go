@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ZaguanLabs/xai-sdk-go/xai/retry"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
@@ -282,43 +283,16 @@ func (hc *HealthChecker) getStateError(state connectivity.State) string {
 
 // RetryWithBackoff performs a retry operation with exponential backoff.
 func RetryWithBackoff(ctx context.Context, policy *RetryPolicy, operation func() error) error {
-	backoff := policy.InitialBackoff
-
-	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
-		err := operation()
-		if err == nil {
-			return nil
-		}
-
-		// Check if error is retryable
-		if !isRetryableError(err, policy.RetryableCodes) {
-			return err
-		}
-
-		// If this is the last attempt, return the error
-		if attempt == policy.MaxAttempts-1 {
-			return err
-		}
-
-		// Create a new context with timeout for the backoff period
-		backoffCtx, cancel := context.WithTimeout(ctx, backoff)
-		defer cancel()
-
-		// Wait for the backoff period or context cancellation
-		select {
-		case <-backoffCtx.Done():
-			return backoffCtx.Err()
-		case <-time.After(backoff):
-		}
-
-		// Increase backoff for next iteration
-		backoff = time.Duration(float64(backoff) * policy.BackoffMultiplier)
-		if backoff > policy.MaxBackoff {
-			backoff = policy.MaxBackoff
-		}
+	p := retry.Policy{
+		MaxRetries:     policy.MaxAttempts - 1,
+		InitialBackoff: policy.InitialBackoff,
+		MaxBackoff:     policy.MaxBackoff,
+		Multiplier:     policy.BackoffMultiplier,
+		Jitter:         -1,
 	}
-
-	return nil
+	return retry.Do(ctx, p, func(err error) (bool, time.Duration) {
+		return isRetryableError(err, policy.RetryableCodes), 0
+	}, operation)
 }
 
 // isRetryableError checks if an error is retryable based on the retryable codes.
@@ -0,0 +1,154 @@
+// Package logging writes the SDK's log records to a log/slog logger. Records
+// pass through a handler redacting API keys, authorization headers and proxy
+// credentials, so that they are never written whatever the logger's handler.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces redacted values.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are the attribute keys whose values are always redacted,
+// lower-cased with dashes replaced by underscores.
+var sensitiveKeys = map[string]bool{
+	"api_key":             true,
+	"apikey":              true,
+	"x_api_key":           true,
+	"management_api_key":  true,
+	"authorization":       true,
+	"proxy_authorization": true,
+	"access_token":        true,
+	"client_secret":       true,
+	"password":            true,
+	"secret":              true,
+}
+
+// secretPatterns match secrets inside strings.
+var secretPatterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[^\s"',;]+`), "$1 " + Redacted},
+	{regexp.MustCompile(`\bxai-[A-Za-z0-9_-]{16,}`), Redacted},
+	{regexp.MustCompile(`(://)[^/\s:@]+:[^/\s@]+@`), "${1}" + Redacted + "@"},
+}
+
+// New returns a logger writing to logger's handler with secrets redacted,
+// or a logger discarding all records if logger is nil. The values of secrets,
+// such as the configured API keys, are redacted wherever they appear.
+func New(logger *slog.Logger, secrets ...string) *slog.Logger {
+	if logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return slog.New(&redactingHandler{next: logger.Handler(), redactor: newRedactor(secrets)})
+}
+
+// Redact redacts the API keys, authorization values and proxy credentials in
+// s, and the values of secrets wherever they appear.
+func Redact(s string, secrets ...string) string {
+	return newRedactor(secrets).string(s)
+}
+
+// IsSensitiveKey reports whether the values of key, an attribute or header
+// name, are always redacted.
+func IsSensitiveKey(key string) bool {
+	return sensitiveKeys[strings.ReplaceAll(strings.ToLower(key), "-", "_")]
+}
+
+// redactor redacts strings and attributes.
+type redactor struct {
+	secrets []string
+}
+
+func newRedactor(secrets []string) *redactor {
+	r := &redactor{}
+	for _, secret := range secrets {
+		if secret != "" {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+	return r
+}
+
+func (r *redactor) string(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	for _, p := range secretPatterns {
+		s = p.re.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+func (r *redactor) attr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if IsSensitiveKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, r.string(a.Value.String()))
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		redacted := make([]slog.Attr, len(attrs))
+		for i, attr := range attrs {
+			redacted[i] = r.attr(attr)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			return slog.String(a.Key, r.string(v.Error()))
+		case json.RawMessage:
+			return slog.String(a.Key, r.string(string(v)))
+		case []byte:
+			return slog.String(a.Key, r.string(string(v)))
+		case fmt.Stringer:
+			return slog.String(a.Key, r.string(v.String()))
+		}
+	}
+	return a
+}
+
+func (r *redactor) attrs(attrs []slog.Attr) []slog.Attr {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = r.attr(a)
+	}
+	return redacted
+}
+
+// redactingHandler redacts the message and attributes of records before
+// passing them to the next handler.
+type redactingHandler struct {
+	next     slog.Handler
+	redactor *redactor
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.string(record.Message), record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.redactor.attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &redactingHandler{next: h.next.WithAttrs(h.redactor.attrs(attrs)), redactor: h.redactor}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{next: h.next.WithGroup(name), redactor: h.redactor}
+}
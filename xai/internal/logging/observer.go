@@ -0,0 +1,170 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	xaierrors "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/telemetry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxBodyLength is the length beyond which logged bodies are truncated.
+const maxBodyLength = 8 << 10
+
+// Observer is a telemetry.Observer logging every call: its start at debug
+// level, failed attempts at warn level and its end at debug level, or warn
+// level if it failed. Request and response bodies are logged only if
+// logBodies is set.
+type Observer struct {
+	logger    *slog.Logger
+	logBodies bool
+}
+
+var _ telemetry.Observer = (*Observer)(nil)
+
+// NewObserver creates an observer logging to logger.
+func NewObserver(logger *slog.Logger, logBodies bool) *Observer {
+	return &Observer{logger: logger, logBodies: logBodies}
+}
+
+// StartCall logs the start of a call.
+func (o *Observer) StartCall(ctx context.Context, call telemetry.Call) (context.Context, telemetry.CallObserver) {
+	attrs := []slog.Attr{slog.String("method", method(call))}
+	if call.Model != "" {
+		attrs = append(attrs, slog.String("model", call.Model))
+	}
+	if call.Stream {
+		attrs = append(attrs, slog.Bool("stream", true))
+	}
+	if o.logBodies && call.Request != nil {
+		attrs = append(attrs, slog.String("request", body(call.Request)))
+	}
+	o.logger.LogAttrs(ctx, slog.LevelDebug, "xai call started", attrs...)
+	return ctx, &callObserver{observer: o, ctx: ctx, call: call}
+}
+
+// method names a call as "service/method" or "METHOD path".
+func method(call telemetry.Call) string {
+	if call.Transport == telemetry.TransportGRPC {
+		return call.Service + "/" + call.Method
+	}
+	return call.Service + " " + call.Method
+}
+
+// callObserver logs one call.
+type callObserver struct {
+	observer *Observer
+	ctx      context.Context
+	call     telemetry.Call
+}
+
+func (c *callObserver) AttemptFailed(attempt int, err error) {
+	c.observer.logger.LogAttrs(c.ctx, slog.LevelWarn, "xai call attempt failed",
+		slog.String("method", method(c.call)),
+		slog.Int("attempt", attempt),
+		slog.String("status", statusCode(err).String()),
+		slog.Any("error", err),
+	)
+}
+
+func (c *callObserver) End(result telemetry.Result) {
+	code := statusCode(result.Err)
+	attrs := append([]slog.Attr{
+		slog.String("method", method(c.call)),
+		slog.Duration("latency", result.Duration),
+		slog.String("status", code.String()),
+	}, c.resultAttrs(result)...)
+
+	level, msg := slog.LevelDebug, "xai call finished"
+	if c.call.Stream {
+		msg = "xai stream ended"
+	}
+	switch {
+	case result.Err == nil:
+	case code == codes.Canceled:
+		// Streams are commonly ended by canceling their context.
+		attrs = append(attrs, slog.Any("error", result.Err))
+	default:
+		level, msg = slog.LevelWarn, "xai call failed"
+		if c.call.Stream {
+			msg = "xai stream failed"
+		}
+		attrs = append(attrs, slog.Any("error", result.Err))
+	}
+	c.observer.logger.LogAttrs(c.ctx, level, msg, attrs...)
+}
+
+// resultAttrs returns the optional attributes of the outcome of a call.
+func (c *callObserver) resultAttrs(result telemetry.Result) []slog.Attr {
+	var attrs []slog.Attr
+	var apiErr *xaierrors.APIError
+	if errors.As(result.Err, &apiErr) && apiErr.HTTPStatus != 0 && c.call.Transport == telemetry.TransportHTTP {
+		attrs = append(attrs, slog.Int("http_status", apiErr.HTTPStatus))
+	}
+	if result.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", result.RequestID))
+	}
+	if result.ResponseID != "" {
+		attrs = append(attrs, slog.String("response_id", result.ResponseID))
+	}
+	if result.Attempts > 1 {
+		attrs = append(attrs, slog.Int("attempts", result.Attempts))
+	}
+	if usage := result.Usage; usage.InputTokens > 0 || usage.OutputTokens > 0 {
+		attrs = append(attrs, slog.Int64("input_tokens", usage.InputTokens), slog.Int64("output_tokens", usage.OutputTokens))
+	}
+	if result.TimeToFirstToken > 0 {
+		attrs = append(attrs, slog.Duration("time_to_first_token", result.TimeToFirstToken))
+	}
+	if c.observer.logBodies && result.Response != nil {
+		attrs = append(attrs, slog.String("response", body(result.Response)))
+	}
+	return attrs
+}
+
+// statusCode returns the gRPC status code of err, which API errors of both
+// transports carry, or of the context error.
+func statusCode(err error) codes.Code {
+	switch {
+	case err == nil:
+		return codes.OK
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	}
+	return status.Code(err)
+}
+
+// body formats a request or response body, truncated to maxBodyLength.
+func body(v any) string {
+	var s string
+	switch b := v.(type) {
+	case proto.Message:
+		data, err := protojson.Marshal(b)
+		if err != nil {
+			return "<" + err.Error() + ">"
+		}
+		s = string(data)
+	case json.RawMessage:
+		s = string(b)
+	case []byte:
+		s = string(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return "<" + err.Error() + ">"
+		}
+		s = string(data)
+	}
+	if len(s) > maxBodyLength {
+		s = s[:maxBodyLength] + "...(truncated)"
+	}
+	return s
+}
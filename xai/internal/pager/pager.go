@@ -0,0 +1,51 @@
+// Package pager provides auto-paginating iterators over token-paginated list APIs.
+package pager
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// FetchFunc fetches the page identified by token and returns its items together
+// with the token of the next page. An empty next token marks the last page.
+type FetchFunc[T any] func(ctx context.Context, token string) ([]T, string, error)
+
+// All returns an iterator over every item of every page, starting at token.
+// Pages are fetched lazily as the iterator advances. Iteration stops after the
+// first error, which is yielded with the zero value of T, including the context
+// error when ctx is cancelled between pages. Breaking out of the loop stops
+// fetching immediately.
+func All[T any](ctx context.Context, token string, fetch FetchFunc[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		seen := make(map[string]struct{})
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+
+			items, next, err := fetch(ctx, token)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if next == "" {
+				return
+			}
+			seen[token] = struct{}{}
+			if _, ok := seen[next]; ok {
+				yield(zero, fmt.Errorf("pagination token %q repeated", next))
+				return
+			}
+			token = next
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package pager
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func pages(calls *[]string, data map[string][]int, next map[string]string) FetchFunc[int] {
+	return func(_ context.Context, token string) ([]int, string, error) {
+		*calls = append(*calls, token)
+		return data[token], next[token], nil
+	}
+}
+
+func TestAllFollowsTokens(t *testing.T) {
+	var calls []string
+	fetch := pages(&calls,
+		map[string][]int{"": {1, 2}, "p2": {3}, "p3": {4, 5}},
+		map[string]string{"": "p2", "p2": "p3"},
+	)
+
+	var got []int
+	for item, err := range All(context.Background(), "", fetch) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, item)
+	}
+	if len(got) != 5 || got[4] != 5 {
+		t.Errorf("items = %v", got)
+	}
+	if len(calls) != 3 {
+		t.Errorf("fetches = %v, want 3", calls)
+	}
+}
+
+func TestAllEarlyBreakStopsFetching(t *testing.T) {
+	var calls []string
+	fetch := pages(&calls,
+		map[string][]int{"": {1, 2}, "p2": {3}},
+		map[string]string{"": "p2"},
+	)
+
+	for item := range All(context.Background(), "", fetch) {
+		if item == 1 {
+			break
+		}
+	}
+	if len(calls) != 1 {
+		t.Errorf("fetches = %v, want 1", calls)
+	}
+}
+
+func TestAllStopsOnErrorAndCancellation(t *testing.T) {
+	boom := errors.New("boom")
+	fetch := func(context.Context, string) ([]int, string, error) { return nil, "", boom }
+	for _, err := range All(context.Background(), "", fetch) {
+		if !errors.Is(err, boom) {
+			t.Errorf("err = %v, want boom", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls []string
+	fetch = pages(&calls, map[string][]int{"": {1}, "p2": {2}}, map[string]string{"": "p2"})
+	var gotErr error
+	for item, err := range All(ctx, "", fetch) {
+		if err != nil {
+			gotErr = err
+			break
+		}
+		if item == 1 {
+			cancel()
+		}
+	}
+	if !errors.Is(gotErr, context.Canceled) || len(calls) != 1 {
+		t.Errorf("err = %v, fetches = %v", gotErr, calls)
+	}
+}
+
+func TestAllDetectsRepeatedToken(t *testing.T) {
+	var calls []string
+	fetch := pages(&calls, map[string][]int{"a": {1}}, map[string]string{"a": "a"})
+	var gotErr error
+	for _, err := range All(context.Background(), "a", fetch) {
+		gotErr = err
+	}
+	if gotErr == nil {
+		t.Error("expected error for repeated pagination token")
+	}
+}
@@ -4,13 +4,22 @@ package rest
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"sync"
 	"time"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai/credentials"
+	xaierrors "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/ratelimit"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/retry"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/telemetry"
 )
 
 // Buffer pool for JSON encoding to reduce allocations
@@ -27,10 +36,13 @@ const (
 
 // Client is a REST client for xAI APIs.
 type Client struct {
-	httpClient *http.Client
-	baseURL    string
-	apiKey     string
-	userAgent  string
+	httpClient  *http.Client
+	transport   *http.Transport
+	baseURL     string
+	credentials credentials.Provider
+	userAgent   string
+	retry       retry.Policy
+	observer    telemetry.Observer
 }
 
 // Config contains configuration for the REST client.
@@ -39,8 +51,28 @@ type Config struct {
 	APIKey    string
 	UserAgent string
 	Timeout   time.Duration
+	// Retry is the retry policy; the zero value disables retries.
+	Retry retry.Policy
+	// RateLimiter paces the requests sent, if set.
+	RateLimiter *ratelimit.Limiter
+	// Credentials provides the API key of each request; it defaults to APIKey.
+	Credentials credentials.Provider
+	// TLSConfig is the TLS configuration of the connections, if set.
+	TLSConfig *tls.Config
+	// Proxy selects the proxy of each request; it defaults to
+	// http.ProxyFromEnvironment.
+	Proxy func(*http.Request) (*url.URL, error)
+	// HTTPClient sends the requests instead of a client built from the
+	// settings above. Its Timeout and Transport are used as is.
+	HTTPClient *http.Client
+	// Observer is notified of every request, if set.
+	Observer telemetry.Observer
 }
 
+// maxCredentialAttempts bounds the attempts of a request failing over between
+// the keys of a credentials provider.
+const maxCredentialAttempts = 4
+
 // NewClient creates a new REST client with optimized connection pooling.
 func NewClient(cfg Config) *Client {
 	if cfg.BaseURL == "" {
@@ -53,8 +85,52 @@ func NewClient(cfg Config) *Client {
 		cfg.UserAgent = "xai-sdk-go"
 	}
 
-	// Create optimized HTTP transport with connection pooling
-	transport := &http.Transport{
+	provider := cfg.Credentials
+	if provider == nil && cfg.APIKey != "" {
+		provider = credentials.Static(cfg.APIKey)
+	}
+
+	client := &Client{
+		baseURL:     cfg.BaseURL,
+		credentials: provider,
+		userAgent:   cfg.UserAgent,
+		retry:       cfg.Retry,
+		observer:    cfg.Observer,
+	}
+
+	if cfg.HTTPClient != nil {
+		httpClient := *cfg.HTTPClient
+		client.httpClient = &httpClient
+	} else {
+		client.transport = newTransport(cfg)
+		client.httpClient = &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: client.transport,
+		}
+	}
+
+	if cfg.RateLimiter != nil {
+		base := client.httpClient.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		client.httpClient.Transport = cfg.RateLimiter.RoundTripper(base)
+	}
+
+	return client
+}
+
+// newTransport creates an HTTP transport with connection pooling.
+func newTransport(cfg Config) *http.Transport {
+	proxy := cfg.Proxy
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+
+	return &http.Transport{
+		Proxy:           proxy,
+		TLSClientConfig: cfg.TLSConfig.Clone(),
+
 		// Connection pooling settings
 		MaxIdleConns:        100,              // Maximum idle connections across all hosts
 		MaxIdleConnsPerHost: 10,               // Maximum idle connections per host
@@ -74,16 +150,6 @@ func NewClient(cfg Config) *Client {
 		DisableCompression: false, // Enable gzip compression
 		ForceAttemptHTTP2:  true,  // Use HTTP/2 when available
 	}
-
-	return &Client{
-		httpClient: &http.Client{
-			Timeout:   cfg.Timeout,
-			Transport: transport,
-		},
-		baseURL:   cfg.BaseURL,
-		apiKey:    cfg.APIKey,
-		userAgent: cfg.UserAgent,
-	}
 }
 
 // Request represents an HTTP request.
@@ -101,32 +167,179 @@ type Response struct {
 	Headers    http.Header
 }
 
-// Do executes an HTTP request.
+// Do executes an HTTP request. Failed requests are retried according to the
+// client's retry policy, which can be overridden per call with retry.WithPolicy.
+// GET, HEAD, PUT, DELETE and OPTIONS requests, and requests carrying an
+// Idempotency-Key header, are idempotent.
 func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
+	body, err := encodeBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, tracker := telemetry.Start(ctx, c.observer, httpCall(req, body))
+
+	policy := retry.PolicyFromContext(ctx, c.retry)
+	idempotent := retry.IdempotentFromContext(ctx, isIdempotent(req))
+
+	var resp *Response
+	var header http.Header
+	classify := func(err error) (bool, time.Duration) {
+		return classifyAttempt(err, header, idempotent)
+	}
+	err = retry.Do(ctx, policy, classify, func() error {
+		n := tracker.StartAttempt()
+		attemptResp, attemptErr := c.attempt(ctx, req, body)
+		resp, header = attemptResp, nil
+		if attemptResp != nil {
+			header = attemptResp.Headers
+			tracker.SetRequestID(header.Values)
+		}
+		tracker.AttemptFailed(n, attemptErr)
+		return attemptErr
+	})
+	if err == nil {
+		tracker.ObserveJSON(resp.Body)
+	}
+	tracker.End(err)
+	return resp, err
+}
+
+// classifyAttempt classifies the error of a failed attempt. header holds the
+// response headers of the attempt, if a response was received.
+func classifyAttempt(err error, header http.Header, idempotent bool) (bool, time.Duration) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return retry.ClassifyHTTP(httpErr.StatusCode, header, idempotent)
+	}
+	if errors.Is(err, ratelimit.ErrLimited) {
+		return false, 0
+	}
+	return retry.ClassifyNetwork(err, idempotent), 0
+}
+
+// restOperations are the GenAI operations of the paths using a model.
+var restOperations = map[string]string{
+	"/chat/completions":   telemetry.OperationChat,
+	"/completions":        telemetry.OperationTextCompletion,
+	"/embeddings":         telemetry.OperationEmbeddings,
+	"/images/generations": telemetry.OperationGenerateContent,
+}
+
+// httpCall describes req for telemetry.
+func httpCall(req Request, body []byte) telemetry.Call {
+	call := telemetry.Call{
+		Transport: telemetry.TransportHTTP,
+		Service:   req.Method,
+		Method:    req.Path,
+		Operation: restOperations[req.Path],
+	}
+	if len(body) > 0 {
+		call.Request = json.RawMessage(body)
+	}
+	if call.Operation != "" && len(body) > 0 {
+		var fields struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		if json.Unmarshal(body, &fields) == nil {
+			call.Model, call.Stream = fields.Model, fields.Stream
+		}
+	}
+	return call
+}
+
+// encodeBody returns the encoded request body, or nil if there is none.
+func encodeBody(value interface{}) ([]byte, error) {
+	switch value := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return value, nil
+	case json.RawMessage:
+		return value, nil
+	}
+
+	// Use buffer pool to reduce allocations
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+
+	if err := json.NewEncoder(buf).Encode(value); err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	// Create a copy since buf will be returned to pool
+	bodyBytes := make([]byte, buf.Len())
+	copy(bodyBytes, buf.Bytes())
+	return bodyBytes, nil
+}
+
+// isIdempotent reports whether req can safely be sent more than once.
+func isIdempotent(req Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	for key := range req.Headers {
+		if http.CanonicalHeaderKey(key) == "Idempotency-Key" {
+			return true
+		}
+	}
+	return false
+}
+
+// attempt performs req, failing over at once to another API key when the
+// credentials provider rejects the one used and offers another.
+func (c *Client) attempt(ctx context.Context, req Request, body []byte) (*Response, error) {
+	var resp *Response
+	err := c.withAPIKey(ctx, func(apiKey string) error {
+		var err error
+		resp, err = c.do(ctx, req, body, apiKey)
+		return err
+	})
+	return resp, err
+}
+
+// withAPIKey calls send with the API key of a request, calling it again at
+// once with another key when the credentials provider rejects the one used
+// and offers another.
+func (c *Client) withAPIKey(ctx context.Context, send func(apiKey string) error) error {
+	for n := 1; ; n++ {
+		apiKey := ""
+		if c.credentials != nil {
+			var err error
+			if apiKey, err = c.credentials.APIKey(ctx); err != nil {
+				return fmt.Errorf("failed to get API key: %w", err)
+			}
+		}
+		err := send(apiKey)
+		if c.credentials == nil || !credentials.Report(c.credentials, apiKey, err) || n == maxCredentialAttempts {
+			return err
+		}
+	}
+}
+
+// do performs a single attempt of req.
+func (c *Client) do(ctx context.Context, req Request, bodyBytes []byte, apiKey string) (*Response, error) {
+	httpResp, err := c.send(ctx, c.httpClient, req, bodyBytes, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = httpResp.Body.Close()
+	}()
+	return readResponse(httpResp)
+}
+
+// send sends req with httpClient and returns the response with its body
+// unread.
+func (c *Client) send(ctx context.Context, httpClient *http.Client, req Request, bodyBytes []byte, apiKey string) (*http.Response, error) {
 	url := c.baseURL + req.Path
 
 	var body io.Reader
 	if req.Body != nil {
-		switch value := req.Body.(type) {
-		case []byte:
-			body = bytes.NewReader(value)
-		case json.RawMessage:
-			body = bytes.NewReader(value)
-		default:
-			// Use buffer pool to reduce allocations
-			buf := bufferPool.Get().(*bytes.Buffer)
-			buf.Reset()
-			defer bufferPool.Put(buf)
-
-			if err := json.NewEncoder(buf).Encode(req.Body); err != nil {
-				return nil, fmt.Errorf("failed to marshal request body: %w", err)
-			}
-
-			// Create a copy since buf will be returned to pool
-			bodyBytes := make([]byte, buf.Len())
-			copy(bodyBytes, buf.Bytes())
-			body = bytes.NewReader(bodyBytes)
-		}
+		body = bytes.NewReader(bodyBytes)
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, req.Method, url, body)
@@ -137,8 +350,8 @@ func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
 	// Set default headers
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("User-Agent", c.userAgent)
-	if c.apiKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
 	}
 
 	// Set custom headers
@@ -146,14 +359,16 @@ func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
 		httpReq.Header.Set(key, value)
 	}
 
-	httpResp, err := c.httpClient.Do(httpReq)
+	httpResp, err := httpClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
-	defer func() {
-		_ = httpResp.Body.Close()
-	}()
+	return httpResp, nil
+}
 
+// readResponse reads the body of httpResp, returning an error for HTTP
+// error statuses.
+func readResponse(httpResp *http.Response) (*Response, error) {
 	// Limit response size to prevent memory exhaustion
 	limitedReader := io.LimitReader(httpResp.Body, MaxResponseSize)
 	respBody, err := io.ReadAll(limitedReader)
@@ -167,17 +382,70 @@ func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
 		Headers:    httpResp.Header,
 	}
 
-	// Check for HTTP errors
+	// Check for HTTP errors; the *HTTPError stays reachable through errors.As
 	if httpResp.StatusCode >= 400 {
-		return resp, &HTTPError{
+		httpErr := &HTTPError{
 			StatusCode: httpResp.StatusCode,
 			Body:       respBody,
 		}
+		return resp, xaierrors.FromHTTP(httpResp.StatusCode, httpResp.Header, respBody, httpErr)
 	}
 
 	return resp, nil
 }
 
+// Stream executes req like Do but returns the response with its body unread
+// and not limited to MaxResponseSize, for downloads. Retries stop once the
+// response headers are received, and the client's timeout does not apply to
+// reading the body: ctx bounds it. The caller must close the body.
+func (c *Client) Stream(ctx context.Context, req Request) (*http.Response, error) {
+	body, err := encodeBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, tracker := telemetry.Start(ctx, c.observer, httpCall(req, body))
+
+	policy := retry.PolicyFromContext(ctx, c.retry)
+	idempotent := retry.IdempotentFromContext(ctx, isIdempotent(req))
+
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+
+	var resp *http.Response
+	var header http.Header
+	classify := func(err error) (bool, time.Duration) {
+		return classifyAttempt(err, header, idempotent)
+	}
+	err = retry.Do(ctx, policy, classify, func() error {
+		n := tracker.StartAttempt()
+		resp, header = nil, nil
+		attemptErr := c.withAPIKey(ctx, func(apiKey string) error {
+			httpResp, err := c.send(ctx, &httpClient, req, body, apiKey)
+			if err != nil {
+				return err
+			}
+			header = httpResp.Header
+			if httpResp.StatusCode >= 400 {
+				defer func() {
+					_ = httpResp.Body.Close()
+				}()
+				_, err := readResponse(httpResp)
+				return err
+			}
+			resp = httpResp
+			return nil
+		})
+		if header != nil {
+			tracker.SetRequestID(header.Values)
+		}
+		tracker.AttemptFailed(n, attemptErr)
+		return attemptErr
+	})
+	tracker.End(err)
+	return resp, err
+}
+
 // Get executes a GET request.
 func (c *Client) Get(ctx context.Context, path string) (*Response, error) {
 	return c.Do(ctx, Request{
@@ -215,10 +483,9 @@ func (c *Client) Delete(ctx context.Context, path string) (*Response, error) {
 // Close closes idle connections in the HTTP client's connection pool.
 // This should be called when the client is no longer needed to free resources.
 func (c *Client) Close() {
-	if c.httpClient != nil && c.httpClient.Transport != nil {
-		if transport, ok := c.httpClient.Transport.(*http.Transport); ok {
-			transport.CloseIdleConnections()
-		}
+	// A caller-supplied HTTP client is left alone.
+	if c.transport != nil {
+		c.transport.CloseIdleConnections()
 	}
 }
 
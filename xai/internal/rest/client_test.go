@@ -0,0 +1,211 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai/credentials"
+	xaierrors "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/retry"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/telemetry"
+)
+
+var testPolicy = retry.Policy{MaxRetries: 2, InitialBackoff: time.Millisecond, Jitter: -1}
+
+// failingServer responds with the given statuses in order, then 200 with the
+// request body echoed back.
+func failingServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestDoRetriesRejectedRequests(t *testing.T) {
+	server, calls := failingServer(t, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	client := NewClient(Config{BaseURL: server.URL, Retry: testPolicy})
+
+	resp, err := client.Post(context.Background(), "/echo", []byte(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if calls.Load() != 3 || string(resp.Body) != `{"a":1}` {
+		t.Errorf("calls = %d, body = %q; the body must be resent on each attempt", calls.Load(), resp.Body)
+	}
+}
+
+func TestDoRetriesServerErrorsOnlyWhenIdempotent(t *testing.T) {
+	server, calls := failingServer(t, http.StatusInternalServerError)
+	client := NewClient(Config{BaseURL: server.URL, Retry: testPolicy})
+
+	_, err := client.Post(context.Background(), "/create", []byte(`{}`))
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusInternalServerError || calls.Load() != 1 {
+		t.Fatalf("POST: err = %v after %d calls, want one 500", err, calls.Load())
+	}
+
+	calls.Store(0)
+	if _, err := client.Get(context.Background(), "/resource"); err != nil || calls.Load() != 2 {
+		t.Errorf("GET: err = %v after %d calls, want success after 2", err, calls.Load())
+	}
+
+	calls.Store(0)
+	ctx := retry.WithIdempotent(context.Background(), true)
+	if _, err := client.Post(ctx, "/create", []byte(`{}`)); err != nil || calls.Load() != 2 {
+		t.Errorf("idempotent POST: err = %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestDoPerCallOverride(t *testing.T) {
+	server, calls := failingServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	// No retries by default.
+	client := NewClient(Config{BaseURL: server.URL})
+	if _, err := client.Get(context.Background(), "/resource"); err == nil || calls.Load() != 1 {
+		t.Fatalf("default: err = %v after %d calls", err, calls.Load())
+	}
+
+	calls.Store(0)
+	ctx := retry.WithPolicy(context.Background(), testPolicy)
+	if _, err := client.Get(ctx, "/resource"); err != nil || calls.Load() != 3 {
+		t.Errorf("override: err = %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestDoReturnsAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Request-Id", "req-1")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"file not found"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(Config{BaseURL: server.URL}).Get(context.Background(), "/files/missing")
+	var apiErr *xaierrors.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Get() error = %v, want *APIError", err)
+	}
+	if apiErr.HTTPStatus != http.StatusNotFound || apiErr.Message != "file not found" || apiErr.RequestID != "req-1" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+	var httpErr *HTTPError
+	if !errors.Is(err, xaierrors.ErrNotFound) || !errors.As(err, &httpErr) {
+		t.Error("the error should match ErrNotFound and unwrap to *HTTPError")
+	}
+}
+
+func TestStream(t *testing.T) {
+	server, calls := failingServer(t, http.StatusServiceUnavailable, http.StatusNotFound)
+	client := NewClient(Config{BaseURL: server.URL, Retry: testPolicy, Timeout: time.Nanosecond})
+
+	// Retried until the headers of the 404
+	_, err := client.Stream(context.Background(), Request{Method: http.MethodGet, Path: "/files/missing/content"})
+	if !errors.Is(err, xaierrors.ErrNotFound) || calls.Load() != 2 {
+		t.Fatalf("Stream() error = %v after %d calls, want 404 after 2", err, calls.Load())
+	}
+
+	// The client timeout does not apply to streamed bodies
+	resp, err := client.Stream(context.Background(), Request{Method: http.MethodPost, Path: "/echo", Body: []byte("streamed")})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if body, err := io.ReadAll(resp.Body); err != nil || string(body) != "streamed" {
+		t.Errorf("body = %q, %v", body, err)
+	}
+}
+
+func TestDoFailsOverBetweenPooledKeys(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		keys = append(keys, auth)
+		if auth != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	pool := credentials.NewPool([]string{"revoked", "good"})
+	client := NewClient(Config{BaseURL: server.URL, Credentials: pool})
+	if _, err := client.Get(context.Background(), "/models"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "Bearer revoked" {
+		t.Errorf("keys sent = %v, want failover from the revoked key", keys)
+	}
+
+	// The revoked key stays set aside.
+	keys = nil
+	if _, err := client.Get(context.Background(), "/models"); err != nil || len(keys) != 1 {
+		t.Errorf("second Get(): err = %v, keys sent = %v", err, keys)
+	}
+}
+
+// recordingObserver records the calls it sees.
+type recordingObserver struct {
+	calls    []telemetry.Call
+	failures int
+	results  []telemetry.Result
+}
+
+func (o *recordingObserver) StartCall(ctx context.Context, call telemetry.Call) (context.Context, telemetry.CallObserver) {
+	o.calls = append(o.calls, call)
+	return ctx, o
+}
+
+func (o *recordingObserver) AttemptFailed(int, error) { o.failures++ }
+
+func (o *recordingObserver) End(result telemetry.Result) { o.results = append(o.results, result) }
+
+func TestDoReportsToObserver(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("X-Request-Id", fmt.Sprintf("req-%d", n))
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"model":"grok-embed","usage":{"prompt_tokens":4}}`))
+	}))
+	defer server.Close()
+
+	observer := &recordingObserver{}
+	client := NewClient(Config{BaseURL: server.URL, Retry: testPolicy, Observer: observer})
+	if _, err := client.Post(context.Background(), "/embeddings", []byte(`{"model":"grok-embed","input":["hi"]}`)); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+
+	if len(observer.calls) != 1 || len(observer.results) != 1 {
+		t.Fatalf("calls = %d, results = %d", len(observer.calls), len(observer.results))
+	}
+	call, result := observer.calls[0], observer.results[0]
+	if call.Operation != telemetry.OperationEmbeddings || call.Model != "grok-embed" || call.Service != http.MethodPost {
+		t.Errorf("call = %+v", call)
+	}
+	if result.Attempts != 2 || observer.failures != 1 || result.RequestID != "req-2" || result.Usage.InputTokens != 4 {
+		t.Errorf("result = %+v after %d failures", result, observer.failures)
+	}
+}
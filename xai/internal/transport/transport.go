@@ -0,0 +1,120 @@
+// Package transport builds the TLS, proxy and dialing settings shared by the
+// gRPC and REST connections.
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Options are the network settings of a client.
+type Options struct {
+	// ProxyURL is an http, https, socks5 or socks5h URL. If empty, the
+	// HTTPS_PROXY and HTTP_PROXY environment variables are used. NO_PROXY
+	// is honored in both cases.
+	ProxyURL string
+
+	// CAFiles are PEM files of certificate authorities trusted in addition to
+	// the system roots.
+	CAFiles []string
+
+	// CertFile and KeyFile are the PEM client certificate and key for mutual TLS.
+	CertFile string
+	KeyFile  string
+
+	// SkipVerify disables server certificate verification.
+	SkipVerify bool
+
+	// Base is the TLS configuration to start from, if any.
+	Base *tls.Config
+}
+
+// TLSConfig returns the TLS configuration for the options.
+func (o Options) TLSConfig() (*tls.Config, error) {
+	var cfg *tls.Config
+	if o.Base != nil {
+		cfg = o.Base.Clone()
+	} else {
+		cfg = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: o.SkipVerify, //nolint:gosec
+		}
+	}
+
+	if len(o.CAFiles) > 0 {
+		pool := cfg.RootCAs
+		if pool == nil {
+			var err error
+			if pool, err = x509.SystemCertPool(); err != nil {
+				pool = x509.NewCertPool()
+			}
+		} else {
+			pool = pool.Clone()
+		}
+		for _, path := range o.CAFiles {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+			}
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, fmt.Errorf("client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+	return cfg, nil
+}
+
+// Proxy returns the function selecting the proxy of each request, for
+// http.Transport.Proxy.
+func (o Options) Proxy() (func(*http.Request) (*url.URL, error), error) {
+	cfg := httpproxy.FromEnvironment()
+	if o.ProxyURL != "" {
+		if _, err := ParseProxyURL(o.ProxyURL); err != nil {
+			return nil, err
+		}
+		cfg.HTTPProxy = o.ProxyURL
+		cfg.HTTPSProxy = o.ProxyURL
+	}
+	proxyFunc := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
+
+// ProxyFor returns the proxy of connections to the host:port address using
+// the given scheme, or nil if they are direct.
+func ProxyFor(proxy func(*http.Request) (*url.URL, error), scheme, addr string) (*url.URL, error) {
+	return proxy(&http.Request{URL: &url.URL{Scheme: scheme, Host: addr}})
+}
+
+// ParseProxyURL parses a proxy URL and checks that its scheme is supported.
+func ParseProxyURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (want http, https, socks5 or socks5h)", u.Scheme)
+	}
+}
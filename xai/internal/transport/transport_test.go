@@ -0,0 +1,183 @@
+package transport
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTLSConfigTrustsCABundle(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(opts Options) error {
+		cfg, err := opts.TLSConfig()
+		if err != nil {
+			return err
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(Options{}); err == nil {
+		t.Error("the test server's certificate should not be trusted by default")
+	}
+	if err := get(Options{CAFiles: []string{caFile}}); err != nil {
+		t.Errorf("with the CA bundle: %v", err)
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for name, opts := range map[string]Options{
+		"missing CA":       {CAFiles: []string{filepath.Join(t.TempDir(), "missing.pem")}},
+		"empty CA":         {CAFiles: []string{empty}},
+		"cert without key": {CertFile: empty},
+		"invalid keypair":  {CertFile: empty, KeyFile: empty},
+	} {
+		if _, err := opts.TLSConfig(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+	t.Setenv("NO_PROXY", "internal.example.com")
+
+	proxy, err := Options{}.Proxy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := ProxyFor(proxy, "https", "api.x.ai:443"); u == nil || u.Host != "env-proxy:3128" {
+		t.Errorf("proxy from environment = %v", u)
+	}
+
+	proxy, err = Options{ProxyURL: "socks5://corp-proxy:1080"}.Proxy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := ProxyFor(proxy, "https", "api.x.ai:443"); u == nil || u.Scheme != "socks5" {
+		t.Errorf("explicit proxy = %v", u)
+	}
+	if u, _ := ProxyFor(proxy, "https", "internal.example.com:443"); u != nil {
+		t.Errorf("NO_PROXY host should be direct, got %v", u)
+	}
+
+	if _, err := (Options{ProxyURL: "ftp://proxy"}).Proxy(); err == nil {
+		t.Error("unsupported proxy scheme should be rejected")
+	}
+}
+
+func TestDialerTunnelsThroughConnect(t *testing.T) {
+	// The target echoes what it receives.
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = target.Close() }()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	connects := make(chan *http.Request, 1)
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connects <- r
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			_ = upstream.Close()
+			return
+		}
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			_ = upstream.Close()
+		}()
+		_, _ = io.Copy(conn, upstream)
+		_ = conn.Close()
+	}))
+	defer proxyServer.Close()
+
+	proxyURL, _ := url.Parse(proxyServer.URL)
+	proxyURL.User = url.UserPassword("user", "secret")
+	dial, err := Dialer(proxyURL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dial(ctx, target.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil || string(reply) != "ping" {
+		t.Errorf("reply = %q, %v", reply, err)
+	}
+	r := <-connects
+	if r.Host != target.Addr().String() || r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpzZWNyZXQ=" {
+		t.Errorf("CONNECT host = %q, Proxy-Authorization = %q", r.Host, r.Header.Get("Proxy-Authorization"))
+	}
+}
+
+func TestDialerReportsRefusedConnect(t *testing.T) {
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusProxyAuthRequired)
+	}))
+	defer proxyServer.Close()
+
+	proxyURL, _ := url.Parse(proxyServer.URL)
+	dial, err := Dialer(proxyURL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dial(context.Background(), "api.x.ai:443"); err == nil {
+		t.Error("expected an error when the proxy refuses CONNECT")
+	}
+}
@@ -0,0 +1,251 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// charsPerToken approximates the number of bytes of prompt per token.
+const charsPerToken = 4
+
+type modelRequest interface {
+	GetModel() string
+}
+
+type maxTokensRequest interface {
+	GetMaxTokens() int32
+}
+
+type usageResponse interface {
+	GetUsage() *xaiv1.SamplingUsage
+}
+
+// keyForMethod returns the key of a gRPC call.
+func keyForMethod(method string, req interface{}) Key {
+	service := strings.TrimPrefix(method, "/")
+	if i := strings.IndexByte(service, '/'); i >= 0 {
+		service = service[:i]
+	}
+	service = strings.ToLower(service[strings.LastIndexByte(service, '.')+1:])
+
+	key := Key{Service: service}
+	if m, ok := req.(modelRequest); ok {
+		key.Model = m.GetModel()
+	}
+	return key
+}
+
+// estimateProto estimates the tokens of a gRPC call from the size of its
+// request plus the completion tokens it may generate.
+func estimateProto(req interface{}) int {
+	tokens := 0
+	if m, ok := req.(proto.Message); ok {
+		tokens = proto.Size(m) / charsPerToken
+	}
+	if m, ok := req.(maxTokensRequest); ok {
+		tokens += int(m.GetMaxTokens())
+	}
+	return tokens
+}
+
+// usageTokens returns the total tokens reported by a response, or -1.
+func usageTokens(reply interface{}) int {
+	if r, ok := reply.(usageResponse); ok && r.GetUsage() != nil {
+		return int(r.GetUsage().GetTotalTokens())
+	}
+	return -1
+}
+
+// observe adapts the buckets of key to the metadata and error of a gRPC call.
+func (l *Limiter) observe(key Key, err error, md ...metadata.MD) {
+	header := http.Header{}
+	for _, m := range md {
+		for name, values := range m {
+			for _, value := range values {
+				header.Add(name, value)
+			}
+		}
+	}
+	l.Update(key, header)
+
+	if status.Code(err) == codes.ResourceExhausted {
+		var trailer metadata.MD
+		if len(md) > 0 {
+			trailer = md[len(md)-1]
+		}
+		if after, ok := retry.Pushback(err, trailer); ok {
+			l.Pause(key, after)
+		}
+	}
+}
+
+// UnaryInterceptor returns a unary interceptor that paces calls.
+func (l *Limiter) UnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		key := keyForMethod(method, req)
+		res, err := l.Acquire(ctx, key, estimateProto(req))
+		if err != nil {
+			return err
+		}
+
+		var header, trailer metadata.MD
+		err = invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header), grpc.Trailer(&trailer))...)
+		if err != nil {
+			// Rejected calls do not consume tokens.
+			res.Reconcile(0)
+		} else {
+			res.Reconcile(usageTokens(reply))
+		}
+		// The budget reported by the server already accounts for this call.
+		l.observe(key, err, header, trailer)
+		return err
+	}
+}
+
+// StreamInterceptor returns a stream interceptor that paces calls. The
+// estimate is reconciled with the last usage received on the stream.
+//
+// Server-streaming calls are opened when their request is sent, so that they
+// are paced by the model it names. Client-streaming calls are only paced by
+// the limits of their service.
+func (l *Limiter) StreamInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		s := &limitedStream{limiter: l, ctx: ctx, usage: -1}
+		s.open = func(req interface{}) error {
+			s.key = keyForMethod(method, req)
+			res, err := l.Acquire(ctx, s.key, estimateProto(req))
+			if err != nil {
+				return err
+			}
+			stream, err := streamer(ctx, desc, cc, method, opts...)
+			if err != nil {
+				l.observe(s.key, err)
+				res.Reconcile(0)
+				return err
+			}
+			s.res, s.ClientStream = res, stream
+			return nil
+		}
+		if desc.ClientStreams {
+			if err := s.open(nil); err != nil {
+				return nil, err
+			}
+			s.open = nil
+		}
+		return s, nil
+	}
+}
+
+// limitedStream acquires its budget when its request is sent and reconciles
+// it when the stream ends.
+type limitedStream struct {
+	grpc.ClientStream
+
+	limiter *Limiter
+	ctx     context.Context
+	key     Key
+	res     *Reservation
+	// open opens a server-streaming call with its request; it is nil once the
+	// stream is open.
+	open func(req interface{}) error
+	// openErr is the error with which the stream failed to open.
+	openErr error
+	// usage is the last total token count received, or -1.
+	usage    int
+	received bool
+	finished bool
+}
+
+func (s *limitedStream) SendMsg(m interface{}) error {
+	if s.open != nil {
+		open := s.open
+		s.open = nil
+		if err := open(m); err != nil {
+			s.openErr = err
+			return err
+		}
+	}
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *limitedStream) Context() context.Context {
+	if s.ClientStream == nil {
+		return s.ctx
+	}
+	return s.ClientStream.Context()
+}
+
+// Header, Trailer and RecvMsg of a stream that failed to open report the
+// error with which it failed.
+func (s *limitedStream) Header() (metadata.MD, error) {
+	if s.ClientStream == nil {
+		return nil, s.openErr
+	}
+	return s.ClientStream.Header()
+}
+
+func (s *limitedStream) Trailer() metadata.MD {
+	if s.ClientStream == nil {
+		return nil
+	}
+	return s.ClientStream.Trailer()
+}
+
+func (s *limitedStream) RecvMsg(m interface{}) error {
+	if s.ClientStream == nil {
+		return s.openErr
+	}
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.finish(err)
+		return err
+	}
+	s.received = true
+	if tokens := usageTokens(m); tokens >= 0 {
+		s.usage = tokens
+	}
+	return nil
+}
+
+func (s *limitedStream) finish(err error) {
+	if s.finished || s.res == nil {
+		return
+	}
+	s.finished = true
+
+	if err == io.EOF {
+		err = nil
+	}
+	switch {
+	case s.usage >= 0:
+		s.res.Reconcile(s.usage)
+	case err != nil && !s.received:
+		s.res.Reconcile(0)
+	}
+	header, _ := s.Header()
+	s.limiter.observe(s.key, err, header, s.Trailer())
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+func tokenLevel(l *Limiter, key Key) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buckets[key].tokens.level
+}
+
+func TestUnaryInterceptorReconcilesUsage(t *testing.T) {
+	l, _ := newTestLimiter(Config{Default: Limits{TokensPerMinute: 1000}})
+	req := &xaiv1.GetCompletionsRequest{Model: "grok-4", MaxTokens: ptr(int32(200))}
+	key := Key{Service: "chat", Model: "grok-4"}
+
+	invoker := func(_ context.Context, _ string, _, reply interface{}, _ *grpc.ClientConn, opts ...grpc.CallOption) error {
+		reply.(*xaiv1.GetChatCompletionResponse).Usage = &xaiv1.SamplingUsage{TotalTokens: 50}
+		for _, opt := range opts {
+			if trailer, ok := opt.(grpc.TrailerCallOption); ok {
+				*trailer.TrailerAddr = metadata.Pairs(HeaderRemainingTokens, "900")
+			}
+		}
+		return nil
+	}
+	reply := &xaiv1.GetChatCompletionResponse{}
+	if err := l.UnaryInterceptor()(context.Background(), "/xai_api.Chat/GetCompletion", req, reply, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+	// The estimate was replaced by the 50 tokens used, then capped by the trailer.
+	if level := tokenLevel(l, key); level != 900 {
+		t.Errorf("token level = %v, want 900", level)
+	}
+}
+
+// fakeStream replays chunks and then returns io.EOF.
+type fakeStream struct {
+	grpc.ClientStream
+	chunks []*xaiv1.GetChatCompletionChunk
+	sent   int
+}
+
+func (s *fakeStream) SendMsg(interface{}) error    { s.sent++; return nil }
+func (s *fakeStream) Header() (metadata.MD, error) { return nil, nil }
+func (s *fakeStream) Trailer() metadata.MD         { return nil }
+
+func (s *fakeStream) RecvMsg(m interface{}) error {
+	if len(s.chunks) == 0 {
+		return io.EOF
+	}
+	proto.Merge(m.(*xaiv1.GetChatCompletionChunk), s.chunks[0])
+	s.chunks = s.chunks[1:]
+	return nil
+}
+
+func TestStreamInterceptorOpensWithRequest(t *testing.T) {
+	l, _ := newTestLimiter(Config{Models: map[string]Limits{"grok-4": {TokensPerMinute: 1000}}})
+	stream := &fakeStream{chunks: []*xaiv1.GetChatCompletionChunk{{}, {Usage: &xaiv1.SamplingUsage{TotalTokens: 120}}}}
+	opened := 0
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		opened++
+		return stream, nil
+	}
+
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	cs, err := l.StreamInterceptor()(context.Background(), desc, nil, "/xai_api.Chat/GetCompletionChunk", streamer)
+	if err != nil || opened != 0 {
+		t.Fatalf("interceptor() = %v with %d streams opened before the request", err, opened)
+	}
+	if err := cs.SendMsg(&xaiv1.GetCompletionsRequest{Model: "grok-4"}); err != nil || opened != 1 || stream.sent != 1 {
+		t.Fatalf("SendMsg() = %v, opened = %d, sent = %d", err, opened, stream.sent)
+	}
+	for {
+		if err := cs.RecvMsg(&xaiv1.GetChatCompletionChunk{}); err != nil {
+			break
+		}
+	}
+	if level := tokenLevel(l, Key{Service: "chat", Model: "grok-4"}); level != 880 {
+		t.Errorf("token level = %v, want 880 after reconciling with the streamed usage", level)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
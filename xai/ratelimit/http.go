@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai/retry"
+)
+
+// maxUsageBody bounds the size of the responses inspected for usage.
+const maxUsageBody = 16 << 20
+
+// RoundTripper returns an http.RoundTripper that paces the requests sent
+// through base, or http.DefaultTransport if base is nil. The model and token
+// estimate of a request are taken from its JSON body, and the usage from the
+// JSON response.
+func (l *Limiter) RoundTripper(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &roundTripper{limiter: l, base: base}
+}
+
+type roundTripper struct {
+	limiter *Limiter
+	base    http.RoundTripper
+}
+
+// jsonRequest holds the fields of a JSON request body that are used for pacing.
+type jsonRequest struct {
+	Model     string `json:"model"`
+	MaxTokens int    `json:"max_tokens"`
+}
+
+// jsonUsage holds the usage of a JSON response.
+type jsonUsage struct {
+	Usage *struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+func (t *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, tokens := keyForRequest(req)
+	res, err := t.limiter.Acquire(req.Context(), key, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		res.Reconcile(0)
+		return nil, err
+	}
+
+	// The budget reported by the server already accounts for this request.
+	defer t.limiter.Update(key, resp.Header)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if after, ok := retry.ParseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			t.limiter.Pause(key, after)
+		}
+	}
+	if resp.StatusCode >= 400 {
+		res.Reconcile(0)
+		return resp, nil
+	}
+	if res.limiter != nil && strings.Contains(resp.Header.Get("Content-Type"), "json") && resp.ContentLength <= maxUsageBody {
+		body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxUsageBody))
+		// Hand the caller the bytes read followed by whatever was not.
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		var usage jsonUsage
+		if readErr == nil && json.Unmarshal(body, &usage) == nil && usage.Usage != nil {
+			res.Reconcile(usage.Usage.TotalTokens)
+		}
+	}
+	return resp, nil
+}
+
+// keyForRequest returns the key and token estimate of a REST request. The
+// service is the first segment of the path after the API version.
+func keyForRequest(req *http.Request) (Key, int) {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) > 1 && len(segments[0]) > 1 && segments[0][0] == 'v' && strings.Trim(segments[0][1:], "0123456789") == "" {
+		segments = segments[1:]
+	}
+	key := Key{Service: segments[0]}
+
+	if req.GetBody == nil || !strings.Contains(req.Header.Get("Content-Type"), "json") {
+		return key, 0
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return key, 0
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return key, 0
+	}
+	var fields jsonRequest
+	if json.Unmarshal(data, &fields) == nil {
+		key.Model = fields.Model
+	}
+	return key, len(data)/charsPerToken + fields.MaxTokens
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoundTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(HeaderLimitRequests, "1")
+		w.Header().Set(HeaderRemainingRequests, "0")
+		_, _ = w.Write([]byte(`{"data":[],"usage":{"total_tokens":7}}`))
+	}))
+	defer server.Close()
+
+	l, _ := newTestLimiter(Config{Services: map[string]Limits{"embeddings": {TokensPerMinute: 100}}})
+	client := &http.Client{Transport: l.RoundTripper(nil)}
+	post := func() (*http.Response, error) {
+		req, _ := http.NewRequestWithContext(WithFailFast(context.Background()), http.MethodPost, server.URL+"/v1/embeddings", http.NoBody)
+		req.Header.Set("Content-Type", "application/json")
+		return client.Do(req)
+	}
+
+	resp, err := post()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != `{"data":[],"usage":{"total_tokens":7}}` {
+		t.Errorf("body = %q, want it intact", body)
+	}
+	if level := tokenLevel(l, Key{Service: "embeddings"}); level != 93 {
+		t.Errorf("token level = %v, want 93", level)
+	}
+
+	// The server allows a single request per minute.
+	if _, err := post(); !errors.Is(err, ErrLimited) {
+		t.Errorf("second request error = %v, want ErrLimited", err)
+	}
+}
@@ -0,0 +1,370 @@
+// Package ratelimit paces the requests sent by the xAI SDK to stay within
+// requests-per-minute (RPM) and tokens-per-minute (TPM) limits.
+//
+// A Limiter keeps a pair of token buckets per service and model. Before a call
+// is sent, it waits until the bucket has room for one request and for the
+// estimated tokens of the call; once the response arrives, the estimate is
+// replaced with the tokens actually reported in the response's usage. Rate
+// limit headers and trailers returned by the server (x-ratelimit-limit-* and
+// x-ratelimit-remaining-*) and rate limit errors adjust the buckets as they come.
+//
+// The same Limiter paces gRPC calls through its interceptors and REST calls
+// through its http.RoundTripper, so it can be shared by several clients:
+//
+//	limiter := ratelimit.New(ratelimit.Config{
+//		Default: ratelimit.Limits{RequestsPerMinute: 480, TokensPerMinute: 2_000_000},
+//	})
+//	client, err := xai.NewClient(xai.NewConfig().WithRateLimiter(limiter))
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	xaierrors "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+)
+
+// ErrLimited is returned when a call cannot be sent without exceeding the
+// rate limit before its context is done, or immediately under WithFailFast.
+// It matches errors.ErrRateLimit with errors.Is.
+var ErrLimited = fmt.Errorf("client-side %w", xaierrors.ErrRateLimit)
+
+// Server headers and trailers through which rate limits are reported.
+const (
+	HeaderLimitRequests     = "x-ratelimit-limit-requests"
+	HeaderLimitTokens       = "x-ratelimit-limit-tokens"
+	HeaderRemainingRequests = "x-ratelimit-remaining-requests"
+	HeaderRemainingTokens   = "x-ratelimit-remaining-tokens"
+)
+
+// Limits are the rates allowed for a service or model. Zero means unlimited.
+type Limits struct {
+	// RequestsPerMinute is the number of calls allowed per minute.
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+
+	// TokensPerMinute is the number of prompt and completion tokens allowed per minute.
+	TokensPerMinute int `json:"tokens_per_minute,omitempty"`
+}
+
+// Config configures a Limiter. The limits of a call are looked up in Models by
+// model name, then in Services by service name, then fall back to Default for
+// calls that name a model.
+type Config struct {
+	// Default applies to every call that names a model.
+	Default Limits `json:"default"`
+
+	// Models overrides the limits of specific models.
+	Models map[string]Limits `json:"models,omitempty"`
+
+	// Services overrides the limits of a service, such as "chat", "embeddings"
+	// or "images", including calls that do not name a model. Services are named
+	// after the gRPC service in lowercase or the first segment of the REST path.
+	Services map[string]Limits `json:"services,omitempty"`
+
+	// DisableAdaptive ignores the rate limits reported by the server.
+	DisableAdaptive bool `json:"disable_adaptive,omitempty"`
+}
+
+// Key identifies the bucket pair a call is paced by.
+type Key struct {
+	// Service is the service called, such as "chat" or "embeddings".
+	Service string
+
+	// Model is the model named by the call, if any.
+	Model string
+}
+
+// String returns the key as service/model.
+func (k Key) String() string {
+	if k.Model == "" {
+		return k.Service
+	}
+	return k.Service + "/" + k.Model
+}
+
+// Limiter paces calls by requests and tokens per minute. It is safe for
+// concurrent use.
+type Limiter struct {
+	config Config
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[Key]*bucketPair
+}
+
+// New creates a Limiter.
+func New(config Config) *Limiter {
+	return &Limiter{
+		config:  config,
+		now:     time.Now,
+		buckets: make(map[Key]*bucketPair),
+	}
+}
+
+// limits returns the configured limits of key.
+func (l *Limiter) limits(key Key) (Limits, bool) {
+	if limits, ok := l.config.Models[key.Model]; ok && key.Model != "" {
+		return limits, true
+	}
+	if limits, ok := l.config.Services[key.Service]; ok {
+		return limits, true
+	}
+	if key.Model != "" {
+		return l.config.Default, true
+	}
+	return Limits{}, false
+}
+
+// bucketsFor returns the buckets of key, creating them if the key is limited
+// or create is set. l.mu must be held.
+func (l *Limiter) bucketsFor(key Key, create bool) *bucketPair {
+	if b, ok := l.buckets[key]; ok {
+		return b
+	}
+	limits, ok := l.limits(key)
+	if !ok && !create {
+		return nil
+	}
+	now := l.now()
+	b := &bucketPair{
+		requests: newBucket(limits.RequestsPerMinute, now),
+		tokens:   newBucket(limits.TokensPerMinute, now),
+	}
+	l.buckets[key] = b
+	return b
+}
+
+// Acquire waits until a call of key with the estimated number of tokens can be
+// sent. It returns ErrLimited without waiting if the wait would outlast the
+// context's deadline or the context was marked with WithFailFast, and the
+// context's error if it is done while waiting.
+func (l *Limiter) Acquire(ctx context.Context, key Key, tokens int) (*Reservation, error) {
+	if override, ok := ctx.Value(tokenEstimateKey{}).(int); ok {
+		tokens = override
+	}
+	for {
+		wait, res := l.tryAcquire(key, tokens)
+		if res != nil {
+			return res, nil
+		}
+
+		if failFast(ctx) {
+			return nil, fmt.Errorf("%w: %s would have to wait %v", ErrLimited, key, wait.Round(time.Millisecond))
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return nil, fmt.Errorf("%w: %s would have to wait %v, past the context deadline", ErrLimited, key, wait.Round(time.Millisecond))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// tryAcquire takes a request and tokens from the buckets of key if they have
+// room, or returns how long to wait until they do.
+func (l *Limiter) tryAcquire(key Key, tokens int) (time.Duration, *Reservation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucketsFor(key, false)
+	if b == nil {
+		return 0, &Reservation{}
+	}
+	now := l.now()
+	// A call larger than the whole token budget waits for a full bucket.
+	cost := min(float64(tokens), b.tokens.limit)
+	wait := max(b.blockedUntil.Sub(now), b.requests.wait(1, now), b.tokens.wait(cost, now))
+	if wait > 0 {
+		return wait, nil
+	}
+	b.requests.take(1)
+	b.tokens.take(cost)
+	return 0, &Reservation{limiter: l, key: key, tokens: cost}
+}
+
+// Update adjusts the buckets of key from the rate limit headers of a response.
+// The limits reported by the server replace the configured ones, and the
+// remaining budget caps the buckets' levels.
+func (l *Limiter) Update(key Key, header http.Header) {
+	if l.config.DisableAdaptive {
+		return
+	}
+	limitRequests, hasLimitRequests := headerInt(header, HeaderLimitRequests)
+	limitTokens, hasLimitTokens := headerInt(header, HeaderLimitTokens)
+	remainingRequests, hasRemainingRequests := headerInt(header, HeaderRemainingRequests)
+	remainingTokens, hasRemainingTokens := headerInt(header, HeaderRemainingTokens)
+	if !hasLimitRequests && !hasLimitTokens && !hasRemainingRequests && !hasRemainingTokens {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucketsFor(key, true)
+	now := l.now()
+	if hasLimitRequests {
+		b.requests.setLimit(limitRequests, now)
+	}
+	if hasLimitTokens {
+		b.tokens.setLimit(limitTokens, now)
+	}
+	if hasRemainingRequests {
+		b.requests.capLevel(remainingRequests, now)
+	}
+	if hasRemainingTokens {
+		b.tokens.capLevel(remainingTokens, now)
+	}
+}
+
+// Pause holds back the calls of key for d, typically the retry delay of a
+// rate limit error.
+func (l *Limiter) Pause(key Key, d time.Duration) {
+	if d <= 0 || l.config.DisableAdaptive {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucketsFor(key, true)
+	if until := l.now().Add(d); until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
+
+func headerInt(header http.Header, name string) (float64, bool) {
+	value := strings.TrimSpace(header.Get(name))
+	if value == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// Reservation is the budget taken by a call.
+type Reservation struct {
+	limiter *Limiter
+	key     Key
+	tokens  float64
+	done    bool
+}
+
+// Reconcile replaces the estimated tokens of the call with the tokens it
+// actually used, returning the difference to the bucket, up to its limit, or
+// taking the excess. Negative counts mean the usage is unknown and keep the
+// estimate. Only the first call has an effect.
+func (r *Reservation) Reconcile(tokens int) {
+	if r == nil || r.limiter == nil || r.done || tokens < 0 {
+		return
+	}
+	r.done = true
+
+	l := r.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[r.key]; ok {
+		b.tokens.refill(l.now())
+		b.tokens.level += r.tokens - float64(tokens)
+		if b.tokens.limit > 0 {
+			// A refunded over-estimate does not fill the bucket past its limit.
+			b.tokens.level = min(b.tokens.limit, b.tokens.level)
+		}
+	}
+}
+
+type bucketPair struct {
+	requests     *bucket
+	tokens       *bucket
+	blockedUntil time.Time
+}
+
+// bucket is a token bucket holding up to limit units and refilled at limit
+// units per minute. A zero limit is unlimited. The level goes negative when a
+// call used more than was reserved.
+type bucket struct {
+	limit   float64
+	level   float64
+	updated time.Time
+}
+
+func newBucket(perMinute int, now time.Time) *bucket {
+	return &bucket{limit: float64(perMinute), level: float64(perMinute), updated: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.level = min(b.limit, b.level+elapsed.Minutes()*b.limit)
+	}
+	b.updated = now
+}
+
+// wait returns how long until the bucket holds n units.
+func (b *bucket) wait(n float64, now time.Time) time.Duration {
+	if b.limit <= 0 {
+		return 0
+	}
+	b.refill(now)
+	if b.level >= n {
+		return 0
+	}
+	return time.Duration((n - b.level) / b.limit * float64(time.Minute))
+}
+
+func (b *bucket) take(n float64) {
+	if b.limit > 0 {
+		b.level -= n
+	}
+}
+
+func (b *bucket) setLimit(perMinute float64, now time.Time) {
+	if perMinute <= 0 {
+		return
+	}
+	if b.limit <= 0 {
+		// The bucket was unlimited; start it full.
+		b.limit, b.level, b.updated = perMinute, perMinute, now
+		return
+	}
+	b.refill(now)
+	b.limit = perMinute
+	b.level = min(b.level, perMinute)
+}
+
+func (b *bucket) capLevel(remaining float64, now time.Time) {
+	if b.limit <= 0 {
+		return
+	}
+	b.refill(now)
+	b.level = min(b.level, remaining)
+}
+
+type failFastKey struct{}
+
+type tokenEstimateKey struct{}
+
+// WithFailFast returns a context under which calls fail with ErrLimited
+// instead of waiting for the rate limit.
+func WithFailFast(ctx context.Context) context.Context {
+	return context.WithValue(ctx, failFastKey{}, true)
+}
+
+func failFast(ctx context.Context) bool {
+	v, _ := ctx.Value(failFastKey{}).(bool)
+	return v
+}
+
+// WithTokenEstimate returns a context that replaces the token estimate of the
+// calls made with it, for callers that counted the prompt's tokens themselves.
+func WithTokenEstimate(ctx context.Context, tokens int) context.Context {
+	return context.WithValue(ctx, tokenEstimateKey{}, tokens)
+}
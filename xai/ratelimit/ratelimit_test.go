@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	xaierrors "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+)
+
+// newTestLimiter returns a limiter whose clock only moves with the returned function.
+func newTestLimiter(config Config) (*Limiter, func(time.Duration)) {
+	l := New(config)
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestAcquireRequestsPerMinute(t *testing.T) {
+	l, advance := newTestLimiter(Config{Default: Limits{RequestsPerMinute: 2}})
+	ctx := WithFailFast(context.Background())
+	key := Key{Service: "chat", Model: "grok-4"}
+
+	for i := range 2 {
+		if _, err := l.Acquire(ctx, key, 0); err != nil {
+			t.Fatalf("Acquire() #%d error = %v", i+1, err)
+		}
+	}
+	_, err := l.Acquire(ctx, key, 0)
+	if !errors.Is(err, ErrLimited) || !errors.Is(err, xaierrors.ErrRateLimit) {
+		t.Fatalf("Acquire() error = %v, want ErrLimited", err)
+	}
+
+	advance(30 * time.Second)
+	if _, err := l.Acquire(ctx, key, 0); err != nil {
+		t.Errorf("Acquire() after refill error = %v", err)
+	}
+
+	// Other models have their own buckets.
+	if _, err := l.Acquire(ctx, Key{Service: "chat", Model: "grok-3"}, 0); err != nil {
+		t.Errorf("Acquire() for another model error = %v", err)
+	}
+}
+
+func TestAcquireTokensAndReconcile(t *testing.T) {
+	l, _ := newTestLimiter(Config{Models: map[string]Limits{"grok-4": {TokensPerMinute: 100}}})
+	ctx := WithFailFast(context.Background())
+	key := Key{Service: "chat", Model: "grok-4"}
+
+	res, err := l.Acquire(ctx, key, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Acquire(ctx, key, 30); !errors.Is(err, ErrLimited) {
+		t.Fatalf("Acquire() error = %v, want ErrLimited", err)
+	}
+
+	// The call used fewer tokens than estimated.
+	res.Reconcile(20)
+	if _, err := l.Acquire(ctx, key, 30); err != nil {
+		t.Errorf("Acquire() after reconcile error = %v", err)
+	}
+	if _, err := l.Acquire(WithTokenEstimate(ctx, 60), key, 1); !errors.Is(err, ErrLimited) {
+		t.Errorf("WithTokenEstimate should replace the estimate, got %v", err)
+	}
+}
+
+func TestReconcileCapsRefund(t *testing.T) {
+	l, advance := newTestLimiter(Config{Models: map[string]Limits{"grok-4": {TokensPerMinute: 100}}})
+	ctx := WithFailFast(context.Background())
+	key := Key{Service: "chat", Model: "grok-4"}
+
+	res, err := l.Acquire(ctx, key, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The bucket is full again when the call reports fewer tokens than reserved.
+	advance(time.Minute)
+	res.Reconcile(10)
+	if _, err := l.Acquire(ctx, key, 100); err != nil {
+		t.Fatalf("Acquire() of the whole budget error = %v", err)
+	}
+	if _, err := l.Acquire(ctx, key, 1); !errors.Is(err, ErrLimited) {
+		t.Errorf("Acquire() past the limit error = %v, want ErrLimited", err)
+	}
+}
+
+func TestAcquireWaitsOrFailsByDeadline(t *testing.T) {
+	l := New(Config{Default: Limits{RequestsPerMinute: 6000}})
+	key := Key{Service: "chat", Model: "grok-4"}
+	l.Update(key, http.Header{"X-Ratelimit-Remaining-Requests": {"0"}})
+
+	start := time.Now()
+	if _, err := l.Acquire(context.Background(), key, 0); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 5*time.Millisecond {
+		t.Errorf("Acquire() returned after %v, want it to wait for the refill", waited)
+	}
+
+	l.Pause(key, time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start = time.Now()
+	if _, err := l.Acquire(ctx, key, 0); !errors.Is(err, ErrLimited) || time.Since(start) > 100*time.Millisecond {
+		t.Errorf("Acquire() = %v after %v, want ErrLimited at once", err, time.Since(start))
+	}
+}
+
+func TestLimitsResolution(t *testing.T) {
+	l := New(Config{
+		Default:  Limits{RequestsPerMinute: 1},
+		Models:   map[string]Limits{"grok-4": {RequestsPerMinute: 5}},
+		Services: map[string]Limits{"files": {RequestsPerMinute: 2}},
+	})
+	tests := []struct {
+		key     Key
+		limits  Limits
+		limited bool
+	}{
+		{Key{"chat", "grok-4"}, Limits{RequestsPerMinute: 5}, true},
+		{Key{"chat", "grok-3"}, Limits{RequestsPerMinute: 1}, true},
+		{Key{"files", ""}, Limits{RequestsPerMinute: 2}, true},
+		{Key{"models", ""}, Limits{}, false},
+	}
+	for _, tt := range tests {
+		if limits, limited := l.limits(tt.key); limits != tt.limits || limited != tt.limited {
+			t.Errorf("limits(%v) = %+v, %v; want %+v, %v", tt.key, limits, limited, tt.limits, tt.limited)
+		}
+	}
+}
+
+func TestUpdateAdaptsToServerLimits(t *testing.T) {
+	l, _ := newTestLimiter(Config{})
+	ctx := WithFailFast(context.Background())
+	key := Key{Service: "models"}
+
+	if _, err := l.Acquire(ctx, key, 0); err != nil {
+		t.Fatalf("unlimited Acquire() error = %v", err)
+	}
+	header := http.Header{}
+	header.Set(HeaderLimitRequests, "10")
+	header.Set(HeaderRemainingRequests, "0")
+	l.Update(key, header)
+	if _, err := l.Acquire(ctx, key, 0); !errors.Is(err, ErrLimited) {
+		t.Errorf("Acquire() error = %v, want ErrLimited after the server reported no remaining requests", err)
+	}
+
+	disabled, _ := newTestLimiter(Config{DisableAdaptive: true})
+	disabled.Update(key, header)
+	if _, err := disabled.Acquire(ctx, key, 0); err != nil {
+		t.Errorf("DisableAdaptive: Acquire() error = %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PushbackHeader is the gRPC trailer through which servers ask clients to wait
+// a number of milliseconds before retrying, or not to retry if it is negative.
+const PushbackHeader = "grpc-retry-pushback-ms"
+
+// ClassifyGRPC classifies a gRPC error. trailer is the trailer metadata of the
+// failed call and may be nil. The delay comes from grpc-retry-pushback-ms, a
+// retry-after trailer or a google.rpc.RetryInfo status detail. ResourceExhausted
+// errors of non-idempotent calls are only retried when the server gives such a
+// delay.
+func ClassifyGRPC(err error, trailer metadata.MD, idempotent bool) (bool, time.Duration) {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, 0
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return false, 0
+	}
+
+	after, pushback := Pushback(err, trailer)
+	switch st.Code() {
+	case codes.Unavailable:
+	case codes.ResourceExhausted:
+		if !idempotent && !pushback {
+			return false, 0
+		}
+	case codes.Internal, codes.Aborted, codes.Unknown:
+		if !idempotent {
+			return false, 0
+		}
+	default:
+		return false, 0
+	}
+
+	if after < 0 {
+		// Malformed or negative pushback means the server asks us not to retry.
+		return false, 0
+	}
+	return true, after
+}
+
+// Pushback returns the delay a server asked for before retrying a failed gRPC
+// call, from grpc-retry-pushback-ms, a retry-after trailer or a
+// google.rpc.RetryInfo status detail. A negative delay means the server asked
+// not to retry. ok is false if the server gave no pushback.
+func Pushback(err error, trailer metadata.MD) (after time.Duration, ok bool) {
+	if values := trailer.Get(PushbackHeader); len(values) > 0 {
+		ms, parseErr := strconv.Atoi(strings.TrimSpace(values[0]))
+		if parseErr != nil || ms < 0 {
+			return -1, true
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	if values := trailer.Get("retry-after"); len(values) > 0 {
+		if after, parsed := ParseRetryAfter(values[0]); parsed {
+			return after, true
+		}
+	}
+	if st, isStatus := status.FromError(err); isStatus {
+		for _, detail := range st.Details() {
+			if info, isInfo := detail.(*errdetails.RetryInfo); isInfo && info.GetRetryDelay() != nil {
+				return info.GetRetryDelay().AsDuration(), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// ClassifyHTTP classifies an HTTP error response. The delay comes from the
+// Retry-After header. 429 responses to non-idempotent calls are only retried
+// when the server sets it.
+func ClassifyHTTP(statusCode int, header http.Header, idempotent bool) (bool, time.Duration) {
+	after, ok := ParseRetryAfter(header.Get("Retry-After"))
+	switch statusCode {
+	case http.StatusServiceUnavailable:
+	case http.StatusTooManyRequests:
+		if !idempotent && !ok {
+			return false, 0
+		}
+	case http.StatusRequestTimeout, http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		if !idempotent {
+			return false, 0
+		}
+	default:
+		return false, 0
+	}
+	return true, after
+}
+
+// ClassifyNetwork classifies a transport error returned before a response was
+// received. Failures to connect are always retryable because nothing was sent;
+// other failures are retryable only for idempotent calls.
+func ClassifyNetwork(err error, idempotent bool) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return idempotent
+}
+
+// ParseRetryAfter parses a Retry-After value given either in seconds or as an
+// HTTP date.
+func ParseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
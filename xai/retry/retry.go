@@ -0,0 +1,165 @@
+// Package retry provides the retry policy shared by the gRPC and REST clients of the xAI SDK.
+//
+// Failed calls are retried with exponential backoff and jitter when the error is
+// retryable: gRPC Unavailable or HTTP 503 are always retried because the server
+// did not process the request, rate limits (ResourceExhausted, HTTP 429) are
+// retried for non-idempotent calls only when the server says when to retry, and
+// other transient failures (Internal, 5xx, broken connections) are only retried
+// for idempotent calls. Server pushback through Retry-After or
+// grpc-retry-pushback-ms replaces the computed backoff.
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	// DefaultMultiplier is the default backoff multiplier.
+	DefaultMultiplier = 1.6
+
+	// DefaultJitter is the default fraction of each backoff that is randomized.
+	DefaultJitter = 0.2
+)
+
+// Policy configures how failed calls are retried.
+type Policy struct {
+	// MaxRetries is the maximum number of retries after the first attempt.
+	// Zero disables retries.
+	MaxRetries int
+
+	// InitialBackoff is the backoff before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the computed backoff. Server pushback is not capped.
+	MaxBackoff time.Duration
+
+	// Multiplier grows the backoff after each retry (default: DefaultMultiplier).
+	Multiplier float64
+
+	// Jitter is the fraction of each backoff that is randomized, between 0 and 1
+	// (default: DefaultJitter). Use a negative value to disable jitter.
+	Jitter float64
+}
+
+// Backoff returns the delay before the given retry, starting at 1.
+func (p Policy) Backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = DefaultMultiplier
+	}
+	jitter := p.Jitter
+	switch {
+	case jitter == 0:
+		jitter = DefaultJitter
+	case jitter < 0:
+		jitter = 0
+	case jitter > 1:
+		jitter = 1
+	}
+
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		backoff *= multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	// Randomize the last jitter fraction of the backoff so that clients that
+	// failed together do not retry together.
+	backoff -= backoff * jitter * rand.Float64() //nolint:gosec // jitter does not need a cryptographic source
+	return time.Duration(backoff)
+}
+
+// Classifier reports whether err is retryable and, if the server asked for a
+// specific delay, how long to wait before retrying.
+type Classifier func(err error) (retryable bool, after time.Duration)
+
+// Do calls fn until it succeeds or returns an error that classify does not
+// consider retryable, at most p.MaxRetries+1 times. It waits between attempts
+// and gives up early, returning the last error, when the wait would outlast the
+// context deadline. If ctx is canceled while waiting, ctx.Err() is returned.
+func Do(ctx context.Context, p Policy, classify Classifier, fn func() error) error {
+	for retry := 1; ; retry++ {
+		err := fn()
+		if err == nil || retry > p.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+		retryable, after := classify(err)
+		if !retryable {
+			return err
+		}
+		delay := after
+		if delay <= 0 {
+			delay = p.Backoff(retry)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+		if waitErr := wait(ctx, delay); waitErr != nil {
+			return waitErr
+		}
+	}
+}
+
+func wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type policyKey struct{}
+
+type maxRetriesKey struct{}
+
+type idempotentKey struct{}
+
+// WithPolicy returns a context that overrides the client's retry policy for
+// calls made with it.
+func WithPolicy(ctx context.Context, p Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, p)
+}
+
+// WithMaxRetries returns a context that overrides only the maximum number of
+// retries for calls made with it. WithMaxRetries(ctx, 0) disables retries.
+func WithMaxRetries(ctx context.Context, maxRetries int) context.Context {
+	return context.WithValue(ctx, maxRetriesKey{}, maxRetries)
+}
+
+// WithIdempotent returns a context that marks calls made with it as idempotent
+// or not, overriding the SDK's classification. Only idempotent calls are
+// retried after failures that may have happened after the server processed them.
+func WithIdempotent(ctx context.Context, idempotent bool) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, idempotent)
+}
+
+// PolicyFromContext returns the policy for a call made with ctx: def, unless
+// overridden with WithPolicy or WithMaxRetries.
+func PolicyFromContext(ctx context.Context, def Policy) Policy {
+	p := def
+	if override, ok := ctx.Value(policyKey{}).(Policy); ok {
+		p = override
+	}
+	if maxRetries, ok := ctx.Value(maxRetriesKey{}).(int); ok {
+		p.MaxRetries = maxRetries
+	}
+	return p
+}
+
+// IdempotentFromContext returns whether a call made with ctx is idempotent:
+// def, unless overridden with WithIdempotent.
+func IdempotentFromContext(ctx context.Context, def bool) bool {
+	if idempotent, ok := ctx.Value(idempotentKey{}).(bool); ok {
+		return idempotent
+	}
+	return def
+}
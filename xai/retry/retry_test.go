@@ -0,0 +1,194 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestBackoffGrowsAndCaps(t *testing.T) {
+	p := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond, Multiplier: 2, Jitter: -1}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	p := Policy{InitialBackoff: time.Second, MaxBackoff: time.Second, Jitter: 0.5}
+	for range 100 {
+		if got := p.Backoff(1); got < 500*time.Millisecond || got > time.Second {
+			t.Fatalf("Backoff(1) = %v, want within [500ms, 1s]", got)
+		}
+	}
+}
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	p := Policy{MaxRetries: 3, InitialBackoff: time.Millisecond, Jitter: -1}
+	calls := 0
+	err := Do(context.Background(), p, func(error) (bool, time.Duration) { return true, 0 }, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Do() = %v after %d calls, want nil after 3", err, calls)
+	}
+}
+
+func TestDoStopsOnPermanentErrorAndExhaustion(t *testing.T) {
+	p := Policy{MaxRetries: 2, InitialBackoff: time.Millisecond}
+
+	calls := 0
+	permanent := errors.New("permanent")
+	err := Do(context.Background(), p, func(error) (bool, time.Duration) { return false, 0 }, func() error {
+		calls++
+		return permanent
+	})
+	if !errors.Is(err, permanent) || calls != 1 {
+		t.Errorf("permanent: Do() = %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = Do(context.Background(), p, func(error) (bool, time.Duration) { return true, 0 }, func() error {
+		calls++
+		return permanent
+	})
+	if !errors.Is(err, permanent) || calls != 3 {
+		t.Errorf("exhausted: Do() = %v after %d calls, want 3 calls", err, calls)
+	}
+}
+
+func TestDoGivesUpWhenPushbackOutlastsDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	err := Do(ctx, Policy{MaxRetries: 3, InitialBackoff: time.Millisecond}, func(error) (bool, time.Duration) {
+		return true, time.Minute
+	}, func() error {
+		calls++
+		return errors.New("rate limited")
+	})
+	if err == nil || calls != 1 || time.Since(start) > 40*time.Millisecond {
+		t.Errorf("Do() = %v after %d calls in %v", err, calls, time.Since(start))
+	}
+}
+
+func TestContextOverrides(t *testing.T) {
+	def := Policy{MaxRetries: 3, InitialBackoff: time.Second}
+	ctx := context.Background()
+	if got := PolicyFromContext(ctx, def); got != def {
+		t.Errorf("PolicyFromContext() = %+v, want default", got)
+	}
+
+	override := Policy{MaxRetries: 1, InitialBackoff: time.Millisecond}
+	if got := PolicyFromContext(WithPolicy(ctx, override), def); got != override {
+		t.Errorf("WithPolicy: got %+v", got)
+	}
+	got := PolicyFromContext(WithMaxRetries(ctx, 0), def)
+	if got.MaxRetries != 0 || got.InitialBackoff != time.Second {
+		t.Errorf("WithMaxRetries: got %+v", got)
+	}
+
+	if IdempotentFromContext(ctx, true) != true || IdempotentFromContext(WithIdempotent(ctx, false), true) != false {
+		t.Error("WithIdempotent should override the default")
+	}
+}
+
+func TestClassifyGRPC(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	internal := status.Error(codes.Internal, "oops")
+
+	tests := []struct {
+		name       string
+		err        error
+		trailer    metadata.MD
+		idempotent bool
+		retryable  bool
+		after      time.Duration
+	}{
+		{"unavailable", unavailable, nil, false, true, 0},
+		{"internal non-idempotent", internal, nil, false, false, 0},
+		{"internal idempotent", internal, nil, true, true, 0},
+		{"invalid argument", status.Error(codes.InvalidArgument, "bad"), nil, true, false, 0},
+		{"pushback", unavailable, metadata.Pairs(PushbackHeader, "250"), false, true, 250 * time.Millisecond},
+		{"negative pushback", unavailable, metadata.Pairs(PushbackHeader, "-1"), false, false, 0},
+		{"retry-after trailer", status.Error(codes.ResourceExhausted, "slow down"), metadata.Pairs("retry-after", "2"), false, true, 2 * time.Second},
+		{"rate limited without pushback", status.Error(codes.ResourceExhausted, "slow down"), nil, false, false, 0},
+		{"rate limited idempotent", status.Error(codes.ResourceExhausted, "slow down"), nil, true, true, 0},
+		{"canceled", context.Canceled, nil, true, false, 0},
+	}
+	for _, tt := range tests {
+		retryable, after := ClassifyGRPC(tt.err, tt.trailer, tt.idempotent)
+		if retryable != tt.retryable || after != tt.after {
+			t.Errorf("%s: ClassifyGRPC() = %v, %v; want %v, %v", tt.name, retryable, after, tt.retryable, tt.after)
+		}
+	}
+
+	st, err := status.New(codes.ResourceExhausted, "rate limited").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(3 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retryable, after := ClassifyGRPC(st.Err(), nil, false); !retryable || after != 3*time.Second {
+		t.Errorf("RetryInfo: ClassifyGRPC() = %v, %v", retryable, after)
+	}
+}
+
+func TestClassifyHTTP(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "7")
+	if retryable, after := ClassifyHTTP(http.StatusTooManyRequests, header, false); !retryable || after != 7*time.Second {
+		t.Errorf("429: got %v, %v", retryable, after)
+	}
+	if retryable, _ := ClassifyHTTP(http.StatusTooManyRequests, nil, false); retryable {
+		t.Error("429 without Retry-After must not be retried for non-idempotent calls")
+	}
+	if retryable, _ := ClassifyHTTP(http.StatusInternalServerError, nil, false); retryable {
+		t.Error("500 must not be retried for non-idempotent requests")
+	}
+	if retryable, _ := ClassifyHTTP(http.StatusBadGateway, nil, true); !retryable {
+		t.Error("502 should be retried for idempotent requests")
+	}
+	if retryable, _ := ClassifyHTTP(http.StatusBadRequest, nil, true); retryable {
+		t.Error("400 must not be retried")
+	}
+}
+
+func TestClassifyNetwork(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	if !ClassifyNetwork(dialErr, false) {
+		t.Error("dial errors should always be retried")
+	}
+	readErr := &net.OpError{Op: "read", Err: errors.New("connection reset")}
+	if ClassifyNetwork(readErr, false) || !ClassifyNetwork(readErr, true) {
+		t.Error("read errors should only be retried for idempotent requests")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := ParseRetryAfter("1.5"); !ok || d != 1500*time.Millisecond {
+		t.Errorf("seconds: got %v, %v", d, ok)
+	}
+	date := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if d, ok := ParseRetryAfter(date); !ok || d <= 8*time.Second || d > 10*time.Second {
+		t.Errorf("date: got %v, %v", d, ok)
+	}
+	if _, ok := ParseRetryAfter("soon"); ok {
+		t.Error("invalid value should not parse")
+	}
+}
@@ -0,0 +1,257 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// grpcOperations are the GenAI operations of the gRPC methods using a model.
+var grpcOperations = map[string]string{
+	"/xai_api.Chat/GetCompletion":           OperationChat,
+	"/xai_api.Chat/GetCompletionChunk":      OperationChat,
+	"/xai_api.Chat/StartDeferredCompletion": OperationChat,
+	"/xai_api.Sample/SampleText":            OperationTextCompletion,
+	"/xai_api.Sample/SampleTextStreaming":   OperationTextCompletion,
+	"/xai_api.Embedder/Embed":               OperationEmbeddings,
+	"/xai_api.Image/GenerateImage":          OperationGenerateContent,
+	"/xai_api.Video/GenerateVideo":          OperationGenerateContent,
+	"/xai_api.Video/ExtendVideo":            OperationGenerateContent,
+}
+
+// grpcCall describes a gRPC call.
+func grpcCall(method string, req any, stream bool) Call {
+	service, name := "", strings.TrimPrefix(method, "/")
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		service, name = name[:i], name[i+1:]
+	}
+	call := Call{
+		Transport: TransportGRPC,
+		Service:   service,
+		Method:    name,
+		Operation: grpcOperations[method],
+		Stream:    stream,
+		Request:   req,
+	}
+	if m, ok := req.(interface{ GetModel() string }); ok {
+		call.Model = m.GetModel()
+	}
+	return call
+}
+
+// UnaryInterceptor returns an interceptor reporting unary calls to observer.
+// It must be the outermost interceptor so that it sees the final error.
+func UnaryInterceptor(observer Observer) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		ctx, t := Start(ctx, observer, grpcCall(method, req, false))
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			t.ObserveResponse(reply)
+			t.SetResponse(reply)
+		}
+		t.End(err)
+		return err
+	}
+}
+
+// StreamInterceptor returns an interceptor reporting streaming calls to
+// observer. The call ends when the stream ends, fails or its context is done.
+func StreamInterceptor(observer Observer) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		if observer == nil {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		s := &observedStream{ctx: ctx, desc: desc, cc: cc, method: method, streamer: streamer, opts: opts, observer: observer}
+		if desc.ClientStreams {
+			// The requests are not known in advance, so the stream opens at once.
+			if err := s.open(nil); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	}
+}
+
+// observedStream reports a stream. Server-streaming calls are opened on the
+// first SendMsg, when the request, and hence the model, is known.
+type observedStream struct {
+	grpc.ClientStream
+
+	ctx      context.Context
+	desc     *grpc.StreamDesc
+	cc       *grpc.ClientConn
+	method   string
+	streamer grpc.Streamer
+	opts     []grpc.CallOption
+	observer Observer
+
+	tracker  *Tracker
+	received bool
+}
+
+func (s *observedStream) open(req any) error {
+	ctx, t := Start(s.ctx, s.observer, grpcCall(s.method, req, true))
+	s.tracker = t
+	stream, err := s.streamer(ctx, s.desc, s.cc, s.method, s.opts...)
+	if err != nil {
+		t.End(err)
+		return err
+	}
+	s.ClientStream = stream
+	t.EndWithContext(ctx)
+	return nil
+}
+
+func (s *observedStream) SendMsg(m interface{}) error {
+	if s.ClientStream == nil {
+		if err := s.open(m); err != nil {
+			return err
+		}
+	}
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *observedStream) CloseSend() error {
+	if s.ClientStream == nil {
+		if err := s.open(nil); err != nil {
+			return err
+		}
+	}
+	return s.ClientStream.CloseSend()
+}
+
+func (s *observedStream) Header() (metadata.MD, error) {
+	if s.ClientStream == nil {
+		return nil, nil
+	}
+	return s.ClientStream.Header()
+}
+
+func (s *observedStream) Trailer() metadata.MD {
+	if s.ClientStream == nil {
+		return nil
+	}
+	return s.ClientStream.Trailer()
+}
+
+func (s *observedStream) Context() context.Context {
+	if s.ClientStream == nil {
+		return s.ctx
+	}
+	return s.ClientStream.Context()
+}
+
+func (s *observedStream) RecvMsg(m interface{}) error {
+	if s.ClientStream == nil {
+		if err := s.open(nil); err != nil {
+			return err
+		}
+	}
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		if !s.received {
+			s.received = true
+			s.tracker.FirstToken()
+		}
+		s.tracker.ObserveResponse(m)
+		if !s.desc.ServerStreams {
+			s.tracker.End(nil)
+		}
+	case err == io.EOF:
+		s.tracker.End(nil)
+	default:
+		s.tracker.End(err)
+	}
+	return err
+}
+
+// AttemptUnaryInterceptor returns an interceptor counting the attempts of
+// unary calls and recording their request IDs. It must be placed after the
+// retry interceptor.
+func AttemptUnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		t := FromContext(ctx)
+		if t == nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		attempt := t.StartAttempt()
+		var header metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...)
+		t.SetRequestID(header.Get)
+		t.AttemptFailed(attempt, err)
+		return err
+	}
+}
+
+// AttemptStreamInterceptor returns an interceptor counting the attempts of
+// streaming calls and recording their request IDs. It must be placed after
+// the retry interceptor.
+func AttemptStreamInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		t := FromContext(ctx)
+		if t == nil {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		attempt := t.StartAttempt()
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			t.AttemptFailed(attempt, err)
+			return nil, err
+		}
+		return &attemptStream{ClientStream: stream, tracker: t, attempt: attempt}, nil
+	}
+}
+
+// attemptStream records the request ID of a stream attempt and reports its failure.
+type attemptStream struct {
+	grpc.ClientStream
+	tracker *Tracker
+	attempt int
+	once    sync.Once
+}
+
+func (s *attemptStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	s.once.Do(func() {
+		if header, headerErr := s.ClientStream.Header(); headerErr == nil {
+			s.tracker.SetRequestID(header.Get)
+		}
+	})
+	if err != nil && err != io.EOF {
+		s.tracker.AttemptFailed(s.attempt, err)
+	}
+	return err
+}
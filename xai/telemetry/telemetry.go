@@ -0,0 +1,172 @@
+// Package telemetry reports the gRPC and REST calls of a client to an
+// Observer, for tracing and metrics. Call attributes follow the OpenTelemetry
+// semantic conventions for generative AI: operation, model, request and
+// response IDs, token usage, finish reasons, retry attempts and the time to
+// the first token of streams.
+//
+// The package has no dependencies beyond the SDK; the xaiotel module adapts
+// an Observer to OpenTelemetry tracers and meters.
+package telemetry
+
+import (
+	"context"
+	"time"
+)
+
+// System is the GenAI system name of xAI.
+const System = "xai"
+
+// Transports of a call.
+const (
+	TransportGRPC = "grpc"
+	TransportHTTP = "http"
+)
+
+// Operation names of the GenAI semantic conventions.
+const (
+	OperationChat            = "chat"
+	OperationTextCompletion  = "text_completion"
+	OperationEmbeddings      = "embeddings"
+	OperationGenerateContent = "generate_content"
+)
+
+// Observer is notified of every call of a client.
+type Observer interface {
+	// StartCall is called when a call starts. The returned context is used
+	// for the call, so that spans can be propagated, and the returned
+	// CallObserver receives the rest of the call.
+	StartCall(ctx context.Context, call Call) (context.Context, CallObserver)
+}
+
+// CallObserver receives the events of one call.
+type CallObserver interface {
+	// AttemptFailed is called when an attempt of the call fails, before it
+	// is retried or the call ends. Attempts are numbered from 1.
+	AttemptFailed(attempt int, err error)
+
+	// End is called once, when the call ends.
+	End(result Result)
+}
+
+// Call describes a call as it starts.
+type Call struct {
+	// Transport is TransportGRPC or TransportHTTP.
+	Transport string
+
+	// Service and Method name the call: the gRPC service and method (such as
+	// "xai_api.Chat" and "GetCompletion"), or the HTTP method and path.
+	Service string
+	Method  string
+
+	// Operation is the GenAI operation name; it is empty for calls that do
+	// not use a model, such as file management.
+	Operation string
+
+	// Model is the model requested, if any.
+	Model string
+
+	// Stream reports whether the response is streamed.
+	Stream bool
+
+	// Request is the request: a proto message for gRPC calls, the JSON body
+	// (a json.RawMessage) for REST calls, or nil for streams sending
+	// several messages. It must not be modified.
+	Request any
+}
+
+// Usage is the token usage and cost reported by a call.
+type Usage struct {
+	InputTokens       int64
+	OutputTokens      int64
+	ReasoningTokens   int64
+	CachedInputTokens int64
+
+	// CostUSD is the cost of the call; HasCost reports whether the API
+	// reported one.
+	CostUSD float64
+	HasCost bool
+}
+
+// Result describes a call as it ends.
+type Result struct {
+	// Err is the error of the call, if it failed.
+	Err error
+
+	// RequestID identifies the request in the API's logs, if reported.
+	RequestID string
+
+	// ResponseID and ResponseModel are the ID and model of the response.
+	ResponseID    string
+	ResponseModel string
+
+	// Usage is the token usage of the call.
+	Usage Usage
+
+	// FinishReasons are the finish reasons of the outputs, in output order.
+	FinishReasons []string
+
+	// Attempts is the number of attempts made, including retries.
+	Attempts int
+
+	// Duration is the duration of the call, up to the end of the stream for
+	// streamed responses.
+	Duration time.Duration
+
+	// TimeToFirstToken is the time until the first message of a stream was
+	// received; it is zero for other calls.
+	TimeToFirstToken time.Duration
+
+	// Response is the response of a successful unary call: a proto message
+	// for gRPC calls or the JSON body (a json.RawMessage) for REST calls. It
+	// is nil for streams and must not be modified.
+	Response any
+}
+
+// Combine returns an observer notifying each of observers in order; nil
+// observers are skipped. It returns nil if there are none.
+func Combine(observers ...Observer) Observer {
+	var combined multiObserver
+	for _, o := range observers {
+		if o != nil {
+			combined = append(combined, o)
+		}
+	}
+	switch len(combined) {
+	case 0:
+		return nil
+	case 1:
+		return combined[0]
+	default:
+		return combined
+	}
+}
+
+type multiObserver []Observer
+
+func (m multiObserver) StartCall(ctx context.Context, call Call) (context.Context, CallObserver) {
+	var calls multiCallObserver
+	for _, o := range m {
+		var c CallObserver
+		if ctx, c = o.StartCall(ctx, call); c != nil {
+			calls = append(calls, c)
+		}
+	}
+	if len(calls) == 0 {
+		return ctx, nil
+	}
+	return ctx, calls
+}
+
+type multiCallObserver []CallObserver
+
+func (m multiCallObserver) AttemptFailed(attempt int, err error) {
+	for _, c := range m {
+		c.AttemptFailed(attempt, err)
+	}
+}
+
+func (m multiCallObserver) End(result Result) {
+	for _, c := range m {
+		c.End(result)
+	}
+}
@@ -0,0 +1,253 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// recorder is an Observer recording the calls it sees.
+type recorder struct {
+	calls    []Call
+	failures []error
+	results  chan Result
+}
+
+func newRecorder() *recorder {
+	return &recorder{results: make(chan Result, 1)}
+}
+
+func (r *recorder) StartCall(ctx context.Context, call Call) (context.Context, CallObserver) {
+	r.calls = append(r.calls, call)
+	return ctx, r
+}
+
+func (r *recorder) AttemptFailed(_ int, err error) {
+	r.failures = append(r.failures, err)
+}
+
+func (r *recorder) End(result Result) {
+	r.results <- result
+}
+
+func (r *recorder) result(t *testing.T) Result {
+	t.Helper()
+	select {
+	case result := <-r.results:
+		return result
+	case <-time.After(time.Second):
+		t.Fatal("the call did not end")
+		return Result{}
+	}
+}
+
+func setHeader(opts []grpc.CallOption, md metadata.MD) {
+	for _, opt := range opts {
+		if h, ok := opt.(grpc.HeaderCallOption); ok {
+			*h.HeaderAddr = md
+		}
+	}
+}
+
+func TestUnaryInterceptorReportsCall(t *testing.T) {
+	rec := newRecorder()
+	ticks := int64(2_500_000_000)
+	response := &xaiv1.GetChatCompletionResponse{
+		Id:    "resp-1",
+		Model: "grok-4-0709",
+		Outputs: []*xaiv1.CompletionOutput{
+			{Index: 1, FinishReason: xaiv1.FinishReason_REASON_TOOL_CALLS},
+			{Index: 0, FinishReason: xaiv1.FinishReason_REASON_STOP},
+		},
+		Usage: &xaiv1.SamplingUsage{PromptTokens: 12, CompletionTokens: 34, ReasoningTokens: 5, CostInUsdTicks: &ticks},
+	}
+
+	// The server fails the first attempt; the retry layer tries again.
+	attempts := 0
+	server := func(_ context.Context, _ string, _, reply interface{}, _ *grpc.ClientConn, opts ...grpc.CallOption) error {
+		attempts++
+		setHeader(opts, metadata.Pairs("x-request-id", "req-1"))
+		if attempts == 1 {
+			return status.Error(codes.Unavailable, "try again")
+		}
+		proto.Merge(reply.(proto.Message), response)
+		return nil
+	}
+	attempt := AttemptUnaryInterceptor()
+	retrying := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if err := attempt(ctx, method, req, reply, cc, server, opts...); err == nil {
+			return nil
+		}
+		return attempt(ctx, method, req, reply, cc, server, opts...)
+	}
+
+	req := &xaiv1.GetCompletionsRequest{Model: "grok-4"}
+	err := UnaryInterceptor(rec)(context.Background(), "/xai_api.Chat/GetCompletion", req, &xaiv1.GetChatCompletionResponse{}, nil, retrying)
+	if err != nil {
+		t.Fatalf("call error = %v", err)
+	}
+
+	call := rec.calls[0]
+	if call.Transport != TransportGRPC || call.Service != "xai_api.Chat" || call.Method != "GetCompletion" ||
+		call.Operation != OperationChat || call.Model != "grok-4" || call.Stream {
+		t.Errorf("call = %+v", call)
+	}
+	result := rec.result(t)
+	if result.Attempts != 2 || len(rec.failures) != 1 || status.Code(rec.failures[0]) != codes.Unavailable {
+		t.Errorf("attempts = %d, failures = %v", result.Attempts, rec.failures)
+	}
+	if result.RequestID != "req-1" || result.ResponseID != "resp-1" || result.ResponseModel != "grok-4-0709" {
+		t.Errorf("result = %+v", result)
+	}
+	if result.Usage.InputTokens != 12 || result.Usage.OutputTokens != 34 || result.Usage.ReasoningTokens != 5 ||
+		!result.Usage.HasCost || result.Usage.CostUSD != 0.25 {
+		t.Errorf("usage = %+v", result.Usage)
+	}
+	if len(result.FinishReasons) != 2 || result.FinishReasons[0] != "stop" || result.FinishReasons[1] != "tool_calls" {
+		t.Errorf("finish reasons = %v", result.FinishReasons)
+	}
+}
+
+// fakeStream is a server stream returning chunks.
+type fakeStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	chunks []*xaiv1.GetChatCompletionChunk
+	err    error
+}
+
+func (s *fakeStream) Context() context.Context  { return s.ctx }
+func (s *fakeStream) SendMsg(interface{}) error { return nil }
+func (s *fakeStream) CloseSend() error          { return nil }
+func (s *fakeStream) Header() (metadata.MD, error) {
+	return metadata.Pairs("x-request-id", "req-2"), nil
+}
+func (s *fakeStream) Trailer() metadata.MD { return nil }
+func (s *fakeStream) RecvMsg(m interface{}) error {
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return s.err
+		}
+		return io.EOF
+	}
+	proto.Merge(m.(proto.Message), s.chunks[0])
+	s.chunks = s.chunks[1:]
+	return nil
+}
+
+func openStream(t *testing.T, ctx context.Context, rec *recorder, fake *fakeStream) grpc.ClientStream {
+	t.Helper()
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		fake.ctx = ctx
+		return AttemptStreamInterceptor()(ctx, desc, cc, method, func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+			return fake, nil
+		}, opts...)
+	}
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	stream, err := StreamInterceptor(rec)(ctx, desc, nil, "/xai_api.Chat/GetCompletionChunk", streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendMsg(&xaiv1.GetCompletionsRequest{Model: "grok-4"}); err != nil {
+		t.Fatal(err)
+	}
+	return stream
+}
+
+func TestStreamInterceptorReportsCall(t *testing.T) {
+	rec := newRecorder()
+	fake := &fakeStream{chunks: []*xaiv1.GetChatCompletionChunk{
+		{Id: "resp-2", Outputs: []*xaiv1.CompletionOutputChunk{{Delta: &xaiv1.Delta{Content: "Hi"}}}},
+		{Outputs: []*xaiv1.CompletionOutputChunk{{FinishReason: xaiv1.FinishReason_REASON_MAX_LEN}}},
+		{Usage: &xaiv1.SamplingUsage{PromptTokens: 3, CompletionTokens: 2}},
+	}}
+	stream := openStream(t, context.Background(), rec, fake)
+
+	for {
+		if err := stream.RecvMsg(&xaiv1.GetChatCompletionChunk{}); err != nil {
+			break
+		}
+	}
+
+	if call := rec.calls[0]; !call.Stream || call.Model != "grok-4" || call.Operation != OperationChat {
+		t.Errorf("call = %+v", call)
+	}
+	result := rec.result(t)
+	if result.Err != nil || result.RequestID != "req-2" || result.ResponseID != "resp-2" || result.Attempts != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if result.TimeToFirstToken <= 0 || result.TimeToFirstToken > result.Duration {
+		t.Errorf("time to first token = %v, duration = %v", result.TimeToFirstToken, result.Duration)
+	}
+	if result.Usage.InputTokens != 3 || result.Usage.OutputTokens != 2 {
+		t.Errorf("usage = %+v", result.Usage)
+	}
+	if len(result.FinishReasons) != 1 || result.FinishReasons[0] != "length" {
+		t.Errorf("finish reasons = %v", result.FinishReasons)
+	}
+}
+
+func TestStreamInterceptorEndsAbandonedStreams(t *testing.T) {
+	rec := newRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := openStream(t, ctx, rec, &fakeStream{chunks: []*xaiv1.GetChatCompletionChunk{{Id: "resp-3"}, {}}})
+	if err := stream.RecvMsg(&xaiv1.GetChatCompletionChunk{}); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	if result := rec.result(t); !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("result error = %v, want context.Canceled", result.Err)
+	}
+}
+
+func TestObserveJSON(t *testing.T) {
+	rec := newRecorder()
+	_, tracker := Start(context.Background(), rec, Call{Transport: TransportHTTP})
+	tracker.ObserveJSON([]byte(`{"id":"emb-1","model":"grok-embed","usage":{"promptTokens":7,"costInUsdTicks":"1000000000"}}`))
+	tracker.End(nil)
+
+	result := rec.result(t)
+	if result.ResponseID != "emb-1" || result.ResponseModel != "grok-embed" || result.Usage.InputTokens != 7 || result.Usage.CostUSD != 0.1 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestNilObserver(t *testing.T) {
+	ctx, tracker := Start(context.Background(), nil, Call{})
+	if tracker != nil || FromContext(ctx) != nil {
+		t.Fatal("no tracker should be created without an observer")
+	}
+	// The methods of a nil tracker do nothing.
+	tracker.StartAttempt()
+	tracker.ObserveJSON([]byte(`{}`))
+	tracker.End(nil)
+}
+
+func TestCombine(t *testing.T) {
+	if Combine(nil, nil) != nil {
+		t.Error("Combine of nil observers should be nil")
+	}
+	a, b := newRecorder(), newRecorder()
+	ctx, tracker := Start(context.Background(), Combine(a, nil, b), Call{Method: "GetCompletion"})
+	if FromContext(ctx) != tracker {
+		t.Fatal("the tracker should be in the context")
+	}
+	tracker.AttemptFailed(1, errors.New("try again"))
+	tracker.End(nil)
+
+	for _, rec := range []*recorder{a, b} {
+		if len(rec.calls) != 1 || len(rec.failures) != 1 || rec.result(t).Err != nil {
+			t.Errorf("calls = %v, failures = %v", rec.calls, rec.failures)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/cost"
+)
+
+// requestIDKeys are the header and metadata keys carrying the request ID.
+var requestIDKeys = []string{"x-request-id", "request-id"}
+
+// Tracker accumulates the result of one call and reports it to a
+// CallObserver. The gRPC interceptors and the REST client create one per
+// call; the methods of a nil Tracker do nothing.
+type Tracker struct {
+	observer CallObserver
+	start    time.Time
+	stop     func() bool
+
+	mu            sync.Mutex
+	result        Result
+	finishReasons map[int32]string
+	ended         bool
+}
+
+type trackerKey struct{}
+
+// Start starts tracking a call if observer is not nil, returning the context
+// of the call, which carries the tracker.
+func Start(ctx context.Context, observer Observer, call Call) (context.Context, *Tracker) {
+	if observer == nil {
+		return ctx, nil
+	}
+	ctx, callObserver := observer.StartCall(ctx, call)
+	if callObserver == nil {
+		return ctx, nil
+	}
+	t := &Tracker{observer: callObserver, start: time.Now()}
+	return context.WithValue(ctx, trackerKey{}, t), t
+}
+
+// FromContext returns the tracker of the call of ctx, or nil.
+func FromContext(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(trackerKey{}).(*Tracker)
+	return t
+}
+
+// EndWithContext ends the call with the context's error if ctx is done
+// before End is called, for streams abandoned by their reader.
+func (t *Tracker) EndWithContext(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	stop := context.AfterFunc(ctx, func() { t.End(ctx.Err()) })
+	t.mu.Lock()
+	t.stop = stop
+	t.mu.Unlock()
+}
+
+// StartAttempt records the start of an attempt and returns its number.
+func (t *Tracker) StartAttempt() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.result.Attempts++
+	return t.result.Attempts
+}
+
+// AttemptFailed reports that an attempt failed.
+func (t *Tracker) AttemptFailed(attempt int, err error) {
+	if t == nil || err == nil {
+		return
+	}
+	t.mu.Lock()
+	ended := t.ended
+	t.mu.Unlock()
+	if !ended {
+		t.observer.AttemptFailed(attempt, err)
+	}
+}
+
+// SetRequestID records the request ID found in headers or metadata, given as
+// a lookup function such as http.Header.Values or metadata.MD.Get.
+func (t *Tracker) SetRequestID(lookup func(key string) []string) {
+	if t == nil {
+		return
+	}
+	for _, key := range requestIDKeys {
+		if values := lookup(key); len(values) > 0 && values[0] != "" {
+			t.mu.Lock()
+			t.result.RequestID = values[0]
+			t.mu.Unlock()
+			return
+		}
+	}
+}
+
+// FirstToken records the arrival of the first message of a stream.
+func (t *Tracker) FirstToken() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.result.TimeToFirstToken == 0 {
+		t.result.TimeToFirstToken = time.Since(t.start)
+	}
+}
+
+// ObserveResponse records the ID, model, usage and finish reasons of a
+// response message or stream chunk.
+func (t *Tracker) ObserveResponse(msg any) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if m, ok := msg.(interface{ GetId() string }); ok && m.GetId() != "" {
+		t.result.ResponseID = m.GetId()
+	}
+	if m, ok := msg.(interface{ GetModel() string }); ok && m.GetModel() != "" {
+		t.result.ResponseModel = m.GetModel()
+	}
+	if m, ok := msg.(interface{ GetUsage() *xaiv1.SamplingUsage }); ok && m.GetUsage() != nil {
+		t.result.Usage = usageFromProto(m.GetUsage())
+	}
+
+	switch m := msg.(type) {
+	case *xaiv1.GetChatCompletionResponse:
+		for _, o := range m.GetOutputs() {
+			t.setFinishReason(o.GetIndex(), o.GetFinishReason())
+		}
+	case *xaiv1.GetChatCompletionChunk:
+		for _, o := range m.GetOutputs() {
+			t.setFinishReason(o.GetIndex(), o.GetFinishReason())
+		}
+	case *xaiv1.SampleTextResponse:
+		for _, c := range m.GetChoices() {
+			t.setFinishReason(c.GetIndex(), c.GetFinishReason())
+		}
+	}
+}
+
+// SetResponse records the response of a unary call, see Result.Response.
+func (t *Tracker) SetResponse(response any) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.result.Response = response
+	t.mu.Unlock()
+}
+
+// ObserveJSON records the ID, model and usage of a JSON response body, and
+// the body as the response.
+func (t *Tracker) ObserveJSON(body []byte) {
+	if t == nil {
+		return
+	}
+	t.SetResponse(json.RawMessage(body))
+	if len(body) == 0 || body[0] != '{' {
+		return
+	}
+	var resp struct {
+		ID    string         `json:"id"`
+		Model string         `json:"model"`
+		Usage map[string]any `json:"usage"`
+	}
+	if json.Unmarshal(body, &resp) != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if resp.ID != "" {
+		t.result.ResponseID = resp.ID
+	}
+	if resp.Model != "" {
+		t.result.ResponseModel = resp.Model
+	}
+	if resp.Usage != nil {
+		t.result.Usage = usageFromJSON(resp.Usage)
+	}
+}
+
+// End ends the call; later calls do nothing.
+func (t *Tracker) End(err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	if t.ended {
+		t.mu.Unlock()
+		return
+	}
+	t.ended = true
+	result := t.result
+	result.Err = err
+	result.Duration = time.Since(t.start)
+	if len(t.finishReasons) > 0 {
+		indexes := make([]int32, 0, len(t.finishReasons))
+		for index := range t.finishReasons {
+			indexes = append(indexes, index)
+		}
+		slices.Sort(indexes)
+		for _, index := range indexes {
+			result.FinishReasons = append(result.FinishReasons, t.finishReasons[index])
+		}
+	}
+	stop := t.stop
+	t.mu.Unlock()
+
+	if stop != nil {
+		stop()
+	}
+	t.observer.End(result)
+}
+
+func (t *Tracker) setFinishReason(index int32, reason xaiv1.FinishReason) {
+	if reason == xaiv1.FinishReason_REASON_INVALID {
+		return
+	}
+	if t.finishReasons == nil {
+		t.finishReasons = make(map[int32]string)
+	}
+	t.finishReasons[index] = FinishReason(reason)
+}
+
+// FinishReason returns the GenAI semantic conventions name of a finish reason.
+func FinishReason(reason xaiv1.FinishReason) string {
+	switch reason {
+	case xaiv1.FinishReason_REASON_STOP:
+		return "stop"
+	case xaiv1.FinishReason_REASON_MAX_LEN, xaiv1.FinishReason_REASON_MAX_CONTEXT:
+		return "length"
+	case xaiv1.FinishReason_REASON_TOOL_CALLS:
+		return "tool_calls"
+	default:
+		return strings.ToLower(strings.TrimPrefix(reason.String(), "REASON_"))
+	}
+}
+
+func usageFromProto(u *xaiv1.SamplingUsage) Usage {
+	usage := Usage{
+		InputTokens:       int64(u.GetPromptTokens()),
+		OutputTokens:      int64(u.GetCompletionTokens()),
+		ReasoningTokens:   int64(u.GetReasoningTokens()),
+		CachedInputTokens: int64(u.GetCachedPromptTextTokens()),
+	}
+	usage.CostUSD, usage.HasCost = cost.USDFromUsage(u)
+	return usage
+}
+
+// usageFromJSON reads a usage object in either the proto JSON (camelCase) or
+// the REST (snake_case) form.
+func usageFromJSON(u map[string]any) Usage {
+	number := func(keys ...string) (float64, bool) {
+		for _, key := range keys {
+			switch v := u[key].(type) {
+			case float64:
+				return v, true
+			case string: // proto JSON encodes int64 as a string
+				var f float64
+				if json.Unmarshal([]byte(v), &f) == nil {
+					return f, true
+				}
+			}
+		}
+		return 0, false
+	}
+	tokens := func(keys ...string) int64 {
+		v, _ := number(keys...)
+		return int64(v)
+	}
+
+	usage := Usage{
+		InputTokens:       tokens("prompt_tokens", "promptTokens", "input_tokens"),
+		OutputTokens:      tokens("completion_tokens", "completionTokens", "output_tokens"),
+		ReasoningTokens:   tokens("reasoning_tokens", "reasoningTokens"),
+		CachedInputTokens: tokens("cached_prompt_text_tokens", "cachedPromptTextTokens"),
+	}
+	if ticks, ok := number("cost_in_usd_ticks", "costInUsdTicks"); ok {
+		usage.CostUSD, usage.HasCost = ticks*cost.USDPerTick, true
+	}
+	return usage
+}
@@ -0,0 +1,238 @@
+package xaitest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// services are the generated gRPC services served.
+var services = []*grpc.ServiceDesc{
+	&xaiv1.Chat_ServiceDesc,
+	&xaiv1.Models_ServiceDesc,
+	&xaiv1.BatchMgmt_ServiceDesc,
+	&xaiv1.Video_ServiceDesc,
+	&xaiv1.Auth_ServiceDesc,
+	&xaiv1.Collections_ServiceDesc,
+	&xaiv1.Documents_ServiceDesc,
+	&xaiv1.Embedder_ServiceDesc,
+	&xaiv1.Files_ServiceDesc,
+	&xaiv1.Image_ServiceDesc,
+	&xaiv1.Sample_ServiceDesc,
+	&xaiv1.Tokenize_ServiceDesc,
+}
+
+// grpcMethod is the request and response types of a gRPC method.
+type grpcMethod struct {
+	input, output protoreflect.MessageType
+}
+
+// registerServices registers generic implementations of services, answering
+// every method from the routes.
+func (s *Server) registerServices() {
+	s.methods = map[string]grpcMethod{}
+	for _, desc := range services {
+		d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(desc.ServiceName))
+		if err != nil {
+			panic(fmt.Sprintf("xaitest: service %s: %v", desc.ServiceName, err))
+		}
+		service := d.(protoreflect.ServiceDescriptor)
+		method := func(name string) (string, grpcMethod) {
+			md := service.Methods().ByName(protoreflect.Name(name))
+			input, err := protoregistry.GlobalTypes.FindMessageByName(md.Input().FullName())
+			if err != nil {
+				panic(fmt.Sprintf("xaitest: method %s: %v", md.FullName(), err))
+			}
+			output, err := protoregistry.GlobalTypes.FindMessageByName(md.Output().FullName())
+			if err != nil {
+				panic(fmt.Sprintf("xaitest: method %s: %v", md.FullName(), err))
+			}
+			fullMethod := "/" + desc.ServiceName + "/" + name
+			s.methods[fullMethod] = grpcMethod{input: input, output: output}
+			return fullMethod, s.methods[fullMethod]
+		}
+
+		generic := grpc.ServiceDesc{ServiceName: desc.ServiceName, HandlerType: (*any)(nil), Metadata: desc.Metadata}
+		for _, m := range desc.Methods {
+			fullMethod, types := method(m.MethodName)
+			generic.Methods = append(generic.Methods, grpc.MethodDesc{
+				MethodName: m.MethodName,
+				Handler:    s.unaryHandler(fullMethod, types),
+			})
+		}
+		for _, st := range desc.Streams {
+			fullMethod, types := method(st.StreamName)
+			generic.Streams = append(generic.Streams, grpc.StreamDesc{
+				StreamName:    st.StreamName,
+				Handler:       s.streamHandler(fullMethod, types, st.ClientStreams, st.ServerStreams),
+				ServerStreams: st.ServerStreams,
+				ClientStreams: st.ClientStreams,
+			})
+		}
+		s.grpcServer.RegisterService(&generic, s)
+	}
+}
+
+func (s *Server) unaryHandler(fullMethod string, types grpcMethod) func(any, context.Context, func(any) error, grpc.UnaryServerInterceptor) (any, error) {
+	return func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+		in := types.input.New().Interface()
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		req := grpcRequest(ctx, fullMethod, in)
+		resp, err := s.serve(&req)
+		if err != nil {
+			return nil, status.Error(codes.Unimplemented, err.Error())
+		}
+		_ = grpc.SetHeader(ctx, responseMetadata(req, resp))
+		if err := wait(ctx, resp.Delay); err != nil {
+			return nil, err
+		}
+		if resp.Err != nil {
+			return nil, resp.Err
+		}
+		return outputMessage(fullMethod, types, resp.Message)
+	}
+}
+
+func (s *Server) streamHandler(fullMethod string, types grpcMethod, clientStreams, serverStreams bool) grpc.StreamHandler {
+	return func(_ any, stream grpc.ServerStream) error {
+		messages, err := receiveMessages(stream, types, clientStreams)
+		if err != nil {
+			return err
+		}
+		var first proto.Message
+		if len(messages) > 0 {
+			first = messages[0]
+		}
+		ctx := stream.Context()
+		req := grpcRequest(ctx, fullMethod, first)
+		req.Messages = messages
+		resp, err := s.serve(&req)
+		if err != nil {
+			return status.Error(codes.Unimplemented, err.Error())
+		}
+		if err := stream.SetHeader(responseMetadata(req, resp)); err != nil {
+			return err
+		}
+
+		if !serverStreams {
+			return sendResponse(stream, fullMethod, types, resp)
+		}
+		return replayChunks(stream, fullMethod, types, resp)
+	}
+}
+
+// receiveMessages reads the request messages of a stream: all of them for
+// client streams, the first one otherwise.
+func receiveMessages(stream grpc.ServerStream, types grpcMethod, clientStreams bool) ([]proto.Message, error) {
+	var messages []proto.Message
+	for {
+		in := types.input.New().Interface()
+		if err := stream.RecvMsg(in); errors.Is(err, io.EOF) {
+			return messages, nil
+		} else if err != nil {
+			return nil, err
+		}
+		messages = append(messages, in)
+		if !clientStreams {
+			return messages, nil
+		}
+	}
+}
+
+// sendResponse sends the single response of a client stream once its delay
+// has passed, or fails with its error.
+func sendResponse(stream grpc.ServerStream, fullMethod string, types grpcMethod, resp Response) error {
+	if err := wait(stream.Context(), resp.Delay); err != nil {
+		return err
+	}
+	if resp.Err != nil {
+		return resp.Err
+	}
+	out, err := outputMessage(fullMethod, types, resp.Message)
+	if err != nil {
+		return err
+	}
+	return stream.SendMsg(out)
+}
+
+// replayChunks sends the message and chunks of a response, waiting its delay
+// before each, then ends the stream with its error.
+func replayChunks(stream grpc.ServerStream, fullMethod string, types grpcMethod, resp Response) error {
+	chunks := resp.Chunks
+	if resp.Message != nil {
+		chunks = append([]any{resp.Message}, chunks...)
+	}
+	for _, chunk := range chunks {
+		if err := wait(stream.Context(), resp.Delay); err != nil {
+			return err
+		}
+		out, err := outputMessage(fullMethod, types, chunk)
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(out); err != nil {
+			return err
+		}
+	}
+	return resp.Err
+}
+
+// grpcRequest describes a gRPC call.
+func grpcRequest(ctx context.Context, fullMethod string, in proto.Message) Request {
+	md, _ := metadata.FromIncomingContext(ctx)
+	req := Request{Method: fullMethod, Message: in, Header: md}
+	if in != nil {
+		req.Messages = []proto.Message{in}
+	}
+	return req
+}
+
+// responseMetadata returns the header metadata of a response.
+func responseMetadata(req Request, resp Response) metadata.MD {
+	md := metadata.Pairs("x-request-id", req.ID)
+	for key, value := range resp.Header {
+		md.Set(key, value)
+	}
+	return md
+}
+
+// outputMessage checks that message is a response of the method, defaulting
+// to an empty one.
+func outputMessage(fullMethod string, types grpcMethod, message any) (proto.Message, error) {
+	if message == nil {
+		return types.output.New().Interface(), nil
+	}
+	m, ok := message.(proto.Message)
+	if !ok || m.ProtoReflect().Descriptor().FullName() != types.output.Descriptor().FullName() {
+		return nil, status.Errorf(codes.Internal, "xaitest: %s returns %s, not %T", fullMethod, types.output.Descriptor().FullName(), message)
+	}
+	return m, nil
+}
+
+// wait waits for d unless ctx is done first.
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
@@ -0,0 +1,83 @@
+package xaitest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	xaierrors "github.com/ZaguanLabs/xai-sdk-go/xai/internal/errors"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ServeHTTP answers REST calls from the routes.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := Request{
+		Method: r.Method + " " + r.URL.Path,
+		Header: lowerHeader(r.Header),
+		Query:  r.URL.Query(),
+		Body:   body,
+	}
+	resp, err := s.serve(&req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Request-Id", req.ID)
+	for key, value := range resp.Header {
+		w.Header().Set(key, value)
+	}
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err := wait(r.Context(), resp.Delay); err != nil {
+		return
+	}
+
+	if resp.Err != nil {
+		st := status.Convert(resp.Err)
+		statusCode := resp.StatusCode
+		if statusCode == 0 {
+			statusCode = xaierrors.HTTPStatusFromCode(st.Code())
+		}
+		writeError(w, statusCode, st.Message())
+		return
+	}
+
+	data, err := encodeMessage(resp.Message)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "xaitest: "+err.Error())
+		return
+	}
+	if resp.StatusCode != 0 {
+		w.WriteHeader(resp.StatusCode)
+	}
+	_, _ = w.Write(data)
+}
+
+// encodeMessage encodes a REST response; see Response.Message.
+func encodeMessage(message any) ([]byte, error) {
+	switch m := message.(type) {
+	case nil:
+		return []byte("{}"), nil
+	case []byte:
+		return m, nil
+	case string:
+		return []byte(m), nil
+	case proto.Message:
+		return protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+	default:
+		return json.Marshal(m)
+	}
+}
+
+// writeError writes an error body in the form of the API.
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
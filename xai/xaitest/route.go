@@ -0,0 +1,245 @@
+package xaitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Response is a scripted response.
+type Response struct {
+	// Message is the response. For gRPC calls it is a proto message of the
+	// method's response type (default: an empty one). For REST calls it is
+	// written as JSON: a proto message (with proto field names), []byte or
+	// string written as is, or any other value encoded with encoding/json.
+	Message any
+
+	// Chunks are the messages of a gRPC server stream, sent one by one after
+	// Message if it is set.
+	Chunks []any
+
+	// Err fails the call, after the chunks of a stream. gRPC status errors
+	// keep their code, which REST calls map to an HTTP status.
+	Err error
+
+	// StatusCode is the HTTP status of a REST response (default: 200, or the
+	// status of Err).
+	StatusCode int
+
+	// Header holds response headers or metadata added to the x-request-id
+	// header that every response carries.
+	Header map[string]string
+
+	// Delay is waited before responding and, for streams, before each chunk.
+	Delay time.Duration
+}
+
+// Request is a request received by a Server.
+type Request struct {
+	// ID is the request ID sent back in the x-request-id header.
+	ID string
+
+	// Method is the gRPC full method name, or the HTTP method and path such
+	// as "GET /v1/files/file-1".
+	Method string
+
+	// Message is the request message of a gRPC call; Messages holds all the
+	// messages of a client stream.
+	Message  proto.Message
+	Messages []proto.Message
+
+	// Header is the gRPC metadata or the HTTP header, with lower-case keys.
+	Header map[string][]string
+
+	// Query and Body are the query and body of a REST request.
+	Query url.Values
+	Body  []byte
+
+	route      string
+	pathValues map[string]string
+}
+
+// Get returns the first value of the header or metadata key.
+func (r Request) Get(key string) string {
+	if values := r.Header[strings.ToLower(key)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// PathValue returns the value of a {name} of the route's path pattern.
+func (r Request) PathValue(name string) string {
+	return r.pathValues[name]
+}
+
+// Decode decodes the JSON body of a REST request into v.
+func (r Request) Decode(v any) error {
+	if m, ok := v.(proto.Message); ok {
+		return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(r.Body, m)
+	}
+	return json.Unmarshal(r.Body, v)
+}
+
+// Route answers the calls of one method with scripted responses.
+type Route struct {
+	server *Server
+	method string
+
+	// grpc reports whether the route answers a gRPC method; otherwise
+	// httpMethod and segments are the HTTP method and path pattern.
+	grpc       bool
+	httpMethod string
+	segments   []string
+
+	when      func(Request) bool
+	handler   func(Request) Response
+	delay     time.Duration
+	responses []Response
+	served    int
+	requests  []Request
+}
+
+func newRoute(s *Server, method string) (*Route, error) {
+	r := &Route{server: s, method: method}
+	if strings.HasPrefix(method, "/") {
+		if _, ok := s.methods[method]; !ok {
+			return nil, fmt.Errorf("unknown gRPC method %s", method)
+		}
+		r.grpc = true
+		return r, nil
+	}
+	httpMethod, path, ok := strings.Cut(method, " ")
+	if !ok || httpMethod == "" || !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid route %q: want a gRPC method or \"METHOD /path\"", method)
+	}
+	r.httpMethod = strings.ToUpper(httpMethod)
+	r.segments = strings.Split(strings.Trim(path, "/"), "/")
+	return r, nil
+}
+
+// Return adds a response per message; see Response.Message.
+func (r *Route) Return(messages ...any) *Route {
+	responses := make([]Response, len(messages))
+	for i, message := range messages {
+		responses[i] = Response{Message: message}
+	}
+	return r.Respond(responses...)
+}
+
+// Stream adds a response streaming chunks.
+func (r *Route) Stream(chunks ...any) *Route {
+	return r.Respond(Response{Chunks: chunks})
+}
+
+// Fail adds a response failing with err, such as a status error.
+func (r *Route) Fail(err error) *Route {
+	return r.Respond(Response{Err: err})
+}
+
+// Respond adds responses.
+func (r *Route) Respond(responses ...Response) *Route {
+	r.server.mu.Lock()
+	defer r.server.mu.Unlock()
+	r.responses = append(r.responses, responses...)
+	return r
+}
+
+// Handle answers every call with handler instead of scripted responses.
+func (r *Route) Handle(handler func(Request) Response) *Route {
+	r.server.mu.Lock()
+	defer r.server.mu.Unlock()
+	r.handler = handler
+	return r
+}
+
+// When restricts the route to the requests accepted by match, such as the
+// requests for one deferred request ID.
+func (r *Route) When(match func(Request) bool) *Route {
+	r.server.mu.Lock()
+	defer r.server.mu.Unlock()
+	r.when = match
+	return r
+}
+
+// Delay adds latency before every response of the route.
+func (r *Route) Delay(d time.Duration) *Route {
+	r.server.mu.Lock()
+	defer r.server.mu.Unlock()
+	r.delay = d
+	return r
+}
+
+// Requests returns the requests answered by the route.
+func (r *Route) Requests() []Request {
+	r.server.mu.Lock()
+	defer r.server.mu.Unlock()
+	return append([]Request(nil), r.requests...)
+}
+
+// Calls returns the number of requests answered by the route.
+func (r *Route) Calls() int {
+	r.server.mu.Lock()
+	defer r.server.mu.Unlock()
+	return len(r.requests)
+}
+
+// match reports whether the route answers req.
+func (r *Route) match(req *Request) bool {
+	if r.grpc {
+		if req.Method != r.method {
+			return false
+		}
+	} else if _, ok := r.pathValues(req); !ok {
+		return false
+	}
+	return r.when == nil || r.when(*req)
+}
+
+// pathValues matches the path of a REST request against the route's pattern.
+func (r *Route) pathValues(req *Request) (map[string]string, bool) {
+	httpMethod, path, ok := strings.Cut(req.Method, " ")
+	if r.grpc || !ok || httpMethod != r.httpMethod {
+		return nil, false
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	values := map[string]string{}
+	for i, pattern := range r.segments {
+		if name, ok := strings.CutPrefix(pattern, "{"); ok && strings.HasSuffix(name, "...}") {
+			if i >= len(segments) {
+				return nil, false
+			}
+			values[strings.TrimSuffix(name, "...}")] = strings.Join(segments[i:], "/")
+			return values, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		if name, ok := strings.CutPrefix(pattern, "{"); ok && strings.HasSuffix(name, "}") {
+			value, err := url.PathUnescape(segments[i])
+			if err != nil {
+				return nil, false
+			}
+			values[strings.TrimSuffix(name, "}")] = value
+			continue
+		}
+		if pattern != segments[i] {
+			return nil, false
+		}
+	}
+	return values, len(segments) == len(r.segments)
+}
+
+// lowerHeader returns header with lower-case keys.
+func lowerHeader(header http.Header) map[string][]string {
+	lower := make(map[string][]string, len(header))
+	for key, values := range header {
+		lower[strings.ToLower(key)] = values
+	}
+	return lower
+}
@@ -0,0 +1,228 @@
+// Package xaitest provides an in-process fake of the xAI API for tests. A
+// Server serves every generated gRPC service (Chat, Models, BatchMgmt, Video,
+// Auth, ...) on a localhost listener and the REST endpoints used by the
+// files, collections, embed, image, tokenizer, documents and sample packages
+// on an HTTP listener, answering each call from scripted routes:
+//
+//	server := xaitest.NewServer(t)
+//	server.On(xaiv1.Chat_GetCompletion_FullMethodName).Return(&xaiv1.GetChatCompletionResponse{...})
+//	server.On("GET /v1/files/{id}").Return(map[string]any{"id": "file-1"})
+//	server.On(xaiv1.Video_GetDeferredVideo_FullMethodName).Return(pending, pending, done)
+//
+//	client := server.Client() // an *xai.Client configured against the server
+//
+// Routes return their scripted responses in order, then repeat the last one,
+// which makes deferred status progressions easy to script. Responses can be
+// streamed chunk by chunk, fail with injected errors and be delayed; every
+// request is recorded for assertions.
+package xaitest
+
+import (
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai"
+	"google.golang.org/grpc"
+)
+
+// APIKey is the API key of the clients created by a Server.
+const APIKey = "xaitest-api-key"
+
+// Server is a fake xAI API server.
+type Server struct {
+	tb         testing.TB
+	grpcServer *grpc.Server
+	listener   net.Listener
+	httpServer *httptest.Server
+	methods    map[string]grpcMethod
+
+	mu       sync.Mutex
+	routes   []*Route
+	requests []Request
+	closed   bool
+}
+
+// NewServer starts a server; it is closed when the test ends.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("xaitest: failed to listen: %v", err)
+	}
+
+	s := &Server{tb: tb, listener: listener, grpcServer: grpc.NewServer()}
+	s.registerServices()
+	go func() { _ = s.grpcServer.Serve(listener) }()
+	s.httpServer = httptest.NewServer(s)
+
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	s.grpcServer.Stop()
+	s.httpServer.Close()
+}
+
+// GRPCAddress returns the address of the gRPC listener.
+func (s *Server) GRPCAddress() string {
+	return s.listener.Addr().String()
+}
+
+// HTTPAddress returns the address of the HTTP listener.
+func (s *Server) HTTPAddress() string {
+	return s.httpServer.Listener.Addr().String()
+}
+
+// Config returns a configuration connecting to the server without TLS, with
+// APIKey as the API key and short retry backoffs. Environment variables are
+// ignored.
+func (s *Server) Config() *xai.Config {
+	config := xai.DefaultConfig().
+		WithAPIKey(APIKey).
+		WithInsecure(true).
+		WithRetryBackoff(time.Millisecond).
+		WithMaxBackoff(10 * time.Millisecond)
+	config.Host, config.GRPCPort, _ = net.SplitHostPort(s.GRPCAddress())
+	config.HTTPHost = s.HTTPAddress()
+	config.ManagementAPIHost = s.HTTPAddress()
+	return config
+}
+
+// Client returns a client of the server built from Config; it is closed when
+// the test ends.
+func (s *Server) Client() *xai.Client {
+	s.tb.Helper()
+	return s.ClientWithConfig(s.Config())
+}
+
+// ClientWithConfig returns a client built from config, usually Config with
+// changes; it is closed when the test ends.
+func (s *Server) ClientWithConfig(config *xai.Config) *xai.Client {
+	s.tb.Helper()
+	client, err := xai.NewClient(config)
+	if err != nil {
+		s.tb.Fatalf("xaitest: failed to create client: %v", err)
+	}
+	s.tb.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// On returns a new route answering method: a gRPC full method name such as
+// xaiv1.Chat_GetCompletion_FullMethodName, or an HTTP method and path
+// pattern such as "GET /v1/files/{id}", where {name} matches a path segment
+// and {name...} the rest of the path. Routes of the same method are tried in
+// the order they were added; see Route.When.
+func (s *Server) On(method string) *Route {
+	r, err := newRoute(s, method)
+	if err != nil {
+		s.tb.Fatalf("xaitest: %v", err)
+	}
+	s.mu.Lock()
+	s.routes = append(s.routes, r)
+	s.mu.Unlock()
+	return r
+}
+
+// Requests returns the requests received, in order. If methods are given,
+// only the requests answered by routes of these methods, or whose Method is
+// one of them, are returned.
+func (s *Server) Requests(methods ...string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(methods) == 0 {
+		return append([]Request(nil), s.requests...)
+	}
+	var requests []Request
+	for _, req := range s.requests {
+		for _, method := range methods {
+			if req.Method == method || req.route == method {
+				requests = append(requests, req)
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// AssertExpectations fails the test if a route has scripted responses that
+// were never requested.
+func (s *Server) AssertExpectations(tb testing.TB) {
+	tb.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.routes {
+		if pending := len(r.responses) - r.served; r.handler == nil && pending > 0 {
+			tb.Errorf("xaitest: %s: %d of %d scripted responses were not requested", r.method, pending, len(r.responses))
+		}
+	}
+}
+
+// serve records req and returns the response of the route answering it.
+func (s *Server) serve(req *Request) (Response, error) {
+	s.mu.Lock()
+	req.ID = "xaitest-" + strconv.Itoa(len(s.requests)+1)
+	route, last := s.findRoute(req)
+	if route != nil {
+		req.pathValues, _ = route.pathValues(req)
+	}
+	var resp Response
+	var err error
+	switch {
+	case route == nil:
+		err = fmt.Errorf("xaitest: no route for %s", req.Method)
+	case route.handler == nil:
+		req.route = route.method
+		route.requests = append(route.requests, *req)
+		resp = route.responses[min(route.served, len(route.responses)-1)]
+		if !last {
+			route.served++
+		}
+	default:
+		req.route = route.method
+		route.requests = append(route.requests, *req)
+	}
+	s.requests = append(s.requests, *req)
+	s.mu.Unlock()
+
+	if route != nil && route.handler != nil {
+		resp = route.handler(*req)
+	}
+	if route != nil {
+		resp.Delay += route.delay
+	}
+	return resp, err
+}
+
+// findRoute returns the first matching route with a handler or unserved
+// responses, or else the last matching route with responses, reporting
+// whether its last response is repeated; s.mu must be held.
+func (s *Server) findRoute(req *Request) (*Route, bool) {
+	var repeat *Route
+	for _, r := range s.routes {
+		if !r.match(req) {
+			continue
+		}
+		if r.handler != nil || r.served < len(r.responses) {
+			return r, false
+		}
+		if len(r.responses) > 0 {
+			repeat = r
+		}
+	}
+	return repeat, true
+}
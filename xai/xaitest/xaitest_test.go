@@ -0,0 +1,222 @@
+package xaitest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	xaiv1 "github.com/ZaguanLabs/xai-sdk-go/proto/gen/go/xai/api/v1"
+	"github.com/ZaguanLabs/xai-sdk-go/xai"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/chat"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/files"
+	"github.com/ZaguanLabs/xai-sdk-go/xai/video"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func completion(content string) *xaiv1.GetChatCompletionResponse {
+	return &xaiv1.GetChatCompletionResponse{
+		Id:      "resp-1",
+		Outputs: []*xaiv1.CompletionOutput{{Message: &xaiv1.CompletionMessage{Content: content}}},
+	}
+}
+
+func chunk(content string) *xaiv1.GetChatCompletionChunk {
+	return &xaiv1.GetChatCompletionChunk{Outputs: []*xaiv1.CompletionOutputChunk{{Delta: &xaiv1.Delta{Content: content}}}}
+}
+
+func hello() *chat.Request {
+	return chat.NewRequest("grok-4", chat.WithMessages(chat.User(chat.Text("Hello"))))
+}
+
+func TestChat(t *testing.T) {
+	server := NewServer(t)
+	route := server.On(xaiv1.Chat_GetCompletion_FullMethodName).Return(completion("Hi!"))
+	client := server.Client()
+
+	resp, err := hello().Sample(context.Background(), client.Chat())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content() != "Hi!" {
+		t.Errorf("content = %q", resp.Content())
+	}
+
+	requests := route.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("requests = %v", requests)
+	}
+	req := requests[0]
+	if got := req.Message.(*xaiv1.GetCompletionsRequest).GetModel(); got != "grok-4" {
+		t.Errorf("model = %q", got)
+	}
+	if got := req.Get("authorization"); got != "Bearer "+APIKey {
+		t.Errorf("authorization = %q", got)
+	}
+	server.AssertExpectations(t)
+}
+
+func TestChatStream(t *testing.T) {
+	server := NewServer(t)
+	server.On(xaiv1.Chat_GetCompletionChunk_FullMethodName).Respond(Response{
+		Chunks: []any{chunk("One, "), chunk("two")},
+		Delay:  time.Millisecond,
+	})
+
+	stream, err := hello().Stream(context.Background(), server.Client().Chat())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content strings.Builder
+	for c, err := range stream.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		content.WriteString(c.Content())
+	}
+	if content.String() != "One, two" {
+		t.Errorf("streamed %q", content.String())
+	}
+}
+
+func TestInjectedErrors(t *testing.T) {
+	server := NewServer(t)
+	route := server.On(xaiv1.Chat_GetCompletion_FullMethodName).
+		Fail(status.Error(codes.Unavailable, "overloaded")).
+		Return(completion("Hi!"))
+	client := server.Client()
+
+	// The client retries the rejected attempt.
+	if _, err := hello().Sample(context.Background(), client.Chat()); err != nil {
+		t.Fatal(err)
+	}
+	if route.Calls() != 2 {
+		t.Errorf("calls = %d, want 2", route.Calls())
+	}
+
+	failing := NewServer(t)
+	failing.On(xaiv1.Chat_GetCompletion_FullMethodName).Fail(status.Error(codes.NotFound, "no such model"))
+	_, err := hello().Sample(context.Background(), failing.Client().Chat())
+	var apiErr *xai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != codes.NotFound || !strings.HasPrefix(apiErr.RequestID, "xaitest-") {
+		t.Errorf("error = %v", err)
+	}
+
+	// Methods without routes are unimplemented.
+	models := xaiv1.NewModelsClient(client.GRPCConnection())
+	if _, err := models.ListLanguageModels(context.Background(), &emptypb.Empty{}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("unscripted call error = %v", err)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	server := NewServer(t)
+	server.On(xaiv1.Chat_GetCompletion_FullMethodName).Delay(time.Second).Return(completion("Hi!"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := hello().Sample(ctx, server.Client().Chat()); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestDeferredProgression(t *testing.T) {
+	server := NewServer(t)
+	server.On(xaiv1.Video_GenerateVideo_FullMethodName).Return(&xaiv1.StartDeferredResponse{RequestId: "video-1"})
+	pending := &xaiv1.GetDeferredVideoResponse{Status: xaiv1.DeferredStatus_PENDING}
+	done := &xaiv1.GetDeferredVideoResponse{Status: xaiv1.DeferredStatus_DONE, Response: &xaiv1.VideoResponse{}}
+	poll := server.On(xaiv1.Video_GetDeferredVideo_FullMethodName).
+		When(func(r Request) bool {
+			return r.Message.(*xaiv1.GetDeferredVideoRequest).GetRequestId() == "video-1"
+		}).
+		Return(pending, pending, done)
+
+	_, err := server.Client().Video().Generate(context.Background(), "A sunset", "grok-video", &video.GenerateOptions{Interval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if poll.Calls() != 3 {
+		t.Errorf("polls = %d, want 3", poll.Calls())
+	}
+	server.AssertExpectations(t)
+}
+
+func TestREST(t *testing.T) {
+	server := NewServer(t)
+	server.On("GET /v1/files/{id}").Handle(func(r Request) Response {
+		return Response{Message: map[string]any{"id": r.PathValue("id"), "filename": "notes.txt"}}
+	})
+	server.On("DELETE /v1/files/{id}").Fail(status.Error(codes.PermissionDenied, "read-only file"))
+	client := server.Client()
+
+	file, err := client.Files().Get(context.Background(), "file-7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.ID != "file-7" {
+		t.Errorf("file = %+v", file)
+	}
+
+	err = client.Files().Delete(context.Background(), "file-7")
+	var apiErr *xai.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatus != 403 || apiErr.Message != "read-only file" {
+		t.Errorf("error = %v", err)
+	}
+
+	requests := server.Requests("GET /v1/files/{id}")
+	if len(requests) != 1 || requests[0].Method != "GET /v1/files/file-7" || requests[0].Get("Authorization") != "Bearer "+APIKey {
+		t.Errorf("requests = %+v", requests)
+	}
+}
+
+func TestFileUpload(t *testing.T) {
+	server := NewServer(t)
+	route := server.On(xaiv1.Files_UploadFile_FullMethodName).Handle(func(r Request) Response {
+		var size int64
+		for _, m := range r.Messages {
+			size += int64(len(m.(*xaiv1.UploadFileChunk).GetData()))
+		}
+		return Response{Message: &xaiv1.File{Id: "file-1", Size: size}}
+	})
+
+	file, err := server.Client().Files().Upload(context.Background(), strings.NewReader("Hello, world"), files.UploadOptions{Name: "hello.txt", ChunkSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.ID != "file-1" || file.Size != 12 {
+		t.Errorf("file = %+v", file)
+	}
+	if requests := route.Requests(); len(requests) != 1 || len(requests[0].Messages) != 3 {
+		t.Errorf("requests = %v, want one stream of 3 chunks", requests)
+	}
+}
+
+// recordingTB records the errors of AssertExpectations.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertExpectations(t *testing.T) {
+	server := NewServer(t)
+	server.On(xaiv1.Chat_GetCompletion_FullMethodName).Return(completion("Hi!"), completion("Bye!"))
+	if _, err := hello().Sample(context.Background(), server.Client().Chat()); err != nil {
+		t.Fatal(err)
+	}
+
+	tb := &recordingTB{TB: t}
+	server.AssertExpectations(tb)
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "1 of 2") {
+		t.Errorf("errors = %v", tb.errors)
+	}
+}
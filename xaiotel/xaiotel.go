@@ -0,0 +1,287 @@
+// Package xaiotel reports the calls of an xAI client to OpenTelemetry. It
+// emits a client span per gRPC and REST call and records metrics, following
+// the semantic conventions for generative AI where they apply:
+//
+//	client, err := xai.NewClient(xai.NewConfig().WithObserver(xaiotel.NewObserver()))
+//
+// Spans carry the operation, requested and response models, request and
+// response IDs, token usage, cost, finish reasons, retry attempts and, for
+// streams, the time to the first token. Failed attempts are recorded as span
+// events.
+package xaiotel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ScopeName is the instrumentation scope of the tracer and meter.
+const ScopeName = "github.com/ZaguanLabs/xai-sdk-go/xaiotel"
+
+// Attribute keys.
+const (
+	keySystem           = attribute.Key("gen_ai.system")
+	keyOperation        = attribute.Key("gen_ai.operation.name")
+	keyRequestModel     = attribute.Key("gen_ai.request.model")
+	keyResponseModel    = attribute.Key("gen_ai.response.model")
+	keyResponseID       = attribute.Key("gen_ai.response.id")
+	keyFinishReasons    = attribute.Key("gen_ai.response.finish_reasons")
+	keyInputTokens      = attribute.Key("gen_ai.usage.input_tokens")
+	keyOutputTokens     = attribute.Key("gen_ai.usage.output_tokens")
+	keyTokenType        = attribute.Key("gen_ai.token.type")
+	keyErrorType        = attribute.Key("error.type")
+	keyRPCSystem        = attribute.Key("rpc.system")
+	keyRPCService       = attribute.Key("rpc.service")
+	keyRPCMethod        = attribute.Key("rpc.method")
+	keyHTTPMethod       = attribute.Key("http.request.method")
+	keyURLPath          = attribute.Key("url.path")
+	keyRequestID        = attribute.Key("xai.request.id")
+	keyAttempts         = attribute.Key("xai.request.attempts")
+	keyAttempt          = attribute.Key("xai.request.attempt")
+	keyStream           = attribute.Key("xai.request.stream")
+	keyReasoningTokens  = attribute.Key("xai.usage.reasoning_tokens")
+	keyCachedTokens     = attribute.Key("xai.usage.cached_input_tokens")
+	keyCostUSD          = attribute.Key("xai.usage.cost_usd")
+	keyTimeToFirstToken = attribute.Key("xai.response.time_to_first_token")
+)
+
+// Option configures an Observer.
+type Option func(*options)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// WithTracerProvider sets the tracer provider (default: the global one).
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider (default: the global one).
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(o *options) {
+		o.meterProvider = provider
+	}
+}
+
+// Observer is a telemetry.Observer emitting OpenTelemetry spans and metrics.
+type Observer struct {
+	tracer trace.Tracer
+
+	duration         metric.Float64Histogram
+	tokenUsage       metric.Int64Histogram
+	timeToFirstToken metric.Float64Histogram
+	errorCount       metric.Int64Counter
+	cost             metric.Float64Counter
+}
+
+var _ telemetry.Observer = (*Observer)(nil)
+
+// NewObserver creates an observer. It panics if the instruments cannot be
+// created; use New to handle the error.
+func NewObserver(opts ...Option) *Observer {
+	o, err := New(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+// New creates an observer.
+func New(opts ...Option) (*Observer, error) {
+	cfg := options{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	meter := cfg.meterProvider.Meter(ScopeName)
+	o := &Observer{tracer: cfg.tracerProvider.Tracer(ScopeName)}
+	var errs []error
+	var err error
+	o.duration, err = meter.Float64Histogram("gen_ai.client.operation.duration",
+		metric.WithDescription("Duration of xAI API calls"), metric.WithUnit("s"))
+	errs = append(errs, err)
+	o.tokenUsage, err = meter.Int64Histogram("gen_ai.client.token.usage",
+		metric.WithDescription("Tokens used by xAI API calls"), metric.WithUnit("{token}"))
+	errs = append(errs, err)
+	o.timeToFirstToken, err = meter.Float64Histogram("gen_ai.client.operation.time_to_first_chunk",
+		metric.WithDescription("Time to the first chunk of streamed xAI API calls"), metric.WithUnit("s"))
+	errs = append(errs, err)
+	o.errorCount, err = meter.Int64Counter("xai.client.errors",
+		metric.WithDescription("Failed xAI API calls"), metric.WithUnit("{call}"))
+	errs = append(errs, err)
+	o.cost, err = meter.Float64Counter("xai.client.cost",
+		metric.WithDescription("Cost of xAI API calls"), metric.WithUnit("USD"))
+	errs = append(errs, err)
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("failed to create instruments: %w", err)
+	}
+	return o, nil
+}
+
+// StartCall starts the span of a call.
+func (o *Observer) StartCall(ctx context.Context, call telemetry.Call) (context.Context, telemetry.CallObserver) {
+	attrs := []attribute.KeyValue{keySystem.String(telemetry.System)}
+	if call.Operation != "" {
+		attrs = append(attrs, keyOperation.String(call.Operation))
+	}
+	if call.Model != "" {
+		attrs = append(attrs, keyRequestModel.String(call.Model))
+	}
+	// Metrics are recorded with the attributes known at the start.
+	metricAttrs := append([]attribute.KeyValue(nil), attrs...)
+
+	if call.Transport == telemetry.TransportGRPC {
+		attrs = append(attrs, keyRPCSystem.String("grpc"), keyRPCService.String(call.Service), keyRPCMethod.String(call.Method))
+	} else {
+		attrs = append(attrs, keyHTTPMethod.String(call.Service), keyURLPath.String(call.Method))
+	}
+	if call.Stream {
+		attrs = append(attrs, keyStream.Bool(true))
+	}
+
+	ctx, span := o.tracer.Start(ctx, spanName(call), trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, &callObserver{observer: o, ctx: ctx, span: span, call: call, attrs: metricAttrs}
+}
+
+// spanName follows the GenAI convention "{operation} {model}" for model
+// calls and the RPC or HTTP conventions otherwise.
+func spanName(call telemetry.Call) string {
+	switch {
+	case call.Operation != "" && call.Model != "":
+		return call.Operation + " " + call.Model
+	case call.Operation != "":
+		return call.Operation
+	case call.Transport == telemetry.TransportGRPC:
+		return call.Service + "/" + call.Method
+	default:
+		return call.Service + " " + call.Method
+	}
+}
+
+// callObserver records one call.
+type callObserver struct {
+	observer *Observer
+	ctx      context.Context
+	span     trace.Span
+	call     telemetry.Call
+	attrs    []attribute.KeyValue
+}
+
+func (c *callObserver) AttemptFailed(attempt int, err error) {
+	c.span.AddEvent("xai.attempt.failed", trace.WithAttributes(
+		keyAttempt.Int(attempt),
+		keyErrorType.String(errorType(err)),
+		attribute.String("exception.message", err.Error()),
+	))
+}
+
+func (c *callObserver) End(result telemetry.Result) {
+	o, span, ctx := c.observer, c.span, c.ctx
+
+	span.SetAttributes(resultAttrs(result)...)
+
+	metricAttrs := c.attrs
+	if result.ResponseModel != "" {
+		metricAttrs = append(metricAttrs[:len(metricAttrs):len(metricAttrs)], keyResponseModel.String(result.ResponseModel))
+	}
+	if result.Err != nil {
+		errType := errorType(result.Err)
+		span.SetAttributes(keyErrorType.String(errType))
+		span.RecordError(result.Err)
+		span.SetStatus(otelcodes.Error, result.Err.Error())
+		metricAttrs = append(metricAttrs[:len(metricAttrs):len(metricAttrs)], keyErrorType.String(errType))
+		o.errorCount.Add(ctx, 1, metric.WithAttributes(metricAttrs...))
+	}
+	span.End()
+	o.record(ctx, result, metricAttrs)
+}
+
+// record records the metrics of a finished call.
+func (o *Observer) record(ctx context.Context, result telemetry.Result, metricAttrs []attribute.KeyValue) {
+	set := metric.WithAttributes(metricAttrs...)
+	o.duration.Record(ctx, result.Duration.Seconds(), set)
+	if result.TimeToFirstToken > 0 {
+		o.timeToFirstToken.Record(ctx, result.TimeToFirstToken.Seconds(), set)
+	}
+	usage := result.Usage
+	if usage.InputTokens > 0 {
+		o.tokenUsage.Record(ctx, usage.InputTokens, metric.WithAttributes(append(metricAttrs[:len(metricAttrs):len(metricAttrs)], keyTokenType.String("input"))...))
+	}
+	if usage.OutputTokens > 0 {
+		o.tokenUsage.Record(ctx, usage.OutputTokens, metric.WithAttributes(append(metricAttrs[:len(metricAttrs):len(metricAttrs)], keyTokenType.String("output"))...))
+	}
+	if usage.HasCost {
+		o.cost.Add(ctx, usage.CostUSD, set)
+	}
+}
+
+// resultAttrs returns the span attributes of the outcome of a call.
+func resultAttrs(result telemetry.Result) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{keyAttempts.Int(result.Attempts)}
+	if result.RequestID != "" {
+		attrs = append(attrs, keyRequestID.String(result.RequestID))
+	}
+	if result.ResponseID != "" {
+		attrs = append(attrs, keyResponseID.String(result.ResponseID))
+	}
+	if result.ResponseModel != "" {
+		attrs = append(attrs, keyResponseModel.String(result.ResponseModel))
+	}
+	if len(result.FinishReasons) > 0 {
+		attrs = append(attrs, keyFinishReasons.StringSlice(result.FinishReasons))
+	}
+	attrs = appendUsageAttrs(attrs, result.Usage)
+	if result.TimeToFirstToken > 0 {
+		attrs = append(attrs, keyTimeToFirstToken.Float64(result.TimeToFirstToken.Seconds()))
+	}
+	return attrs
+}
+
+// appendUsageAttrs appends the token usage and cost attributes of a call.
+func appendUsageAttrs(attrs []attribute.KeyValue, usage telemetry.Usage) []attribute.KeyValue {
+	if usage.InputTokens > 0 || usage.OutputTokens > 0 {
+		attrs = append(attrs, keyInputTokens.Int64(usage.InputTokens), keyOutputTokens.Int64(usage.OutputTokens))
+	}
+	if usage.ReasoningTokens > 0 {
+		attrs = append(attrs, keyReasoningTokens.Int64(usage.ReasoningTokens))
+	}
+	if usage.CachedInputTokens > 0 {
+		attrs = append(attrs, keyCachedTokens.Int64(usage.CachedInputTokens))
+	}
+	if usage.HasCost {
+		attrs = append(attrs, keyCostUSD.Float64(usage.CostUSD))
+	}
+	return attrs
+}
+
+// errorType returns the error.type attribute of err: its gRPC status code,
+// which API errors of both transports carry, or the context error.
+func errorType(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled.String()
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded.String()
+	}
+	if st, ok := status.FromError(err); ok && st.Code() != codes.Unknown {
+		return st.Code().String()
+	}
+	return "_OTHER"
+}
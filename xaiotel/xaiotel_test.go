@@ -0,0 +1,129 @@
+package xaiotel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ZaguanLabs/xai-sdk-go/xai/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestObserver(t *testing.T) (*Observer, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	t.Helper()
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	o, err := New(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o, spans, reader
+}
+
+func attrs(kvs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value, len(kvs))
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestObserverRecordsChatCall(t *testing.T) {
+	o, spans, reader := newTestObserver(t)
+
+	_, call := o.StartCall(context.Background(), telemetry.Call{
+		Transport: telemetry.TransportGRPC,
+		Service:   "xai_api.Chat",
+		Method:    "GetCompletionChunk",
+		Operation: telemetry.OperationChat,
+		Model:     "grok-4",
+		Stream:    true,
+	})
+	call.AttemptFailed(1, status.Error(codes.Unavailable, "try again"))
+	call.End(telemetry.Result{
+		RequestID:        "req-1",
+		ResponseID:       "resp-1",
+		ResponseModel:    "grok-4-0709",
+		Usage:            telemetry.Usage{InputTokens: 10, OutputTokens: 20, CostUSD: 0.5, HasCost: true},
+		FinishReasons:    []string{"stop"},
+		Attempts:         2,
+		Duration:         2 * time.Second,
+		TimeToFirstToken: 300 * time.Millisecond,
+	})
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("ended spans = %d", len(ended))
+	}
+	span := ended[0]
+	if span.Name() != "chat grok-4" {
+		t.Errorf("span name = %q", span.Name())
+	}
+	got := attrs(span.Attributes())
+	for key, want := range map[attribute.Key]attribute.Value{
+		keyOperation:     attribute.StringValue("chat"),
+		keyRequestModel:  attribute.StringValue("grok-4"),
+		keyResponseModel: attribute.StringValue("grok-4-0709"),
+		keyResponseID:    attribute.StringValue("resp-1"),
+		keyRequestID:     attribute.StringValue("req-1"),
+		keyInputTokens:   attribute.Int64Value(10),
+		keyOutputTokens:  attribute.Int64Value(20),
+		keyAttempts:      attribute.IntValue(2),
+		keyRPCMethod:     attribute.StringValue("GetCompletionChunk"),
+	} {
+		if got[key] != want {
+			t.Errorf("%s = %v, want %v", key, got[key].Emit(), want.Emit())
+		}
+	}
+	if events := span.Events(); len(events) != 1 || events[0].Name != "xai.attempt.failed" {
+		t.Errorf("events = %v", events)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			names[m.Name] = true
+		}
+	}
+	for _, name := range []string{"gen_ai.client.operation.duration", "gen_ai.client.token.usage", "gen_ai.client.operation.time_to_first_chunk", "xai.client.cost"} {
+		if !names[name] {
+			t.Errorf("metric %s was not recorded; got %v", name, names)
+		}
+	}
+	if names["xai.client.errors"] {
+		t.Error("no error should be counted for a successful call")
+	}
+}
+
+func TestObserverRecordsFailedCall(t *testing.T) {
+	o, spans, _ := newTestObserver(t)
+
+	_, call := o.StartCall(context.Background(), telemetry.Call{
+		Transport: telemetry.TransportHTTP,
+		Service:   "GET",
+		Method:    "/files/file-1",
+	})
+	call.End(telemetry.Result{Err: status.Error(codes.NotFound, "no such file"), Attempts: 1})
+
+	span := spans.Ended()[0]
+	if span.Name() != "GET /files/file-1" || span.Status().Code != otelcodes.Error {
+		t.Errorf("span %q status = %v", span.Name(), span.Status())
+	}
+	if got := attrs(span.Attributes())[keyErrorType]; got.AsString() != "NotFound" {
+		t.Errorf("error.type = %q", got.AsString())
+	}
+}